@@ -1,7 +1,11 @@
 package rd
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
 )
 
@@ -11,11 +15,50 @@ const (
 	TypeForm  = `application/x-www-form-urlencoded`
 	TypeMulti = `multipart/form-data`
 
+	// Newline-delimited JSON, supported only by `rd.Decode` for slice outputs.
+	TypeNdjson = `application/x-ndjson`
+
+	// Supported via `rd.Xml`, based on "encoding/xml".
+	TypeXml     = `application/xml`
+	TypeXmlText = `text/xml`
+
+	// Supported via `rd.Csv`, based on "encoding/csv", for slice outputs.
+	TypeCsv = `text/csv`
+
+	// Supported only after registering an unmarshaler via `rd.Register`.
+	TypeToml     = `application/toml`
+	TypeYaml     = `application/yaml`
+	TypeYamlText = `text/yaml`
+	TypeMsgpack  = `application/msgpack`
+	TypeMsgpackX = `application/x-msgpack`
+
 	// Used for `(*Request).ParseMultipartForm`.
 	// 32 MB, same as the default in the "http" package.
 	BufSize = 32 << 20
 )
 
+/*
+Default limit on the size of JSON bodies, in bytes, used by `rd.Json.Download`,
+and therefore by `rd.Download` and `rd.Decode`, unless overridden by
+`rd.Config.JsonLimit`. Bodies exceeding the limit produce an error with HTTP
+status 413. Also applies to `rd.Xml`, `rd.Csv`, and to the decoders of formats
+supported via `rd.Register`, which download bodies the same way. Zero means
+unbounded, which is the default for backward compatibility. Should be set
+during initialization, before handling requests.
+*/
+var JsonLimit int64
+
+/*
+Limit on the size of decompressed request bodies, in bytes, used by
+`rd.Decode`, `rd.Download`, and other functions which decompress bodies
+according to `Content-Encoding`. Applies to all content types, including
+multipart, and guards against decompression bombs: small compressed bodies
+which expand to huge sizes. Bodies exceeding the limit produce an error with
+HTTP status 413. Zero means unbounded. Should be set during initialization,
+before handling requests.
+*/
+var DecompressLimit int64 = BufSize
+
 // Returned by `rd.Download`. Implemented by all decoder types in this package.
 type Dec interface {
 	Decoder
@@ -32,6 +75,7 @@ request:
 	* Form-encoded request -> backed by `url.Values`, decodes into structs.
 	* Multipart request    -> backed by `url.Values`, decodes into structs.
 	* JSON request         -> backed by `[]byte`, decodes into anything.
+	* XML request          -> backed by `[]byte`, decodes into anything.
 
 Once constructed, a decoder is considered immutable, concurrency-safe, and can
 decode into arbitrary outputs any amount of times. Also see `rd.Json` and
@@ -83,14 +127,34 @@ func TryDecode(req *http.Request, out interface{}) {
 	}
 }
 
+/*
+Shortcut for `rd.Decode` that returns the decoded value, avoiding the need to
+declare a variable. Returns the zero value of the given type on errors.
+Example:
+
+	input, err := rd.DecodeTyped[Input](req)
+*/
+func DecodeTyped[T any](req *http.Request) (T, error) {
+	var out T
+	err := Decode(req, &out)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
 /*
 Decodes an arbitrary request into an arbitrary Go structure. Uses the request's
-`Content-Type` header to choose the decoding method.
+`Content-Type` header to choose the decoding method. Never panics; any panics
+during decoding, including those in user-defined methods such as `rd.Parser`,
+are converted to errors.
 
-When `Content-Type` is present but unrecognized, returns an error.
+When `Content-Type` is present but unrecognized, returns an error with HTTP
+status 415.
 
 When `Content-Type` is missing and the request does have a body, returns an
-error.
+error with HTTP status 400.
 
 When `Content-Type` is missing and the request doesn't have a body, treats the
 request's URL query exactly like the body of a formdata request. See below.
@@ -106,43 +170,219 @@ become available via `req.MultipartForm.File`.
 When `Content-Type` is `rd.TypeJson`, decodes the body into the output in a
 streaming fashion, using `json.Decoder`. The output must be a pointer to any
 value compatible with the structure of the provided JSON.
+
+When `Content-Type` is `rd.TypeNdjson`, decodes a stream of JSON values, such
+as newline-delimited JSON objects, into a slice, appending one element per
+value, in a streaming fashion. The output must be a non-nil pointer to a slice;
+on success, its previous elements are discarded, and on failure, the slice is
+left unchanged. `rd.Download` doesn't support this content type.
+
+When `Content-Type` is `rd.TypeXml` or `rd.TypeXmlText`, decodes the body via
+`rd.Xml`, which uses the "xml" field tag rather than "json".
+
+When `Content-Type` is `rd.TypeCsv`, decodes the body via `rd.Csv`, treating the
+first row as the header, and each subsequent row as an element of the output,
+which must be a non-nil pointer to a slice of structs.
+
+For `rd.TypeForm`, `rd.TypeJson`, and `rd.TypeNdjson`, a leading UTF-8 BOM in
+the body, which some clients prepend, is ignored. BOMs elsewhere in the body
+are not affected.
+
+When `Content-Encoding` is "gzip" or "deflate", the body is decompressed before
+decoding, for any content type, and the header is removed from the request.
+The decompressed body is limited by `rd.DecompressLimit`, which guards against
+decompression bombs. Other limits such as `rd.JsonLimit` also apply to the
+decompressed body. Unsupported encodings produce an error with HTTP status 415,
+and malformed compressed data produces an error with HTTP status 400.
+
+When `Content-Type` is `rd.TypeToml` and a TOML unmarshaler has been registered
+via `rd.Register`, decodes the body via `rd.Toml`. Otherwise returns an error.
+The same applies to `rd.TypeYaml` and `rd.TypeYamlText`, decoded via `rd.Yaml`,
+which falls back on `rd.YamlUnmarshal`, and to `rd.TypeMsgpack` and
+`rd.TypeMsgpackX`, decoded via `rd.Msgpack`.
 */
 func Decode(req *http.Request, out interface{}) error {
+	return DecodeWith(req, out, Config{})
+}
+
+/*
+Decodes an arbitrary request into an arbitrary Go structure, like `rd.Decode`,
+using the provided settings. See `rd.Config`. After successful decoding, calls
+the function registered for the output type via `rd.RegisterPostDecode`, if
+any.
+*/
+func DecodeWith(req *http.Request, out interface{}, conf Config) (err error) {
+	defer rescue(&err)
+
 	if req == nil || out == nil {
 		return nil
 	}
 
-	typ := reqContentType(req)
+	defer errDecompressLimit(req, &err)
+	err = decodeWith(req, out, conf)
+	if err != nil {
+		return err
+	}
+	return postDecode(out)
+}
+
+/*
+Decodes the request like `rd.Decode`, and additionally returns the names of the
+top-level fields of the output struct which were present in the request, using
+the names from the "json" tag. Useful for partial updates, such as building an
+SQL "update" which touches only the provided columns. Unlike `rd.Decode`,
+always buffers the request body; see `rd.Download`.
+
+For forms, this uses `rd.Form.DecodeTracked`, reducing nested fields such as
+"inner.innerStr" to their top-level names such as "inner", so that all formats
+report the same names. For JSON and other formats, top-level keys of the body
+are matched to fields like in "encoding/json", preferring exact matches to
+case-insensitive ones, and reported by field name; keys which don't correspond
+to any field are excluded. For outputs other than structs, and when nothing is
+present, the set is nil.
+*/
+func DecodeFields(req *http.Request, out interface{}) (_ Set, err error) {
+	defer rescue(&err)
+
+	if req == nil || out == nil {
+		return nil, nil
+	}
+
+	dec, err := Download(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var set Set
+	form, ok := dec.(Form)
+	if ok {
+		set, err = form.DecodeTracked(out, Config{})
+		set = rootNames(set)
+	} else {
+		err = dec.Decode(out)
+		if err == nil {
+			set = matchFields(dec.Set(), out)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return set, postDecode(out)
+}
+
+func decodeWith(req *http.Request, out interface{}, conf Config) error {
+	conf.prefer(req)
+
+	typ := ContentType(req)
+	if conf.Log != nil {
+		conf.logContentType(req, typ)
+	}
+
+	err := decompressBody(req)
+	if err != nil {
+		return err
+	}
+
+	if conf.RequireBody && typ != `` {
+		err := requireBody(req, typ)
+		if err != nil {
+			return err
+		}
+	}
 
 	switch typ {
 	case ``:
 		if reqHasBody(req) {
 			return errContentType(typ)
 		}
-		return Form(reqQuery(req)).Decode(out)
+		return Form(reqQuery(req)).DecodeWith(out, conf)
 
 	case TypeForm:
+		if conf.StreamForm && req.Body != nil && req.PostForm == nil {
+			return DecodeFormReader(req.Body, out, conf)
+		}
+
 		var dec Form
 		err := dec.DownloadForm(req)
 		if err != nil {
 			return err
 		}
-		return dec.Decode(out)
+		return dec.DecodeWith(out, conf)
 
 	case TypeMulti:
 		var dec Form
-		err := dec.DownloadMultipart(req)
+		err := dec.DownloadMultipartWith(req, conf.maxMultipartMem())
 		if err != nil {
 			return err
 		}
-		return dec.Decode(out)
+		return dec.DecodeWith(out, conf)
 
 	case TypeJson:
 		body := req.Body
 		if body == nil {
 			return nil
 		}
-		return errBadReq(json.NewDecoder(body).Decode(out))
+
+		// Buffering is required for detecting bodies over the limit, rather than
+		// only decoding their beginning.
+		limit := conf.jsonLimit()
+		if conf.jsonBuffer() || limit > 0 {
+			var dec Json
+			err := dec.DownloadLimited(req, limit)
+			if err != nil {
+				return err
+			}
+			return dec.DecodeWith(out, conf)
+		}
+		err := stripBodyBom(req)
+		if err != nil {
+			return errBadReq(err)
+		}
+		return errBadReq(json.NewDecoder(req.Body).Decode(out))
+
+	case TypeNdjson:
+		if req.Body == nil {
+			return nil
+		}
+
+		limit := conf.jsonLimit()
+		if limit > 0 {
+			var dec Json
+			err := dec.DownloadLimited(req, limit)
+			if err != nil {
+				return err
+			}
+			return decodeNdjson(bytes.NewReader(dec), out)
+		}
+
+		err := stripBodyBom(req)
+		if err != nil {
+			return errBadReq(err)
+		}
+		return decodeNdjson(req.Body, out)
+
+	case TypeXml, TypeXmlText:
+		var dec Xml
+		err := dec.DownloadLimited(req, conf.jsonLimit())
+		if err != nil {
+			return err
+		}
+		return dec.Decode(out)
+
+	case TypeCsv:
+		var dec Csv
+		err := dec.DownloadLimited(req, conf.jsonLimit())
+		if err != nil {
+			return err
+		}
+		return dec.Decode(out)
+
+	case TypeToml, TypeYaml, TypeYamlText, TypeMsgpack, TypeMsgpackX:
+		dec, err := downloadRegistered(req, typ)
+		if err != nil {
+			return err
+		}
+		return dec.Decode(out)
 
 	default:
 		return errContentType(typ)
@@ -161,12 +401,14 @@ func TryDownload(req *http.Request) Dec {
 /*
 Downloads the request's data, using the request's `Content-Type` header to
 choose the appropriate decoder type. Unlike `Decode`, this always buffers
-the request data in memory.
+the request data in memory. Like `rd.Decode`, decompresses bodies according to
+`Content-Encoding`.
 
-When `Content-Type` is present but unrecognized, returns an error.
+When `Content-Type` is present but unrecognized, returns an error with HTTP
+status 415.
 
 When `Content-Type` is missing and the request does have a body, returns an
-error.
+error with HTTP status 400.
 
 When `Content-Type` is missing and the request doesn't have a body, returns
 `rd.Form` with the request's URL query.
@@ -180,13 +422,52 @@ Downloaded files become available via `req.MultipartForm.File`.
 
 When `Content-Type` is `rd.TypeJson`, returns `rd.Json` containing the
 downloaded response body, without any decoding or modification.
+
+When `Content-Type` is `rd.TypeXml` or `rd.TypeXmlText`, returns `rd.Xml`
+containing the downloaded response body.
+
+When `Content-Type` is `rd.TypeCsv`, returns `rd.Csv` containing the downloaded
+response body.
+
+When `Content-Type` is `rd.TypeToml` and a TOML unmarshaler has been registered
+via `rd.Register`, returns `rd.Toml` containing the downloaded response body.
+Otherwise returns an error. The same applies to `rd.TypeYaml` and
+`rd.TypeYamlText`, returning `rd.Yaml`, which falls back on
+`rd.YamlUnmarshal`, and to `rd.TypeMsgpack` and `rd.TypeMsgpackX`, returning
+`rd.Msgpack`.
 */
 func Download(req *http.Request) (Dec, error) {
+	return DownloadWith(req, Config{})
+}
+
+/*
+Downloads the request's data, like `rd.Download`, using the provided settings.
+See `rd.Config`.
+*/
+func DownloadWith(req *http.Request, conf Config) (_ Dec, err error) {
+	defer rescue(&err)
+
 	if req == nil {
 		return decEmpty{}, nil
 	}
 
-	typ := reqContentType(req)
+	typ := ContentType(req)
+	if conf.Log != nil {
+		conf.logContentType(req, typ)
+	}
+
+	defer errDecompressLimit(req, &err)
+	err = decompressBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.RequireBody && typ != `` {
+		err := requireBody(req, typ)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	switch typ {
 	case ``:
@@ -202,15 +483,186 @@ func Download(req *http.Request) (Dec, error) {
 
 	case TypeMulti:
 		var dec Form
-		err := dec.DownloadMultipart(req)
+		err := dec.DownloadMultipartWith(req, conf.maxMultipartMem())
 		return dec, err
 
 	case TypeJson:
 		var dec Json
-		err := dec.Download(req)
+		err := dec.DownloadLimited(req, conf.jsonLimit())
+		return dec, err
+
+	case TypeXml, TypeXmlText:
+		var dec Xml
+		err := dec.DownloadLimited(req, conf.jsonLimit())
 		return dec, err
 
+	case TypeCsv:
+		var dec Csv
+		err := dec.DownloadLimited(req, conf.jsonLimit())
+		return dec, err
+
+	case TypeToml, TypeYaml, TypeYamlText, TypeMsgpack, TypeMsgpackX:
+		return downloadRegistered(req, typ)
+
 	default:
 		return nil, errContentType(typ)
 	}
 }
+
+/*
+Downloads the request's data, like `rd.Download`, aborting when the given
+context is done, usually `req.Context()`. This bounds the download by the
+deadline of the context, and interrupts reads of bodies which stall midway,
+such as from slow or malicious clients. When the context is done before the
+download is finished, closes the request body and returns an error with HTTP
+status 408, whose cause is the error of the context, such as
+`context.DeadlineExceeded`.
+*/
+func DownloadContext(ctx context.Context, req *http.Request) (_ Dec, err error) {
+	defer rescue(&err)
+
+	if req == nil {
+		return decEmpty{}, nil
+	}
+
+	defer watchBody(ctx, req)(&err)
+	return DownloadWith(req, Config{})
+}
+
+/*
+Shortcut for `rd.Download` followed by `.Haser().Has`, answering the question
+"was this key submitted at the top level?". Like `rd.Download`, this consumes
+the request body, so the request can't be decoded afterwards; when both are
+needed, use `rd.Download` and reuse the resulting decoder. Malformed JSON
+produces an error with HTTP status 400.
+*/
+func Has(req *http.Request, key string) (_ bool, err error) {
+	defer rescue(&err)
+
+	dec, err := Download(req)
+	if err != nil {
+		return false, err
+	}
+	return decHas(dec, key), nil
+}
+
+/*
+Like `.Haser().Has`, but avoids allocating a set for `rd.Json`, which would be
+discarded immediately.
+*/
+func decHas(dec Dec, key string) bool {
+	impl, ok := dec.(Json)
+	if !ok {
+		return dec.Haser().Has(key)
+	}
+
+	out, err := hasKey(bytesString(impl), key)
+	if err != nil {
+		panic(errBadReq(err))
+	}
+	return out
+}
+
+/*
+Returns the media type from the request's `Content-Type` header, normalized the
+same way as in `rd.Decode` and `rd.Download`, via `mime.ParseMediaType`: without
+parameters such as "charset", lowercased, and without surrounding whitespace.
+Returns an empty string when the request is nil, or when the header is missing,
+or when the media type is malformed; malformed parameters are ignored. Useful
+for middleware which routes requests by content type before decoding, such as
+rejecting multipart requests early, using the same detection as the decoders.
+*/
+func ContentType(req *http.Request) string {
+	if req == nil {
+		return ``
+	}
+	val, _, _ := mime.ParseMediaType(req.Header.Get(Type))
+	return val
+}
+
+/*
+Checks the request for inconsistencies between its `Content-Type` header and
+the actual shape of its body, without consuming the body. Meant as a cheap
+sanity check before decoding, for strict gateways. Only the beginning of the
+body is examined, so passing this check doesn't guarantee that decoding will
+succeed. Returns an error with HTTP status 415 when the content type is
+unsupported, like `rd.Decode`, and with HTTP status 400 in the following cases:
+
+	* Content type is missing, but the body is not empty.
+	* Content type is `rd.TypeJson` or `rd.TypeNdjson`, but the body doesn't
+	  start with a JSON value.
+	* Content type is `rd.TypeXml` or `rd.TypeXmlText`, but the body doesn't
+	  start with "<".
+	* Content type is `rd.TypeForm`, but the body looks like JSON or XML.
+	* Content type is `rd.TypeMulti`, but the header lacks a boundary, or the
+	  body looks like JSON or XML.
+
+An empty body is considered consistent with any content type. Compressed bodies
+are examined after decompression, like in `rd.Decode`; the request body is
+replaced with a decompressing reader, which can be decoded afterwards.
+*/
+func Validate(req *http.Request) (err error) {
+	defer rescue(&err)
+
+	if !reqHasBody(req) {
+		return nil
+	}
+
+	defer errDecompressLimit(req, &err)
+	err = decompressBody(req)
+	if err != nil {
+		return err
+	}
+
+	head, err := peekBody(req)
+	if err != nil {
+		return errBadReq(err)
+	}
+
+	typ := ContentType(req)
+	char := jsonHead(trimBom(head))
+	if char == 0 {
+		return nil
+	}
+
+	switch typ {
+	case ``:
+		return errContentType(typ)
+
+	case TypeJson, TypeNdjson:
+		if !jsonHeads.has(char) {
+			return errBodyShape(typ, head)
+		}
+
+	case TypeForm:
+		if markupHeads.has(char) {
+			return errBodyShape(typ, head)
+		}
+
+	case TypeMulti:
+		_, params, _ := mime.ParseMediaType(req.Header.Get(Type))
+		if params[`boundary`] == `` {
+			return errBadReq(fmt.Errorf(`missing multipart boundary in content type`))
+		}
+		if markupHeads.has(char) {
+			return errBodyShape(typ, head)
+		}
+
+	case TypeXml, TypeXmlText:
+		if char != '<' {
+			return errBodyShape(typ, head)
+		}
+
+	// Any text may be valid CSV.
+	case TypeCsv:
+
+	case TypeToml, TypeYaml, TypeYamlText, TypeMsgpack, TypeMsgpackX:
+		if loadUnmarshal(typ) == nil {
+			return errContentType(typ)
+		}
+
+	default:
+		return errContentType(typ)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package rd
+
+import (
+	"fmt"
+	"net/http"
+	r "reflect"
+)
+
+// Sources of struct fields for `rd.Binder`, in the order of decoding.
+const (
+	InBody   = `body`
+	InQuery  = `query`
+	InHeader = `header`
+	InCookie = `cookie`
+	InPath   = `path`
+)
+
+/*
+Decodes a request into a struct whose fields come from different parts of the
+request, in one call. The source of each top-level field is determined by the
+"rd" field tag option "in", such as:
+
+	type Input struct {
+		Id      string `json:"id"           rd:"in=path"`
+		Page    int    `json:"page"         rd:"in=query"`
+		Trace   string `json:"X-Request-Id" rd:"in=header"`
+		Session string `json:"session"      rd:"in=cookie"`
+		Name    string `json:"name"`
+	}
+
+	err := rd.Binder{Path: pathParam}.Bind(req, &input)
+
+Supported sources:
+
+	* "body" or no option: decoded like in `rd.DecodeWith`, from the request
+	  body, or from the URL query when the request has no body.
+	* "query": decoded from the URL query, even when the request has a body.
+	* "header": decoded from request headers. Names are case-insensitive.
+	* "cookie": decoded from request cookies.
+	* "path": decoded from path parameters, via `.Path`.
+
+Field names come from the field tag used for forms, "json" by default; see
+`rd.Config.Tag`. Fields from all sources except the body are decoded like
+form fields, supporting nested fields, list fields with multiple values, and
+the "required" tag option; see `rd.Form`. Nested fields use the source of
+their top-level field. Fields of other sources are never populated from the
+body, for any content type, which prevents clients from overriding them.
+
+Sources are decoded in the order listed above. The configuration applies to
+every source; `rd.Config.JsonKey` applies only to the body. When using
+`rd.Config.Strict`, keys of other sources are not allowed in form and JSON
+bodies, and produce errors with HTTP status 400. The
+function registered via `rd.RegisterPostDecode` is called once, after all
+sources have been decoded. Like `rd.Decode`, never panics, and converts
+panics into errors.
+*/
+type Binder struct {
+	// Settings used for every source. See `rd.Config`.
+	Config Config
+
+	// Returns the value of the path parameter with the given name, or an empty
+	// string if missing. The parameters of the request are router-specific; for
+	// example, with Go 1.22+ this may be `(*http.Request).PathValue`. Required
+	// for fields tagged `rd:"in=path"`.
+	Path func(req *http.Request, name string) string
+}
+
+/*
+Decodes the request into the output, which must be a non-nil pointer to a
+struct. See `rd.Binder`.
+*/
+func (self Binder) Bind(req *http.Request, outVal interface{}) (err error) {
+	defer rescue(&err)
+
+	if req == nil {
+		return nil
+	}
+
+	out, err := derefStruct(r.ValueOf(outVal))
+	if err != nil {
+		return err
+	}
+
+	conf := self.Config
+	fields := loadTagFields(out.Type(), conf.tag())
+
+	for _, field := range fields {
+		if !isBindSource(field.In) {
+			return errInternal(fmt.Errorf(`unknown source %q in "rd" tag of field %q`, field.In, field.Name))
+		}
+		if field.In == InPath && self.Path == nil {
+			return errInternal(fmt.Errorf(`unable to bind path field %q without rd.Binder.Path`, field.Name))
+		}
+	}
+
+	// Without a body, the query is the body source, see `rd.Decode`.
+	queryBody := ContentType(req) == `` && !reqHasBody(req)
+
+	err = self.body(req, outVal, out, fields, queryBody)
+	if err != nil {
+		return err
+	}
+
+	for _, in := range []string{InQuery, InHeader, InCookie, InPath} {
+		if in == InQuery && queryBody {
+			continue
+		}
+
+		allowed := bindAllowed(fields, &conf, in)
+		if allowed == nil {
+			continue
+		}
+
+		sub := conf
+		sub.JsonKey = ``
+		sub.Allowed = allowed
+
+		err := self.source(req, fields, in).DecodeWith(outVal, sub)
+		if err != nil {
+			return err
+		}
+	}
+	return postDecode(outVal)
+}
+
+/*
+Decodes the body source. Fields of other sources are set aside, and restored
+after decoding, because the allow-list doesn't apply to every content type.
+They're zeroed during decoding, so that decoding doesn't modify any data they
+reference.
+*/
+func (self Binder) body(req *http.Request, outVal interface{}, out r.Value, fields []jsonField, queryBody bool) error {
+	conf := self.Config
+	conf.Allowed = bindAllowed(fields, &conf, InBody)
+	if conf.Allowed == nil {
+		conf.Allowed = Set{}
+	}
+	if queryBody {
+		conf.Allowed = conf.Allowed.Union(bindAllowed(fields, &self.Config, InQuery))
+	}
+	if conf.JsonKey != `` && self.Config.allows(conf.JsonKey) {
+		conf.Allowed.Add(conf.JsonKey)
+	}
+
+	var saved []bindSaved
+	for _, field := range fields {
+		if field.Nested || isBodySource(field.In) || (queryBody && field.In == InQuery) {
+			continue
+		}
+
+		val, ok := valueAt(out, field.Path)
+		if !ok || !val.CanSet() {
+			continue
+		}
+
+		prev := r.New(val.Type()).Elem()
+		prev.Set(val)
+		saved = append(saved, bindSaved{field.Path, prev})
+		val.Set(r.Zero(val.Type()))
+	}
+
+	defer func() {
+		for _, val := range saved {
+			field, ok := valueAt(out, val.path)
+			if ok {
+				field.Set(val.val)
+			}
+		}
+	}()
+
+	if ContentType(req) == TypeJson {
+		return bindJson(req, outVal, out.Type(), fields, conf)
+	}
+	return decodeWith(req, outVal, conf)
+}
+
+/*
+Decodes a JSON body. In strict mode, also rejects top-level keys which match
+fields of other sources, like `rd.Config.Allowed` for forms, which doesn't apply
+to JSON. Keys are matched to fields like in "encoding/json", by the "json" tag,
+and the fields are identified by their position in the struct, which doesn't
+depend on `rd.Config.Tag`.
+*/
+func bindJson(req *http.Request, outVal interface{}, typ r.Type, fields []jsonField, conf Config) error {
+	conf.prefer(req)
+	if !conf.Strict || req.Body == nil {
+		return decodeWith(req, outVal, conf)
+	}
+
+	dec, err := DownloadWith(req, conf)
+	if err != nil {
+		return err
+	}
+
+	body, _ := dec.(Json)
+	err = body.DecodeWith(outVal, conf)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	jsonFields := loadJsonFields(typ)
+
+	for key := range body.Set() {
+		match, ok := matchField(jsonFields, key)
+		if !ok {
+			continue
+		}
+
+		for _, field := range fields {
+			if !field.Nested && !isBodySource(field.In) && equalPath(field.Path, match.Path) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	return errBadReq(errDisallowedKeys(keys))
+}
+
+type bindSaved struct {
+	path []int
+	val  r.Value
+}
+
+// Returns a form with the source's values for the fields of that source.
+func (self Binder) source(req *http.Request, fields []jsonField, in string) Form {
+	if in == InQuery {
+		return Form(reqQuery(req))
+	}
+
+	out := Form{}
+	for _, field := range fields {
+		if field.Kind != fieldNormal || field.In != in {
+			continue
+		}
+
+		var vals []string
+		switch in {
+		case InHeader:
+			vals = req.Header.Values(field.Name)
+		case InCookie:
+			for _, val := range req.Cookies() {
+				if val.Name == field.Name {
+					vals = append(vals, val.Value)
+				}
+			}
+		case InPath:
+			val := self.Path(req, field.Name)
+			if val != `` {
+				vals = []string{val}
+			}
+		}
+
+		if len(vals) > 0 {
+			out[field.Name] = vals
+		}
+	}
+	return out
+}
+
+/*
+Returns the names of top-level fields of the given source which are allowed by
+the config, or nil if there are none. Nested fields are allowed via dotted
+prefixes, see `rd.Config.Allowed`.
+*/
+func bindAllowed(fields []jsonField, conf *Config, in string) Set {
+	var out Set
+	for _, field := range fields {
+		if field.Kind != fieldNormal || field.Nested || !conf.allows(field.Name) {
+			continue
+		}
+		if field.In == in || (in == InBody && isBodySource(field.In)) {
+			if out == nil {
+				out = Set{}
+			}
+			out.Add(field.Name)
+		}
+	}
+	return out
+}
+
+func isBodySource(in string) bool { return in == `` || in == InBody }
+
+func isBindSource(in string) bool {
+	switch in {
+	case ``, InBody, InQuery, InHeader, InCookie, InPath:
+		return true
+	default:
+		return false
+	}
+}
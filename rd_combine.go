@@ -0,0 +1,143 @@
+package rd
+
+import (
+	"fmt"
+	"net/url"
+	r "reflect"
+	"sync"
+)
+
+var combinerReg sync.Map
+
+type combiner struct {
+	keys []string
+	fun  func([]string) (interface{}, error)
+}
+
+/*
+Registers a function which `rd.Form` uses for decoding fields of the given type
+from multiple form keys, rather than from a single key. Useful for inputs split
+across several form controls, such as a date entered as separate day, month,
+and year. Example:
+
+	rd.RegisterCombiner(
+		reflect.TypeOf(time.Time{}),
+		[]string{`year`, `month`, `day`},
+		func(src []string) (interface{}, error) {
+			return time.Parse(`2006-1-2`, strings.Join(src, `-`))
+		},
+	)
+
+With this registration, a field tagged `json:"birth"` is decoded from the form
+keys "birth.year", "birth.month", "birth.day", in that order. Keys are relative
+to the field name, using the same dotted notation as nested structs, which
+allows multiple fields of the same type in one struct. For each key, the
+function receives the first value, or an empty string if the key is missing.
+
+The combiner is used only when at least one of its keys is present in the
+form; otherwise the field is decoded from its own key as usual. The function
+must return a value assignable or convertible to the field type, or nil to
+zero the field. Fields of pointer types also use the combiner registered for
+the pointed-to type. Errors returned by the function are treated as parsing
+errors, with HTTP status 400.
+
+Registering nil removes the registration. Should be called during
+initialization; safe for concurrent use regardless.
+*/
+func RegisterCombiner(typ r.Type, keys []string, fun func([]string) (interface{}, error)) {
+	if typ == nil {
+		panic(errInternal(fmt.Errorf(`unable to register combiner for nil type`)))
+	}
+
+	if fun == nil {
+		combinerReg.Delete(typ)
+		return
+	}
+
+	if !(len(keys) > 0) {
+		panic(errInternal(fmt.Errorf(`unable to register combiner for %v without keys`, typ)))
+	}
+
+	combinerReg.Store(typ, combiner{copyStrings(keys), fun})
+}
+
+// Checks the exact type first, then the pointed-to type.
+func loadCombiner(typ r.Type) (combiner, bool) {
+	if typ == nil {
+		return combiner{}, false
+	}
+
+	val, ok := combinerReg.Load(typ)
+	if !ok && typ.Kind() == r.Ptr {
+		val, ok = combinerReg.Load(derefType(typ))
+	}
+	if !ok {
+		return combiner{}, false
+	}
+	return val.(combiner), true
+}
+
+func (self combiner) has(src Form, name string) bool {
+	for _, key := range self.keys {
+		if src.Has(name + `.` + key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self combiner) isKey(name, key string) bool {
+	for _, val := range self.keys {
+		if key == name+`.`+val {
+			return true
+		}
+	}
+	return false
+}
+
+// Caller must ensure that `.has` is true.
+func (self combiner) decode(src Form, root r.Value, field jsonField, conf *Config) error {
+	inputs := make([]string, len(self.keys))
+	for i, key := range self.keys {
+		key = field.Name + `.` + key
+		if conf.arity() {
+			err := checkArity(key, src[key])
+			if err != nil {
+				return err
+			}
+		}
+		inputs[i] = url.Values(src).Get(key)
+	}
+
+	val, err := self.fun(inputs)
+	if err != nil {
+		return err
+	}
+
+	if val == nil {
+		zeroAt(root, field.Path)
+		return nil
+	}
+
+	out := derefAllocAt(root, field.Path)
+	input := r.ValueOf(val)
+
+	if input.Type().AssignableTo(out.Type()) {
+		out.Set(input)
+		return nil
+	}
+	if input.Type().ConvertibleTo(out.Type()) {
+		out.Set(input.Convert(out.Type()))
+		return nil
+	}
+	return errInternal(fmt.Errorf(`combiner for %v returned incompatible value of type %v`, field.Type, input.Type()))
+}
+
+func copyStrings(src []string) []string {
+	if src == nil {
+		return nil
+	}
+	out := make([]string, len(src))
+	copy(out, src)
+	return out
+}
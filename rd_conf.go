@@ -0,0 +1,390 @@
+package rd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/*
+Optional decoding settings, accepted by `rd.DecodeWith` and the `.DecodeWith`
+methods of decoder types. The zero value is valid and matches the default
+behavior of `rd.Decode` and the `.Decode` methods of decoder types. Each setting
+documents which decoders it applies to; other decoders ignore it.
+*/
+type Config struct {
+	// Name of a form key whose value is JSON text, decoded into the output of
+	// `rd.Form.DecodeWith` before any other form fields. Useful for mixed forms
+	// where one field carries complex structured data. Other form fields take
+	// precedence: they're decoded afterwards and overwrite any matching fields
+	// populated from the JSON. Empty means disabled.
+	JsonKey string
+
+	// Enables lenient arity coercion in `rd.Json.DecodeWith`, for top-level fields
+	// of the output struct. A non-array value for a slice or array field is
+	// wrapped into a single-element array. A single-element array for any other
+	// field is unwrapped. Useful for clients that are inconsistent about lists
+	// with one element. Disabled by default.
+	Coerce bool
+
+	// Enables `rd.SliceParser` support in `rd.Json.DecodeWith`, for top-level
+	// fields of the output struct, unifying custom list parsing across JSON and
+	// form inputs. For fields whose types implement `rd.SliceParser` but not
+	// `json.Unmarshaler`, which are otherwise decoded as regular JSON values,
+	// the JSON value must be an array of scalars, or a single scalar. The
+	// elements are converted to strings, as if they were form values: strings
+	// are unquoted, "null" becomes an empty string, and numbers and booleans
+	// are used as-is. The strings are passed to `.ParseSlice`. A JSON "null"
+	// zeroes the field, like in `rd.Form`. Disabled by default.
+	JsonSliceParser bool
+
+	// Enables null-zeroing in `rd.Json.DecodeWith`, for top-level fields of the
+	// output struct, matching the semantics of `rd.Form`, which is useful for
+	// PATCH endpoints which decode JSON and forms into non-zero structs. By
+	// default, "encoding/json" ignores "null" for fields other than pointers,
+	// slices, maps, and interfaces, leaving them as-is. With this setting, a
+	// top-level key whose value is "null" zeroes the matching field, regardless
+	// of its type, without invoking `json.Unmarshaler`. Like "encoding/json",
+	// keys are matched to the names in the "json" tag case-insensitively,
+	// preferring exact matches. Disabled by default.
+	ZeroNull bool
+
+	// Enables number-to-string leniency in `rd.Json.DecodeWith`, for top-level
+	// fields of the output struct, matching the semantics of `rd.Form`, where
+	// all values are text. JSON numbers are accepted for string fields, and for
+	// elements of slices and arrays of strings, and are decoded as their source
+	// text, such as "10" for 10 or "1.50" for 1.50. Types with custom
+	// unmarshaling are excluded. Useful for endpoints which accept both JSON and
+	// forms interchangeably. By default, "encoding/json" rejects numbers for
+	// string fields. Disabled by default.
+	JsonNumberString bool
+
+	// Enables strict decoding. Applies to `rd.Form.DecodeWith` and
+	// `rd.Json.DecodeWith`. In strict mode, all keys in the request must
+	// correspond to fields of the output struct, and duplicates are rejected:
+	// JSON must not have duplicate top-level keys, and form fields which are not
+	// lists must not have multiple values. Violations produce errors with HTTP
+	// status 400. For JSON, unknown keys are rejected by "encoding/json", which
+	// reports only the first one, such as `unknown field "two"`; combine with
+	// `.RejectUnknown` to list all unknown top-level keys. Disabled by default.
+	Strict bool
+
+	// Rejects unknown keys in `rd.Form.DecodeWith` and `rd.Json.DecodeWith`,
+	// without the other checks of `.Strict`, which implies this. All keys in the
+	// request must correspond to fields of the output struct, otherwise this
+	// produces an error with HTTP status 400 which lists the unknown keys, such
+	// as `unknown fields ["three" "two"]`. For JSON, top-level keys are matched
+	// to fields by the rules of "encoding/json", and unknown keys of nested
+	// objects are rejected via `json.Decoder.DisallowUnknownFields`, which
+	// reports only the first one in its own format, such as `unknown field
+	// "two"`. Disabled by default.
+	RejectUnknown bool
+
+	// Rejects requests which declare a content type but have no body, or an
+	// empty body, with HTTP status 400, in `rd.DecodeWith` and `rd.DownloadWith`.
+	// Useful for catching buggy clients which set `Content-Type` but forget the
+	// payload. By default, a missing JSON body is a no-op, and empty bodies are
+	// decoded as usual, which for some content types means no fields. Doesn't
+	// apply to requests without a content type, which use the URL query.
+	// Disabled by default.
+	RequireBody bool
+
+	// Limit on the size of JSON, XML, and CSV bodies, in bytes, for
+	// `rd.DecodeWith` and `rd.DownloadWith`. Bodies exceeding the limit produce
+	// an error with HTTP status 413. When positive, overrides `rd.JsonLimit`.
+	// Zero means `rd.JsonLimit`.
+	JsonLimit int64
+
+	// Enables streaming decoding of URL-encoded bodies in `rd.DecodeWith`, via
+	// `rd.DecodeFormReader`, which skips keys that don't correspond to any field
+	// of the output, instead of building the entire form. Reduces peak memory for
+	// large forms. Doesn't populate `(*http.Request).PostForm`. Disabled by
+	// default.
+	StreamForm bool
+
+	// Maximum amount of memory, in bytes, for the non-file parts of multipart
+	// bodies in `rd.DecodeWith` and `rd.DownloadWith`, passed to
+	// `rd.Form.DownloadMultipartWith`. Files which don't fit are stored on disk.
+	// Useful for capping memory use far below the default, or raising it for
+	// large uploads. Zero means `rd.BufSize`.
+	MaxMultipartMem int64
+
+	// Name of a request header, such as `Prefer`, which allows clients to choose
+	// between strict and lenient decoding per request, overriding `.Strict`.
+	// Consulted only by `rd.DecodeWith`. Recognized preferences, case-insensitive:
+	// "handling=strict" and "handling=lenient" as defined in RFC 7240, and their
+	// short forms "strict" and "lenient". Other preferences are ignored. When
+	// several are present, the last one wins. Empty means disabled.
+	PreferHeader string
+
+	// Rejects output types in `rd.Form.DecodeWith` where several fields have the
+	// same name, such as fields of two embedded structs, with HTTP status 500,
+	// since this is a programmer error. By default, every field with the name is
+	// decoded from the same key. Unlike "encoding/json", which silently ignores
+	// ambiguous fields at the same depth, and prefers shallower fields to deeper
+	// ones. Prefixes of embedded structs avoid the ambiguity; see `rd.Form`.
+	// The check is performed once per type and tag, and cached together with
+	// the field metadata. Disabled by default.
+	RejectAmbiguous bool
+
+	// Enables exhaustive validation in `rd.Form.DecodeWith`. Every value provided
+	// for a field must parse cleanly, including all elements of slices and
+	// extra values for non-slice fields, which are otherwise ignored. Implies
+	// `.AllErrors`. Disabled by default.
+	StrictTypes bool
+
+	// Enables error aggregation in `rd.Form.DecodeWith`. Instead of stopping at
+	// the first failure, decoding continues through all fields, and the
+	// failures are reported together as `rd.Errs` of `rd.FieldErr`, one per
+	// field, with HTTP status 400. Use `errors.Is` and `errors.As` to inspect
+	// individual failures. Disabled by default.
+	AllErrors bool
+
+	// Enables strict arity checks in `rd.Form.DecodeWith`, without the other
+	// checks of `.Strict`, which implies this. Multiple values for a field which
+	// is not a list are rejected with HTTP status 400, instead of using the first
+	// value. This also applies to map entries, keys of combined fields, and the
+	// JSON key; see `rd.RegisterCombiner` and `.JsonKey`. Disabled by default.
+	StrictArity bool
+
+	// Name of the struct field tag used by `rd.Form.DecodeWith` for field names,
+	// such as "form" or "query", for APIs where form field names differ from JSON
+	// names. Fields without this tag are ignored, just like fields without the
+	// "json" tag by default. Tag options such as ",omitempty" and the name "-"
+	// are treated the same way as in the "json" tag. Doesn't affect the "json"
+	// tag used by the JSON decoder, or by the JSON key; see `.JsonKey`. Empty
+	// means "json".
+	Tag string
+
+	// Priority list of struct field tags used by `rd.Form.DecodeWith` for field
+	// names, such as "form" then "json", or "db" then "json" for APIs where
+	// inbound names match database columns. Each field uses the first tag from
+	// the list which is present on the field, including its options, even when
+	// the name is "-", which allows excluding a field which has other tags.
+	// Fields without any of these tags are ignored. Applies wherever `.Tag`
+	// applies, and overrides it when non-empty. Tag names must not contain
+	// commas. Empty means `.Tag`.
+	Tags []string
+
+	// Enables case-insensitive matching of form keys to field names in
+	// `rd.Form.DecodeWith`, for clients which are inconsistent about casing,
+	// such as sending "EmbedStr" for "embedStr". An exact match is always
+	// preferred. Otherwise, the form keys are indexed by their lowercased
+	// versions, once per call. When several keys differ only in case and none
+	// of them matches exactly, the last one in map iteration order wins, which
+	// means the choice is unspecified. Disabled by default, because exact
+	// matching is faster.
+	CaseInsensitive bool
+
+	// Enables simpler case-insensitive matching in `rd.Form.DecodeWith`, for
+	// clients which use uniform casing, such as "OUTERSTR" or "outerstr" for
+	// "outerStr". Form keys and field names are matched by their lowercased
+	// versions, without preferring exact matches. When several keys differ only
+	// in case, their values are combined in the sorted order of the keys, so
+	// for non-list fields the first key in that order wins, such as "OuterStr"
+	// before "outerStr"; in strict mode, such collisions for non-list fields are
+	// rejected; see `.Strict`. Fields whose names differ only in case receive
+	// the same values. Doesn't apply to map entries or keys of combined fields.
+	// Takes precedence over `.CaseInsensitive`. Disabled by default.
+	LowercaseKeys bool
+
+	// Optional sentinel value, such as "__empty__", which allows clients to
+	// clear list fields in `rd.Form.DecodeWith` to an empty list rather than
+	// null. When the only value of a slice field, or a field implementing
+	// `rd.SliceParser`, is exactly the sentinel, slices are set to a non-nil
+	// empty slice, and `.ParseSlice` receives a non-nil empty slice. This
+	// distinguishes "clear to empty" from "leave unchanged", when the key is
+	// missing, and from "set to null" via a single empty string. The sentinel
+	// is parsed as a regular value in other cases, including for other fields,
+	// and when combined with other values. Empty means disabled.
+	EmptySentinel string
+
+	// Enables lenient parsing of booleans in `rd.Form.DecodeWith`, for HTML
+	// checkboxes, which submit "on", and for clients which send "1" and "0".
+	// Applies to boolean fields, pointers to them, and slices of them, but not
+	// to map values, or to types implementing `rd.Parser` or
+	// `encoding.TextUnmarshaler`. In addition to "true" and "false", accepts
+	// the following, case-insensitive:
+	//
+	//   * "t", "1", "on", "yes" for true
+	//   * "f", "0", "off", "no" for false
+	//
+	// Disabled by default: `rd.Parse` accepts only "true" and "false".
+	LenientBool bool
+
+	// Disables null-zeroing in `rd.Form.DecodeWith`: keys with null values, such
+	// as "name=", leave the fields unchanged, as if they were missing, instead
+	// of zeroing them. Doesn't apply to map entries, or to keys of combined
+	// fields; see `rd.RegisterCombiner`. When combined with `.DefaultOnNull`,
+	// fields with defaults use them instead. See `rd.Form.DecodeMerge`.
+	// Disabled by default.
+	SkipNull bool
+
+	// Enables the "default" tag in `rd.Form.DecodeWith` for keys with null
+	// values, such as "page=", which otherwise zero the field. Such keys then
+	// use the default instead, as if they were missing. Fields without the
+	// "default" tag are zeroed as usual. Disabled by default.
+	DefaultOnNull bool
+
+	// Disables the "default" tag in `rd.Form.DecodeWith`, including for
+	// `.DefaultOnNull`: missing keys leave the fields unchanged, which is useful
+	// for partial updates of existing values. See `rd.Form.DecodeMerge`.
+	// Disabled by default.
+	SkipDefaults bool
+
+	// Optional logger for diagnosing decoding issues. Receives messages about
+	// the detected content type and about which form fields were matched or
+	// skipped. Messages mention only content types, field names, and keys, never
+	// values, and are safe to log in production. Applies to `rd.DecodeWith`,
+	// `rd.DownloadWith`, and `rd.Form.DecodeWith`. Nil means disabled, with no
+	// overhead.
+	Log func(string)
+
+	// Optional hook for normalizing inputs in `rd.Form.DecodeWith`, such as
+	// trimming whitespace or lowercasing emails, without a custom `rd.Parser` for
+	// each field. Called with the field name and each raw value of the field,
+	// including every element of list fields, before nulls are detected and
+	// before any parsing, and the result is used instead. A value which becomes
+	// empty is treated as null. Values of "csv" fields are transformed before
+	// splitting. Also applies to values of map entries such as "name[key]", with
+	// the name of the map field, but not to their keys, nor to defaults, nor to
+	// combined fields; see `rd.RegisterCombiner`. Doesn't modify the form. Nil
+	// means disabled.
+	Transform func(name, input string) string
+
+	// Optional allow-list of field names for `rd.Form.DecodeWith`, for APIs where
+	// different callers may set different fields. When non-nil, only the fields
+	// whose names are in the set are decoded; keys of other fields are ignored,
+	// and in strict mode rejected with HTTP status 400; see `.Strict`. A nested
+	// field such as "inner.innerStr" is also allowed when any of its dotted
+	// prefixes, such as "inner", is allowed. The JSON key counts as a field of
+	// its own: when allowed, it may populate any fields; see `.JsonKey`. Fields
+	// tagged `rd:"querystring"` still receive unknown keys, but never keys of
+	// disallowed fields. Nil means all fields are allowed.
+	Allowed Set
+}
+
+// Callers should check `.Log` before calling, to avoid allocating arguments.
+func (self *Config) logf(pattern string, args ...interface{}) {
+	if self.Log != nil {
+		self.Log(fmt.Sprintf(pattern, args...))
+	}
+}
+
+func (self *Config) allows(name string) bool {
+	if self.Allowed == nil {
+		return true
+	}
+
+	for {
+		if self.Allowed.Has(name) {
+			return true
+		}
+
+		index := strings.LastIndexByte(name, '.')
+		if index < 0 {
+			return false
+		}
+		name = name[:index]
+	}
+}
+
+func (self *Config) isEmptySentinel(input []string) bool {
+	return self.EmptySentinel != `` && len(input) == 1 && input[0] == self.EmptySentinel
+}
+
+func (self *Config) allErrors() bool { return self.StrictTypes || self.AllErrors }
+
+func (self *Config) arity() bool { return self.Strict || self.StrictArity }
+
+// Used for `.Transform`. Copies the input, which belongs to the form.
+func (self *Config) transform(name string, input []string) []string {
+	if self.Transform == nil || input == nil {
+		return input
+	}
+
+	out := make([]string, len(input))
+	for i, val := range input {
+		out[i] = self.Transform(name, val)
+	}
+	return out
+}
+
+func (self *Config) rejectUnknown() bool { return self.Strict || self.RejectUnknown }
+
+func (self *Config) fold() bool { return self.CaseInsensitive || self.LowercaseKeys }
+
+/*
+Returns either a single tag or a comma-separated priority list, which is also
+the cache key for struct fields; see `tagGet`.
+*/
+func (self *Config) tag() string {
+	if len(self.Tags) == 1 {
+		return self.Tags[0]
+	}
+	if len(self.Tags) > 1 {
+		return strings.Join(self.Tags, `,`)
+	}
+	if self.Tag == `` {
+		return `json`
+	}
+	return self.Tag
+}
+
+func (self *Config) maxMultipartMem() int64 {
+	if self.MaxMultipartMem > 0 {
+		return self.MaxMultipartMem
+	}
+	return BufSize
+}
+
+func (self *Config) jsonLimit() int64 {
+	if self.JsonLimit > 0 {
+		return self.JsonLimit
+	}
+	return JsonLimit
+}
+
+// True if JSON decoding requires a custom pass over top-level fields.
+func (self *Config) jsonPass() bool {
+	return self.Coerce || self.JsonSliceParser || self.ZeroNull || self.JsonNumberString
+}
+
+// True if JSON decoding can't be done by streaming from the request body.
+func (self *Config) jsonBuffer() bool { return self.jsonPass() || self.rejectUnknown() }
+
+// Applies the preference from the request header specified by `.PreferHeader`.
+func (self *Config) prefer(req *http.Request) {
+	if self.PreferHeader == `` {
+		return
+	}
+
+	for _, val := range req.Header.Values(self.PreferHeader) {
+		for _, pref := range strings.Split(val, `,`) {
+			index := strings.IndexByte(pref, ';')
+			if index >= 0 {
+				pref = pref[:index]
+			}
+			pref = strings.ReplaceAll(strings.TrimSpace(pref), `"`, ``)
+
+			switch strings.ToLower(pref) {
+			case `strict`, `handling=strict`:
+				self.Strict = true
+			case `lenient`, `handling=lenient`:
+				self.Strict = false
+			}
+		}
+	}
+}
+
+func (self *Config) logContentType(req *http.Request, typ string) {
+	if typ == `` {
+		if reqHasBody(req) {
+			self.logf(`missing content type for request with body`)
+		} else {
+			self.logf(`missing content type, using URL query`)
+		}
+		return
+	}
+	self.logf(`content type %q`, typ)
+}
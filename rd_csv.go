@@ -0,0 +1,52 @@
+package rd
+
+import "net/http"
+
+/*
+Implements `rd.Decoder` for CSV via "encoding/csv". The first row is the header;
+each subsequent row is decoded into a new element of the output, which must be
+a non-nil pointer to a slice of structs, or of pointers to structs. Header
+columns are matched to fields by the "json" field tag, like form keys in
+`rd.Form`, including dotted names of nested fields such as "inner.innerStr".
+Cells are parsed via `rd.Parse`, with the following tag options of `rd.Form`:
+`rd:"base=N"`, `rd:"unix=U"`, and the "layout" tag. Other options are ignored.
+Columns without matching fields are ignored; fields without matching columns,
+and fields whose cells are empty, are left zero. When several columns match the
+same field, the first one wins. A leading UTF-8 BOM is ignored.
+*/
+type Csv []byte
+
+/*
+Fully downloads the request body and stores it as-is, like
+`rd.Json.Download`. Used by `rd.Download`.
+*/
+func (self *Csv) Download(req *http.Request) error {
+	return (*Json)(self).Download(req)
+}
+
+// Downloads the request body, like `rd.Json.DownloadLimited`.
+func (self *Csv) DownloadLimited(req *http.Request, limit int64) error {
+	return (*Json)(self).DownloadLimited(req, limit)
+}
+
+// Clears the slice, preserving the capacity if any.
+func (self *Csv) Zero() { (*Json)(self).Zero() }
+
+/*
+Implement `rd.Decoder`, decoding rows into a slice. On success, the previous
+elements of the output are discarded; on failure, the output is left unchanged.
+Malformed CSV and unparsable cells produce errors with
+HTTP status 400, which mention the row, starting at 0 after the header, and the
+field. Unsupported outputs produce errors with HTTP status 500.
+*/
+func (self Csv) Decode(out interface{}) error { return decodeCsv(self, out) }
+
+// Implement `rd.Haserer` by calling `rd.Csv.Set`.
+func (self Csv) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Returns an instance of `rd.Set` with the names of the
+header columns, which correspond to fields of each element of the output.
+Panics on a malformed header, with an error with HTTP status 400.
+*/
+func (self Csv) Set() Set { return parseCsvSet(self) }
@@ -0,0 +1,300 @@
+package rd
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	r "reflect"
+	"strconv"
+	"time"
+)
+
+/*
+Inverse of `rd.Form.Decode`: encodes the fields of a struct, or a pointer to a
+struct, into `url.Values`, using the same field names, from the "json" tag.
+Useful for building outbound requests and for tests. Shortcut for
+`rd.EncodeFormWith` with the zero config. A nil pointer produces nil.
+*/
+func EncodeForm(val interface{}) (url.Values, error) {
+	return EncodeFormWith(val, Config{})
+}
+
+/*
+Encodes the fields of a struct, or a pointer to a struct, into `url.Values`,
+symmetrically with `rd.Form.DecodeWith`, which decodes the result into an equal
+struct. Uses the field tag from the config; see `rd.Config.Tag` and
+`rd.Config.Tags`. Settings which affect matching of form keys to fields, such
+as `rd.Config.JsonKey` and `rd.Config.LowercaseKeys`, apply to the "all"
+option described below. Other settings are ignored. Rules:
+
+	* Values are formatted via `encoding.TextMarshaler`, then
+	  `encoding.BinaryMarshaler`, falling back on `strconv` for numbers and
+	  booleans. Strings are used as-is. Durations are formatted like "30s".
+	  Types without an inverse of their parsing, such as types which implement
+	  `rd.Parser` but not `encoding.TextMarshaler`, produce errors.
+	* The options of the "rd" and "layout" tags apply, such as integer bases and
+	  time layouts; see `rd.Form`.
+	* Slices and arrays become repeated values, one per element.
+	* Maps become entries such as "name[key]".
+	* Nested structs become fields such as "inner.innerStr".
+	* Nil pointers are omitted, as well as fields behind them.
+	* Zero values are included, unless the field has the "omitempty" tag option,
+	  which omits zero and empty values, but not pointers to them.
+	* Fields tagged `rd:"querystring"` contribute their values as-is. With the
+	  "all" option, keys of other fields are skipped.
+	* Fields with a combiner are omitted; see `rd.RegisterCombiner`.
+
+Never panics; invalid inputs produce errors.
+*/
+func EncodeFormWith(val interface{}, conf Config) (_ url.Values, err error) {
+	defer rescue(&err)
+
+	root := r.ValueOf(val)
+	for root.Kind() == r.Ptr {
+		if root.IsNil() {
+			return nil, nil
+		}
+		root = root.Elem()
+	}
+
+	if !root.IsValid() {
+		return nil, nil
+	}
+	if root.Kind() != r.Struct {
+		return nil, fmt.Errorf(`unable to encode %v as form: expected struct`, root.Type())
+	}
+
+	out := url.Values{}
+	root = addressable(root)
+
+	fields := loadTagFields(root.Type(), conf.tag())
+	for _, field := range fields {
+		err := encodeField(out, root, field, fields, &conf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func encodeField(out url.Values, root r.Value, field jsonField, fields []jsonField, conf *Config) error {
+	val, ok := valueAt(root, field.Path)
+	if !ok {
+		return nil
+	}
+
+	if field.Kind == fieldQuery {
+		return errEncodeField(`querystring`, encodeQuery(out, val, field, fields, conf))
+	}
+	if _, ok := loadCombiner(field.Type); ok {
+		return nil
+	}
+
+	if field.Omit && isEmptyValue(val) {
+		return nil
+	}
+
+	val, ok = derefValue(val)
+	if !ok {
+		return nil
+	}
+
+	if val.Kind() == r.Map {
+		return encodeMap(out, val, field)
+	}
+
+	if isListOut(val) {
+		for i := range iter(val.Len()) {
+			str, err := formatElem(val.Index(i), field.opts())
+			if err != nil {
+				return errEncodeField(field.Name, err)
+			}
+			out.Add(field.Name, str)
+		}
+		return nil
+	}
+
+	// Encoded via nested fields, unless the struct has custom parsing.
+	if val.Kind() == r.Struct && !isScalarParser(val) && val.Type() != typeIPNet {
+		if _, ok := val.Addr().Interface().(SliceParser); ok {
+			return errEncodeField(field.Name, fmt.Errorf(`unable to encode %v: implements rd.SliceParser but not encoding.TextMarshaler`, val.Type()))
+		}
+		return nil
+	}
+
+	str, err := formatValue(val, field.opts())
+	if err != nil {
+		return errEncodeField(field.Name, err)
+	}
+	out.Add(field.Name, str)
+	return nil
+}
+
+/*
+With the "all" option, the field also holds the keys of other fields, which are
+skipped to avoid duplicating their values.
+*/
+func encodeQuery(out url.Values, val r.Value, field jsonField, fields []jsonField, conf *Config) error {
+	val, ok := derefValue(val)
+	if !ok {
+		return nil
+	}
+
+	var src url.Values
+	if val.Type().ConvertibleTo(typeValues) {
+		src = val.Convert(typeValues).Interface().(url.Values)
+	} else {
+		str, err := formatValue(val, parseDefault)
+		if err != nil {
+			return err
+		}
+		src, err = url.ParseQuery(str)
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, vals := range src {
+		if field.All && isKnownKey(key, fields, conf) {
+			continue
+		}
+		out[key] = append(out[key], vals...)
+	}
+	return nil
+}
+
+// Entries with nil values are encoded as empty strings, which decode as zero.
+func encodeMap(out url.Values, val r.Value, field jsonField) error {
+	iter := val.MapRange()
+	for iter.Next() {
+		key, err := formatElem(iter.Key(), parseDefault)
+		if err != nil {
+			return errEncodeField(field.Name, err)
+		}
+
+		str, err := formatElem(iter.Value(), field.opts())
+		if err != nil {
+			return errEncodeField(field.Name, err)
+		}
+		out.Add(field.Name+`[`+key+`]`, str)
+	}
+	return nil
+}
+
+// Nil pointers are encoded as empty strings, which decode as zero.
+func formatElem(val r.Value, opts parseOpts) (string, error) {
+	val, ok := derefValue(val)
+	if !ok {
+		return ``, nil
+	}
+	return formatValue(addressable(val), opts)
+}
+
+/*
+Inverse of `parseWith`. The value must be addressable, because methods such as
+`.MarshalText` may be defined on pointers.
+*/
+func formatValue(val r.Value, opts parseOpts) (string, error) {
+	typ := val.Type()
+
+	if typ == typeTime && opts.Layout != `` {
+		return val.Interface().(time.Time).Format(opts.Layout), nil
+	}
+	if typ == typeUrl {
+		return val.Addr().Interface().(*url.URL).String(), nil
+	}
+
+	ptr := val.Addr().Interface()
+
+	text, _ := ptr.(encoding.TextMarshaler)
+	if text != nil {
+		out, err := text.MarshalText()
+		return string(out), err
+	}
+
+	binary, _ := ptr.(encoding.BinaryMarshaler)
+	if binary != nil {
+		out, err := binary.MarshalBinary()
+		return string(out), err
+	}
+
+	if _, ok := ptr.(Parser); ok {
+		return ``, fmt.Errorf(`unable to encode %v: implements rd.Parser but not encoding.TextMarshaler`, typ)
+	}
+
+	if typ.AssignableTo(typeDuration) {
+		return time.Duration(val.Int()).String(), nil
+	}
+	if typ == typeIPNet {
+		return val.Addr().Interface().(*net.IPNet).String(), nil
+	}
+
+	switch kind := typ.Kind(); kind {
+	case r.Int8, r.Int16, r.Int32, r.Int64, r.Int:
+		return strconv.FormatInt(val.Int(), formatBase(opts.Base)), nil
+
+	case r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uint:
+		return strconv.FormatUint(val.Uint(), formatBase(opts.Base)), nil
+
+	case r.Float32, r.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, typeBits(typ)), nil
+
+	case r.Bool:
+		return strconv.FormatBool(val.Bool()), nil
+
+	case r.String:
+		return val.String(), nil
+
+	default:
+		if typ.ConvertibleTo(typeBytes) {
+			return string(val.Convert(typeBytes).Bytes()), nil
+		}
+		return ``, fmt.Errorf(`unable to encode %v: unsupported kind %v`, typ, kind)
+	}
+}
+
+// Base 0 auto-detects prefixes when parsing, and accepts decimal.
+func formatBase(base int) int {
+	if base == 0 {
+		return 10
+	}
+	return base
+}
+
+/*
+Used for the "omitempty" tag option. Like in "encoding/json", empty strings,
+slices, and maps are empty, and so are nil pointers, but not pointers to zero
+values. Unlike "encoding/json", zero structs are also empty.
+*/
+func isEmptyValue(val r.Value) bool {
+	switch val.Kind() {
+	case r.String, r.Slice, r.Map:
+		return val.Len() == 0
+	case r.Ptr, r.Interface:
+		return val.IsNil()
+	default:
+		return val.IsZero()
+	}
+}
+
+// Returns false if the value is a nil pointer, possibly multi-level.
+func derefValue(val r.Value) (r.Value, bool) {
+	for val.Kind() == r.Ptr {
+		if val.IsNil() {
+			return val, false
+		}
+		val = val.Elem()
+	}
+	return val, true
+}
+
+// Copies the value if it's not addressable, such as map keys and values.
+func addressable(val r.Value) r.Value {
+	if val.CanAddr() {
+		return val
+	}
+	out := r.New(val.Type()).Elem()
+	out.Set(val)
+	return out
+}
@@ -1,11 +1,15 @@
 package rd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	r "reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 /*
@@ -40,12 +44,7 @@ func (self Err) AppendTo(buf []byte) []byte {
 	cause := self.Cause
 	if cause != nil {
 		buf = append(buf, `: `...)
-		impl, _ := cause.(interface{ AppendTo([]byte) []byte })
-		if impl != nil {
-			buf = impl.AppendTo(buf)
-		} else {
-			buf = append(buf, cause.Error()...)
-		}
+		buf = appendErr(buf, cause)
 	}
 
 	return buf
@@ -75,10 +74,215 @@ func (self Err) Format(out fmt.State, verb rune) {
 	_, _ = out.Write(self.AppendTo(nil))
 }
 
+/*
+Describes a failure to decode a specific field. Returned by decoders which
+aggregate errors, such as `rd.Form.DecodeStrictTypes`, as part of `rd.Errs`.
+*/
+type FieldErr struct {
+	Name  string `json:"name"`
+	Cause error  `json:"cause"`
+}
+
+// Implement a hidden interface in "errors".
+func (self FieldErr) Unwrap() error { return self.Cause }
+
+// Implement the `error` interface.
+func (self FieldErr) Error() string { return bytesString(self.AppendTo(nil)) }
+
+// Appends the error representation. Used internally by `.Error`.
+func (self FieldErr) AppendTo(buf []byte) []byte {
+	buf = append(buf, `invalid field `...)
+	buf = strconv.AppendQuote(buf, self.Name)
+	if self.Cause != nil {
+		buf = append(buf, `: `...)
+		buf = appendErr(buf, self.Cause)
+	}
+	return buf
+}
+
+/*
+Combines multiple errors, reporting all of them rather than only the first.
+Supports `errors.Is` and `errors.As`, which find the first matching error. Since
+Go 1.20, they use `.Unwrap`; before that, `.Is` and `.As`.
+*/
+type Errs []error
+
+// Implement a hidden interface in "errors".
+func (self Errs) Unwrap() []error { return self }
+
+// Implement a hidden interface in "errors", for Go versions before 1.20.
+func (self Errs) Is(err error) bool {
+	for _, val := range self {
+		if errors.Is(val, err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Implement a hidden interface in "errors", for Go versions before 1.20.
+func (self Errs) As(out interface{}) bool {
+	for _, val := range self {
+		if errors.As(val, out) {
+			return true
+		}
+	}
+	return false
+}
+
+// Implement the `error` interface.
+func (self Errs) Error() string { return bytesString(self.AppendTo(nil)) }
+
+// Appends the error representation, separating errors with "; ". Used
+// internally by `.Error`.
+func (self Errs) AppendTo(buf []byte) []byte {
+	for i, err := range self {
+		if i > 0 {
+			buf = append(buf, `; `...)
+		}
+		buf = appendErr(buf, err)
+	}
+	return buf
+}
+
+// Returns nil if there are no errors, and the only error if there's just one.
+func (self Errs) Err() error {
+	switch len(self) {
+	case 0:
+		return nil
+	case 1:
+		return self[0]
+	default:
+		return self
+	}
+}
+
+func appendErr(buf []byte, err error) []byte {
+	impl, _ := err.(interface{ AppendTo([]byte) []byte })
+	if impl != nil {
+		return impl.AppendTo(buf)
+	}
+	return append(buf, err.Error()...)
+}
+
+/*
+Describes malformed JSON encountered by the JSON parser bundled with this
+package, which is used by `rd.Json.Set`, `rd.Json.Haser`, `rd.Json.TrySet`, and
+strict decoding. Usually wrapped in `rd.Err` with HTTP status 400; use
+`errors.As` to extract it. At the end of input, the cause is `io.EOF`, which is
+detectable via `errors.Is`.
+*/
+type JsonSyntaxError struct {
+	Pos     int    `json:"pos"`     // Byte offset, starting at 0.
+	Line    int    `json:"line"`    // Line number, starting at 1.
+	Col     int    `json:"col"`     // Byte offset in the line, starting at 1.
+	Snippet string `json:"snippet"` // Source text at the position, truncated.
+	Cause   error  `json:"cause"`
+}
+
+// Implement a hidden interface in "errors".
+func (self JsonSyntaxError) Unwrap() error { return self.Cause }
+
+// Implement the `error` interface.
+func (self JsonSyntaxError) Error() string { return bytesString(self.AppendTo(nil)) }
+
+// Appends the error representation. Used internally by `.Error`.
+func (self JsonSyntaxError) AppendTo(buf []byte) []byte {
+	buf = append(buf, `invalid JSON syntax in position `...)
+	buf = strconv.AppendInt(buf, int64(self.Pos), 10)
+	buf = append(buf, ` (line `...)
+	buf = strconv.AppendInt(buf, int64(self.Line), 10)
+	buf = append(buf, `, column `...)
+	buf = strconv.AppendInt(buf, int64(self.Col), 10)
+	buf = append(buf, `)`...)
+	if self.Cause != nil {
+		buf = append(buf, `: `...)
+		buf = appendErr(buf, self.Cause)
+	}
+	return buf
+}
+
+/*
+If the cause is nil, it describes the unexpected text at the position, or the
+unexpected end of input.
+*/
+func newJsonSyntaxError(src string, pos int, cause error) JsonSyntaxError {
+	line := strings.LastIndexByte(src[:pos], '\n')
+	snip := jsonSnippet(src[pos:])
+
+	if cause == nil {
+		cause = jsonSyntaxCause(snip)
+	}
+
+	return JsonSyntaxError{
+		Pos:     pos,
+		Line:    strings.Count(src[:pos], "\n") + 1,
+		Col:     pos - line,
+		Snippet: snip,
+		Cause:   cause,
+	}
+}
+
+/*
+Adds the position to errors of "encoding/json", which provide it only as a
+field, for the given source. Syntax errors are converted to
+`rd.JsonSyntaxError`, whose cause is the original error. Type errors, which
+already mention the field, are prefixed with the position where the value ends,
+as reported by "encoding/json". Other errors are returned as-is.
+*/
+func errJsonPos(src []byte, err error) error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		pos := clampPos(int(syntax.Offset), len(src))
+		// The offset is after the invalid character.
+		if pos > 0 && strings.HasPrefix(syntax.Error(), `invalid character`) {
+			pos--
+		}
+		return newJsonSyntaxError(bytesString(src), pos, syntax)
+	}
+
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) {
+		return fmt.Errorf(`invalid JSON value in position %v: %w`, clampPos(int(typ.Offset), len(src)), err)
+	}
+	return err
+}
+
+func clampPos(pos, size int) int {
+	if pos < 0 {
+		return 0
+	}
+	if pos > size {
+		return size
+	}
+	return pos
+}
+
+const jsonSnippetLimit = 32
+
+func jsonSnippet(src string) string {
+	if len(src) > jsonSnippetLimit {
+		return src[:jsonSnippetLimit]
+	}
+	return src
+}
+
+// Describes the unexpected text, or the unexpected end of input.
+func jsonSyntaxCause(snip string) error {
+	if strings.TrimSpace(snip) == `` {
+		return fmt.Errorf(`unexpected %w`, io.EOF)
+	}
+	return fmt.Errorf(`unexpected %q`, snip)
+}
+
+// Errors which already have a status, such as internal errors, are kept as-is.
 func errBadReq(err error) error {
 	if err == nil {
 		return nil
 	}
+	if _, ok := err.(Err); ok {
+		return err
+	}
 	return Err{http.StatusBadRequest, err}
 }
 
@@ -100,13 +304,79 @@ func errParse(err error, input string, out r.Type) error {
 	return fmt.Errorf(`failed to parse %q into %v: %v`, input, out, err)
 }
 
+/*
+Adds the field name to a per-field decoding error, preserving the HTTP status,
+if any. Skipped when aggregating errors, where the name is already provided by
+`rd.FieldErr`.
+*/
+func errField(name string, err error, conf *Config) error {
+	if err == nil || conf.allErrors() {
+		return err
+	}
+	if impl, ok := err.(Err); ok {
+		return Err{impl.Status, fmt.Errorf(`failed to decode field %q: %w`, name, impl.Cause)}
+	}
+	return fmt.Errorf(`failed to decode field %q: %w`, name, err)
+}
+
+func errEncodeField(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf(`failed to encode field %q: %w`, name, err)
+}
+
 func errContentType(typ string) error {
 	if typ == `` {
 		return errBadReq(fmt.Errorf(`missing content type`))
 	}
-	return errBadReq(fmt.Errorf(`unsupported content type %q`, typ))
+	return Err{http.StatusUnsupportedMediaType, fmt.Errorf(`unsupported content type %q`, typ)}
 }
 
-var errJsonEof = errInternal(fmt.Errorf(`unexpected %w during JSON decoding`, io.EOF))
+func errContentEncoding(enc string) error {
+	return Err{http.StatusUnsupportedMediaType, fmt.Errorf(`unsupported content encoding %q`, enc)}
+}
+
+// Used for `rd.Config.RejectAmbiguous`. See `loadAmbiguous`.
+func errAmbiguousFields(typ r.Type, names []string) error {
+	if names == nil {
+		return nil
+	}
+	return errInternal(fmt.Errorf(`ambiguous fields %q in type %v`, names, typ))
+}
+
+func errMissingBody(typ string) error {
+	return errBadReq(fmt.Errorf(`missing request body for content type %q`, typ))
+}
+
+func errUnknownKeys(keys []string) error {
+	if !(len(keys) > 0) {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf(`unknown fields %q`, keys)
+}
+
+func errDisallowedKeys(keys []string) error {
+	if !(len(keys) > 0) {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf(`disallowed fields %q`, keys)
+}
+
+func errBodyShape(typ string, head []byte) error {
+	const limit = 32
+	if len(head) > limit {
+		head = head[:limit]
+	}
+	return errBadReq(fmt.Errorf(`content type %q doesn't match body starting with %q`, typ, head))
+}
+
+/*
+Cause of `rd.FieldErr` for fields with the "required" tag option which are
+missing from the input. See `rd.Form`. Detectable via `errors.Is`.
+*/
+var ErrMissing = errors.New(`missing required field`)
 
 var errUnreachable = errInternal(fmt.Errorf(`unexpected violation of internal invariant`))
@@ -1,11 +1,18 @@
 package rd
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	r "reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 /*
@@ -18,7 +25,8 @@ Similarities with "encoding/json":
 
 	* Uses reflection to decode into arbitrary outputs.
 
-	* Uses the "json" field tag.
+	* Uses the "json" field tag by default. A different tag, such as "form",
+	  can be specified via `rd.Config.Tag`.
 
 	* Supports embedded structs.
 
@@ -31,12 +39,113 @@ Differences from "encoding/json":
 
 	* The top-level value must be a struct.
 
-	* Doesn't support nested non-embedded structs.
+	* Supports nested non-embedded structs via dotted keys, such as
+	  "inner.innerStr" for the field tagged "innerStr" in the struct field
+	  tagged "inner". Intermediary pointers are allocated as needed.
 
 	* Decodes only into fields with a "json" name, ignoring un-named fields.
 
+	* Supports prefixing the names of the fields of an embedded struct via
+	  the "prefix" option in the "rd" tag of the embedded field, such as
+	  `rd:"prefix=embed_"`, which decodes the field tagged "embedStr" from
+	  the key "embed_embedStr". This avoids collisions between embedded
+	  structs with the same field names. Prefixes of nested embedded structs
+	  are concatenated. Embedded structs without the option are flattened as
+	  usual. JSON ignores the option, because "encoding/json" doesn't support
+	  it.
+
+	  When several fields have the same name, such as fields of two embedded
+	  structs, or a field and a field of an embedded struct, every such field
+	  is decoded from the same key. This differs from "encoding/json", which
+	  prefers the shallowest field, and ignores ambiguous fields at the same
+	  depth. To reject such types, use `rd.Config.RejectAmbiguous`.
+
+	* Supports the "required" option in the field tag, such as
+	  `json:"email,required"`. When any required fields are missing, decoding
+	  fails before modifying the output, with an error with HTTP status 400,
+	  which combines an `rd.FieldErr` for each missing field, with the cause
+	  `rd.ErrMissing`; see `rd.Errs`. Fields with null values, such as empty
+	  strings, count as missing. Nested required fields, such as
+	  "inner.innerStr", are required regardless of the parent field. The
+	  option refers only to form keys, ignoring the JSON key; see
+	  `rd.Config.JsonKey`.
+
+	* Supports the "default" field tag, such as `json:"page" default:"1"`.
+	  When the key of a field is missing, the default is decoded as if it
+	  were the only value of the key, following the usual rules, including
+	  the "csv" and "rd" options, and allocating intermediary pointers as
+	  needed. This also applies to empty forms. Keys with null values are
+	  not missing: they zero the field as usual, unless
+	  `rd.Config.DefaultOnNull` is set. An empty default such as
+	  `default:""` zeroes the field when the key is missing. Defaults are
+	  decoded before the JSON key, which overrides them; see
+	  `rd.Config.JsonKey`. Required fields are still reported as missing
+	  regardless of defaults. Invalid defaults are reported with HTTP status
+	  500. Disabled by `rd.Config.SkipDefaults`.
+
+	* Supports the "rd" field tag with additional options. A field tagged
+	  `rd:"querystring"` receives all keys which don't correspond to any other
+	  field, encoded as a URL query via `url.Values.Encode`. The field must be
+	  a string or implement `rd.Parser` or `encoding.TextUnmarshaler`, or be
+	  convertible to `url.Values`, such as `rd.Form` or `map[string][]string`,
+	  in which case it receives the keys and values directly, copied. A field
+	  tagged `rd:"querystring,all"` receives all keys, including those which
+	  correspond to other fields, which are also decoded as usual; this is
+	  useful for capturing dynamic filters such as "filter=a&filter=b" along
+	  with the known fields. Such fields also allow unknown keys in strict
+	  mode, like `rd:"querystring"`. A field tagged `rd:"base=N"` parses
+	  integers in the base N, as defined by `strconv.ParseInt`, which must be
+	  between 2 and 36, or 0. The latter auto-detects Go-style prefixes, such
+	  as "0xff", "0o17", "0b1010", at the cost of treating decimal inputs with
+	  leading zeros, such as "010", as octal. Applies to elements of slices
+	  and to map values, but not to map keys. Integers are decimal by default.
+	  A `time.Time` field tagged `rd:"unix=U"` parses numeric inputs as Unix
+	  timestamps in the unit U, which must be "s", "ms", "us", or "ns"; see
+	  `rd.Parse`. Seconds are the default. Options may be combined with
+	  commas, such as `rd:"in=query,unix=ms"`; see `rd.Binder`.
+
+	* Supports the "layout" field tag for `time.Time` fields, such as
+	  `json:"born" layout:"2006-01-02"`, parsing inputs via `time.Parse`
+	  with the given layout, instead of RFC 3339 or Unix timestamps. Applies
+	  to pointers and to elements of slices, and to map values. Inputs
+	  without a time zone produce times in UTC. Other fields ignore this tag.
+
+	* Supports the "csv" option in the field tag, such as `json:"ids,csv"`,
+	  for slice and array fields, and fields implementing `rd.SliceParser`.
+	  Each value is split on commas before parsing, so "ids=1,2,3" is decoded
+	  like "ids=1&ids=2&ids=3". The split is naive: commas can't be escaped
+	  or quoted, and empty parts such as in "1,,2" are kept. Repeated keys
+	  are still supported, and are split individually, in order. Other
+	  fields ignore this option.
+
+	* Supports array fields, such as `[2]float64` decoded from
+	  "p=1.5&p=2.5". The amount of values must match the array length,
+	  otherwise decoding fails with HTTP status 400. Arrays which implement
+	  `rd.Parser`, `encoding.TextUnmarshaler`, or
+	  `encoding.BinaryUnmarshaler` are parsed from a single value instead.
+
+	* Supports bracket notation for lists, as produced by many frontend
+	  libraries: "items[]" and "items[0]", "items[1]", and so on, are
+	  treated as "items". Indexed values are ordered by their numeric index,
+	  ignoring gaps. When several notations are combined, plain values come
+	  first, then values of "items[]", then indexed values.
+
+	* Supports map fields via bracket keys, such as "meta[color]" and
+	  "meta[size]" for the map field tagged "meta". Map values, and keys
+	  other than strings, are parsed like other fields. Nil maps are
+	  allocated as needed. Numeric subkeys such as "meta[0]" are also map
+	  keys, rather than list indexes.
+
+	* When a non-list field has multiple values, the first value wins, just
+	  like in `url.Values.Get`. This applies uniformly, including to pointer
+	  fields, nested fields, and the JSON key; see `rd.Config.JsonKey`. Note
+	  that "encoding/json" does the opposite for duplicate JSON keys: the last
+	  one wins. In strict mode, both are rejected; see `rd.Config.Strict`.
+
 	* For source fields which are "null", zeroes the corresponding fields of the
-	  output struct, instead of leaving them as-is. "null" is defined as:
+	  output struct, instead of leaving them as-is, unless
+	  `rd.Config.SkipNull` is set; see `rd.Form.DecodeMerge`. "null" is
+	  defined as:
 
 		* []string(nil)
 
@@ -44,6 +153,19 @@ Differences from "encoding/json":
 
 		* []string{``}
 
+	* Parse failures mention the field, including the dotted path of nested
+	  fields, such as `failed to decode field "inner.email": ...`. This
+	  includes errors returned by `rd.Parser` and `rd.SliceParser`, which
+	  remain accessible via `errors.Is` and `errors.As`. HTTP statuses of
+	  such errors are preserved. When aggregating errors, the field is
+	  reported via `rd.FieldErr` instead; see `rd.Config.AllErrors`.
+
+	* Doesn't support dynamically-typed `interface{}` fields, including
+	  pointers to them and slices of them, because form values carry no type
+	  information. Such fields are ignored when their keys are missing, and
+	  otherwise produce a parse failure. Unlike forms, `rd.Json` supports
+	  such fields, via "encoding/json".
+
 	* Has better performance.
 */
 type Form url.Values
@@ -61,14 +183,54 @@ func (self *Form) DownloadBody(req *http.Request, typ string) error {
 	}
 }
 
+/*
+Downloads the request body like `rd.Form.DownloadBody`, using the content type
+of the request, populating the receiver, then decodes into the output like
+`rd.Form.Decode`. Shortcut for handlers which accept only URL-encoded and
+multipart forms. Like `rd.Decode`, undoes `Content-Encoding`. Unlike
+`rd.Decode`, requests with other content types produce an error with HTTP
+status 415, and requests without a content type produce an error with HTTP
+status 400, instead of using the URL query. A nil request zeroes the receiver
+and leaves the output unchanged.
+*/
+func (self *Form) DecodeRequest(req *http.Request, out interface{}) (err error) {
+	defer rescue(&err)
+
+	if req == nil {
+		self.Zero()
+		return nil
+	}
+
+	defer errDecompressLimit(req, &err)
+	err = decompressBody(req)
+	if err != nil {
+		return err
+	}
+
+	err = self.DownloadBody(req, ContentType(req))
+	if err != nil {
+		return err
+	}
+	return self.Decode(out)
+}
+
 // Assumes that the request has a URL-encoded body, downloads that body as a
-// side effect, and populates the receiver.
+// side effect, and populates the receiver. Ignores a leading UTF-8 BOM.
 func (self *Form) DownloadForm(req *http.Request) error {
 	if req == nil {
 		self.Zero()
 		return nil
 	}
 
+	// Otherwise the first key would begin with the BOM. Skipped when the body has
+	// already been parsed.
+	if req.Body != nil && req.PostForm == nil {
+		err := stripBodyBom(req)
+		if err != nil {
+			return errBadReq(err)
+		}
+	}
+
 	err := req.ParseForm()
 	if err != nil {
 		return errBadReq(err)
@@ -81,6 +243,60 @@ func (self *Form) DownloadForm(req *http.Request) error {
 	return nil
 }
 
+/*
+Decodes a URL-encoded body from the reader into a struct, like
+`rd.Form.DecodeWith`, without building the entire form in memory. Keys which
+don't correspond to any field of the output are skipped while scanning, without
+allocating their values, which reduces peak memory for large bodies with
+mostly irrelevant keys. The remaining keys are decoded by `rd.Form.DecodeWith`,
+with the same semantics. The body is limited to 10 megabytes, like in
+`(*http.Request).ParseForm`, and each key to 64 kilobytes, including skipped
+ones; exceeding either limit produces an error with HTTP status 413. Malformed
+input produces an error with
+HTTP status 400, even in skipped keys. Ignores a leading UTF-8 BOM. Used by
+`rd.DecodeWith` with `rd.Config.StreamForm`.
+*/
+func DecodeFormReader(src io.Reader, outVal interface{}, conf Config) (err error) {
+	defer rescue(&err)
+
+	out, err := derefStruct(r.ValueOf(outVal))
+	if err != nil {
+		return err
+	}
+
+	fields := loadTagFields(out.Type(), conf.tag())
+	form, err := parseFormReader(src, formKeeper(fields, &conf))
+	if err != nil {
+		return err
+	}
+	return form.DecodeWith(outVal, conf)
+}
+
+/*
+Used by `rd.DecodeFormReader`. Keeps the keys which may affect decoding. Fields
+tagged `rd:"querystring"` receive unknown keys, which must be kept. Rejecting
+unknown keys and logging need the names of unknown keys, but not their values.
+*/
+func formKeeper(fields []jsonField, conf *Config) func(string) formKeep {
+	all := hasFieldKind(fields, fieldQuery)
+
+	return func(key string) formKeep {
+		if all || isKnownKey(key, fields, conf) {
+			return formKeepAll
+		}
+
+		name, _, ok := bracketKey(key)
+		if ok && isKnownKey(name, fields, conf) {
+			return formKeepAll
+		}
+
+		if conf.rejectUnknown() || conf.Log != nil {
+			return formKeepKey
+		}
+		return formKeepNone
+	}
+}
+
 /*
 Assumes that the request has a multipart body, downloads that body as a side
 effect, and populates the receiver. Uses the default buffer size of 32
@@ -142,6 +358,56 @@ func (self *Form) UnmarshalText(src []byte) error {
 	return self.Parse(string(src))
 }
 
+/*
+Like `rd.Form.Parse`, but splits key-value pairs on the given separator, such
+as ";" for legacy systems which produce queries such as "one=two;three=four".
+For "&", this is equivalent to `rd.Form.Parse`. Only the given separator splits
+pairs; other characters, including "&" and ";", are treated literally. Since a
+raw separator always splits, values containing the separator must escape it,
+such as "%3B" for ";"; a query such as "one=two;three" is ambiguous between a
+value "two;three" and two pairs, and always means two pairs. Otherwise keys and
+values are unescaped like in `url.ParseQuery`. On error, the receiver is
+unchanged.
+*/
+func (self *Form) ParseWith(src string, sep byte) error {
+	if sep == '&' {
+		return self.Parse(src)
+	}
+
+	out := Form{}
+	for src != `` {
+		var pair string
+		index := strings.IndexByte(src, sep)
+		if index >= 0 {
+			pair, src = src[:index], src[index+1:]
+		} else {
+			pair, src = src, ``
+		}
+		if pair == `` {
+			continue
+		}
+
+		key, val := pair, ``
+		index = strings.IndexByte(pair, '=')
+		if index >= 0 {
+			key, val = pair[:index], pair[index+1:]
+		}
+
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return err
+		}
+		val, err = url.QueryUnescape(val)
+		if err != nil {
+			return err
+		}
+		out[key] = append(out[key], val)
+	}
+
+	*self = out
+	return nil
+}
+
 // Implement `rd.Haser`. Returns true if the key is present in the query map,
 // regardless of its value.
 func (self Form) Has(key string) bool {
@@ -149,6 +415,32 @@ func (self Form) Has(key string) bool {
 	return ok
 }
 
+/*
+True if every given key is present in the form, regardless of values. Checks
+the form directly, without allocating a set like `rd.Form.Set`. True for no keys.
+*/
+func (self Form) HasAll(keys ...string) bool {
+	for _, key := range keys {
+		if !self.Has(key) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+True if any of the given keys is present in the form, regardless of values. See
+`rd.Form.HasAll`. False for no keys.
+*/
+func (self Form) HasAny(keys ...string) bool {
+	for _, key := range keys {
+		if self.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Implement `rd.Haserer` by returning self..
 func (self Form) Haser() Haser { return self }
 
@@ -164,15 +456,82 @@ func (self Form) Set() Set {
 	return out
 }
 
+/*
+Decodes the first value of the given key into the output, which must be a
+non-nil pointer, via `rd.Parse`, without decoding into a struct. Useful for
+endpoints which read only one or two parameters. Follows the same rules as
+fields of `rd.Form.Decode`: a missing key leaves the output unchanged, which
+allows to provide defaults, a null value zeroes it, and pointers are allocated
+as needed. Parse failures mention the key, and have HTTP status 400. Example:
+
+	page := 1
+	err := form.Value(`page`, &page)
+*/
+func (self Form) Value(key string, outVal interface{}) (err error) {
+	defer rescue(&err)
+	defer trans(&err, errBadReq)
+
+	out := r.ValueOf(outVal)
+	if out.Kind() != r.Ptr || out.IsNil() {
+		return errInternal(fmt.Errorf(`expected non-nil pointer, got %T`, outVal))
+	}
+	out = out.Elem()
+
+	input, ok := self[key]
+	if !ok {
+		return nil
+	}
+	if isSliceEmpty(input) {
+		out.Set(r.Zero(out.Type()))
+		return nil
+	}
+	return errField(key, parse(input[0], derefAlloc(out)), &Config{})
+}
+
+/*
+Decodes the first value of the given key as an integer, like `rd.Form.Value`.
+A missing key or a null value produce 0.
+*/
+func (self Form) Int(key string) (out int, err error) {
+	err = self.Value(key, &out)
+	return
+}
+
+/*
+Decodes the first value of the given key as a boolean, like `rd.Form.Value`.
+A missing key or a null value produce false.
+*/
+func (self Form) Bool(key string) (out bool, err error) {
+	err = self.Value(key, &out)
+	return
+}
+
 /*
 Implement `rd.Decoder`, decoding into a struct. See `rd.Form` for the decoding
 semantics.
 */
-func (self Form) Decode(outVal interface{}) (err error) {
+func (self Form) Decode(outVal interface{}) error {
+	return self.DecodeWith(outVal, Config{})
+}
+
+/*
+Decodes into a struct, like `rd.Form.Decode`, using the provided settings. See
+`rd.Config`.
+*/
+func (self Form) DecodeWith(outVal interface{}, conf Config) error {
+	return self.decodeWith(outVal, &conf, nil)
+}
+
+/*
+The set contains the names of top-level fields provided by another source,
+which are neither required nor defaulted here. See `rd.Merged`.
+*/
+func (self Form) decodeWith(outVal interface{}, conf *Config, other Set) (err error) {
 	if !(len(self) > 0) {
-		return nil
+		return self.decodeEmpty(outVal, conf, other)
 	}
 
+	defer rescue(&err)
 	defer trans(&err, errBadReq)
 
 	out, err := derefStruct(r.ValueOf(outVal))
@@ -180,38 +539,1063 @@ func (self Form) Decode(outVal interface{}) (err error) {
 		return err
 	}
 
-	for _, field := range loadJsonFields(out.Type()) {
-		err := self.decodeField(out, field)
+	fields := loadTagFields(out.Type(), conf.tag())
+	if conf.RejectAmbiguous {
+		err := errAmbiguousFields(out.Type(), loadAmbiguous(out.Type(), conf.tag()))
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+
+	src := self.source(fields, conf)
+
+	if conf.Strict {
+		err := src.checkAllowed(fields, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.rejectUnknown() {
+		err := src.checkUnknown(fields, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = src.checkRequired(fields, conf, other)
+	if err != nil {
+		return err
+	}
+
+	// Before the JSON key, which takes precedence over defaults.
+	err = src.decodeDefaults(out, fields, conf, other)
+	if err != nil {
+		return err
+	}
+
+	if conf.allows(conf.JsonKey) {
+		err = self.decodeJsonKey(outVal, conf.JsonKey, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs Errs
+
+	if conf.Log != nil {
+		src.logFields(fields, conf)
+	}
+
+	for _, field := range fields {
+		if field.Kind == fieldNormal && !conf.allows(field.Name) {
+			continue
+		}
+
+		var err error
+		if field.Kind == fieldQuery {
+			err = src.decodeQuery(out, field, fields, conf)
+		} else {
+			err = src.decodeField(out, field, conf)
+		}
+		if err == nil {
+			continue
+		}
+		if !conf.allErrors() {
+			return err
+		}
+		errs = append(errs, FieldErr{field.Name, err})
+	}
+	return errs.Err()
 }
 
-func (self Form) decodeField(root r.Value, field jsonField) error {
-	input, ok := self[field.Name]
-	if !ok {
-		return nil
+/*
+Decodes into a struct, like `rd.Form.Decode`, for partial updates of an
+existing value, with the given treatment of null values, such as empty
+strings; see `rd.Form` for the definition of "null". Missing keys always leave
+the fields unchanged, because the "default" tag is ignored; see
+`rd.Config.SkipDefaults`. Fields with the "required" tag option must still be
+present. The full matrix:
+
+	| key     | value     | `rd.MergeZero` | `rd.MergeSkip` |
+	|---------|-----------|----------------|----------------|
+	| missing | -         | unchanged      | unchanged      |
+	| present | null      | zeroed         | unchanged      |
+	| present | non-null  | decoded        | decoded        |
+
+`rd.MergeZero` is the default behavior of `rd.Form.Decode`, and lets clients
+clear fields by sending empty keys. `rd.MergeSkip` is a shortcut for
+`rd.Form.DecodeWith` with `rd.Config.SkipNull`, and is useful for HTML forms
+which submit every input, including those left empty.
+*/
+func (self Form) DecodeMerge(outVal interface{}, mode Merge) error {
+	return self.DecodeWith(outVal, Config{SkipNull: mode == MergeSkip, SkipDefaults: true})
+}
+
+// Treatment of null values in `rd.Form.DecodeMerge`.
+type Merge byte
+
+const (
+	// Null values zero the fields. The default behavior of `rd.Form.Decode`.
+	MergeZero Merge = iota
+
+	// Null values are ignored, leaving the fields unchanged.
+	MergeSkip
+)
+
+/*
+Decodes into a struct, like `rd.Form.Decode`, reporting the failures of all
+fields rather than only the first. Shortcut for `rd.Form.DecodeWith` with
+`rd.Config.AllErrors`; see that setting for details. Fields which decode
+successfully are populated even when other fields fail.
+*/
+func (self Form) DecodeAll(outVal interface{}) error {
+	return self.DecodeWith(outVal, Config{AllErrors: true})
+}
+
+/*
+Decodes into a struct, like `rd.Form.Decode`, validating every provided value
+and reporting all failures rather than only the first. Shortcut for
+`rd.Form.DecodeWith` with `rd.Config.StrictTypes`; see that setting for
+details.
+*/
+func (self Form) DecodeStrictTypes(outVal interface{}) error {
+	return self.DecodeWith(outVal, Config{StrictTypes: true})
+}
+
+/*
+Decodes into a struct, like `rd.Form.Decode`, decoding only the fields whose
+names are in the given set, and ignoring others. Unlike `rd.Config.Allowed`, a
+nil set allows nothing, which is the safe default. Shortcut for
+`rd.Form.DecodeWith` with `rd.Config.Allowed`; see that setting for details. To
+reject disallowed fields instead of ignoring them, use `rd.Form.DecodeWith`
+with both `rd.Config.Allowed` and `rd.Config.Strict`.
+*/
+func (self Form) DecodeAllowed(allowed Set, outVal interface{}) error {
+	if allowed == nil {
+		allowed = Set{}
 	}
+	return self.DecodeWith(outVal, Config{Allowed: allowed})
+}
 
-	if isSliceEmpty(input) {
-		zeroAt(root, field.Path)
-		return nil
+/*
+Decodes into a struct, like `rd.Form.Decode`, using a prototype value as the
+source of defaults. The prototype must be a struct of the same type as the
+output, or a pointer to such a struct. First, the prototype is copied into the
+output, then the form is decoded over it. Fields absent from the form retain
+the values from the prototype; fields present in the form override them,
+following the usual rules, including zeroing for empty values. Pointers, maps,
+and slices reachable through decodable fields are copied rather than shared, so
+decoding never modifies the prototype. Other fields, such as those tagged with
+"-", are copied shallowly.
+*/
+func (self Form) DecodeWithDefaults(defaults, outVal interface{}) (err error) {
+	defer rescue(&err)
+
+	out, err := derefStruct(r.ValueOf(outVal))
+	if err != nil {
+		return err
 	}
 
-	out := derefAllocAt(root, field.Path)
+	src := r.ValueOf(defaults)
+	for src.Kind() == r.Ptr && !src.IsNil() {
+		src = src.Elem()
+	}
+	if !src.IsValid() || src.Type() != out.Type() {
+		return errInternal(fmt.Errorf(`expected defaults of type %v, got %v`, out.Type(), r.TypeOf(defaults)))
+	}
 
-	impl, _ := out.Addr().Interface().(SliceParser)
-	if impl != nil {
-		return impl.ParseSlice(input)
+	out.Set(src)
+	clonePtrs(out, (&Config{}).tag())
+	return self.Decode(outVal)
+}
+
+/*
+Decodes into a struct, like `rd.Form.DecodeWith`, and additionally returns the
+exact raw inputs used for each decoded field, keyed by field name, and for map
+fields, also the inputs of each entry, keyed as in the source. Useful for
+reconstructing a canonical representation of the decoded fields, for example
+when verifying a signature computed over specific fields. Keys which don't
+correspond to any field are excluded. The returned slices are shared with the
+receiver and must not be mutated.
+*/
+func (self Form) DecodeRaw(outVal interface{}, conf Config) (Form, error) {
+	err := self.DecodeWith(outVal, conf)
+	if err != nil || !(len(self) > 0) {
+		return nil, err
+	}
+
+	fields := loadTagFields(derefType(r.TypeOf(outVal)), conf.tag())
+	src := self.source(fields, &conf)
+
+	var out Form
+	for _, field := range fields {
+		if field.Kind != fieldNormal || !conf.allows(field.Name) {
+			continue
+		}
+
+		input, ok := src[field.Name]
+		if ok {
+			out = out.with(field.Name, input)
+		}
+
+		if !isMapType(field.Type) {
+			continue
+		}
+		for key, input := range src {
+			if _, ok := mapSubKey(field.Name, key); ok {
+				out = out.with(key, input)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Allocates the form if necessary.
+func (self Form) with(key string, val []string) Form {
+	if self == nil {
+		self = make(Form)
+	}
+	self[key] = val
+	return self
+}
+
+/*
+Decodes into a struct, like `rd.Form.DecodeWith`, and additionally returns the
+names of the decoded fields which were present in the form. Presence is
+determined by keys, not by decoded values: a field provided as "0" or as an
+empty string is included, even though it decodes to the zero value. Useful for
+PATCH semantics, which must distinguish fields explicitly set to zero from
+absent fields. Fields decoded via `rd.RegisterCombiner` are included when any
+of their keys is present. Keys which don't correspond to any field are
+excluded. When nothing is present, the set is nil.
+*/
+func (self Form) DecodeTracked(outVal interface{}, conf Config) (Set, error) {
+	err := self.DecodeWith(outVal, conf)
+	if err != nil || !(len(self) > 0) {
+		return nil, err
+	}
+
+	fields := loadTagFields(derefType(r.TypeOf(outVal)), conf.tag())
+	src := self.source(fields, &conf)
+
+	var out Set
+	for _, field := range fields {
+		if field.Kind != fieldNormal || !conf.allows(field.Name) || !src.present(field) {
+			continue
+		}
+		if out == nil {
+			out = make(Set)
+		}
+		out.Add(field.Name)
+	}
+	return out, nil
+}
+
+/*
+Decodes into a struct, like `rd.Form.Decode`, and populates a separate presence
+struct, which mirrors the output struct but has boolean fields with the same
+"json" names. Each boolean field is set to true if the corresponding field was
+present in the form, as determined by `rd.Form.DecodeTracked`, and false
+otherwise. A boolean field which corresponds to a nested struct, such as
+"inner", is true if any of its nested fields was present. Presence fields can
+be nested to mirror nested fields, such as "inner.innerStr". Presence fields of
+other types are ignored. An ergonomic alternative to pointer fields for
+distinguishing "explicitly set to zero" from "absent". Presence fields which
+don't correspond to any field of the output produce an error with HTTP status
+500.
+*/
+func (self Form) DecodeWithPresenceStruct(outVal, presenceVal interface{}) error {
+	presence, err := derefStruct(r.ValueOf(presenceVal))
+	if err != nil {
+		return err
+	}
+
+	typ := derefType(r.TypeOf(outVal))
+	if typ == nil || typ.Kind() != r.Struct {
+		return errInvalidPtr(r.ValueOf(outVal))
+	}
+
+	known := loadJsonFields(typ)
+	fields := loadJsonFields(presence.Type())
+	for _, field := range fields {
+		if field.Kind == fieldNormal && isBoolType(field.Type) && !hasJsonField(known, field.Name) {
+			return errInternal(fmt.Errorf(`presence field %q doesn't correspond to any field of %v`, field.Name, typ))
+		}
+	}
+
+	tracked, err := self.DecodeTracked(outVal, Config{})
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if field.Kind != fieldNormal || !isBoolType(field.Type) {
+			continue
+		}
+
+		ok := isTracked(tracked, field.Name)
+		if ok {
+			derefAllocAt(presence, field.Path).SetBool(true)
+		} else {
+			zeroAt(presence, field.Path)
+		}
+	}
+	return nil
+}
+
+// True if the name or any of its nested names is in the set.
+func isTracked(tracked Set, name string) bool {
+	if tracked.Has(name) {
+		return true
 	}
+	for key := range tracked {
+		if strings.HasPrefix(key, name) && len(key) > len(name) && key[len(name)] == '.' {
+			return true
+		}
+	}
+	return false
+}
+
+func isBoolType(typ r.Type) bool { return derefType(typ).Kind() == r.Bool }
 
-	if out.Kind() == r.Slice {
-		return parseSlice(input, out)
+func (self Form) decodeJsonKey(out interface{}, key string, conf *Config) error {
+	if key == `` {
+		return nil
 	}
 
-	return Parse(input[0], out)
+	input := self[key]
+	if isSliceEmpty(input) {
+		return nil
+	}
+
+	if conf.arity() {
+		err := checkArity(key, input)
+		if err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(stringToBytesUnsafe(input[0]), out)
+}
+
+// An empty form decodes nothing, but may be missing required fields.
+func (self Form) decodeEmpty(outVal interface{}, conf *Config, other Set) error {
+	typ := derefType(r.TypeOf(outVal))
+	if typ == nil || typ.Kind() != r.Struct {
+		return nil
+	}
+
+	fields := loadTagFields(typ, conf.tag())
+	err := self.checkRequired(fields, conf, other)
+	if err != nil || conf.SkipDefaults || !hasDefaults(fields) {
+		return errBadReq(err)
+	}
+	return self.decodeOnlyDefaults(outVal, fields, conf, other)
+}
+
+func (self Form) decodeOnlyDefaults(outVal interface{}, fields []jsonField, conf *Config, other Set) (err error) {
+	defer rescue(&err)
+	defer trans(&err, errBadReq)
+
+	out, err := derefStruct(r.ValueOf(outVal))
+	if err != nil {
+		return err
+	}
+	return self.decodeDefaults(out, fields, conf, other)
+}
+
+/*
+Decodes the "default" tag of each field whose key is missing. Keys with null
+values are not missing; see `rd.Config.DefaultOnNull`. Fields provided by
+another source are not missing either; see `.decodeWith`.
+*/
+func (self Form) decodeDefaults(root r.Value, fields []jsonField, conf *Config, other Set) error {
+	if conf.SkipDefaults {
+		return nil
+	}
+
+	for _, field := range fields {
+		if field.Kind != fieldNormal || field.Default == nil || !conf.allows(field.Name) ||
+			self.present(field) || other.Has(rootName(field.Name)) {
+			continue
+		}
+
+		err := self.decodeDefault(root, field, *conf)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Null defaults such as `default:""` zero the field, like null inputs. Invalid
+defaults are reported with HTTP status 500, because they're programmer errors.
+Takes the config by value, because it disables the field name in parse errors,
+which is already provided by `errDefault`.
+*/
+func (self Form) decodeDefault(root r.Value, field jsonField, conf Config) error {
+	if isSliceEmpty(field.Default) {
+		zeroAt(root, field.Path)
+		return nil
+	}
+
+	conf.AllErrors = true
+	return errDefault(field.Name, self.decodeInput(root, field, field.Default, &conf))
+}
+
+func hasDefaults(fields []jsonField) bool {
+	for _, field := range fields {
+		if field.Default != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func errDefault(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errInternal(fmt.Errorf(`invalid default value of field %q: %w`, name, errors.Unwrap(errBadReq(err))))
+}
+
+// Reports every missing field with the "required" tag option. See `rd.Form`.
+func (self Form) checkRequired(fields []jsonField, conf *Config, other Set) error {
+	var errs Errs
+	for _, field := range fields {
+		if field.Kind == fieldNormal && field.Required && conf.allows(field.Name) &&
+			self.missing(field) && !other.Has(rootName(field.Name)) {
+			errs = append(errs, FieldErr{field.Name, ErrMissing})
+		}
+	}
+	return errs.Err()
+}
+
+// Like the negation of `.present`, but treats null values as missing.
+func (self Form) missing(field jsonField) bool {
+	return isSliceEmpty(self[field.Name]) && !self.hasCombined(field) &&
+		!(isMapType(field.Type) && self.hasMapEntries(field.Name))
+}
+
+// Used for `rd.Config.Allowed` in strict mode.
+func (self Form) checkAllowed(fields []jsonField, conf *Config) error {
+	if conf.Allowed == nil {
+		return nil
+	}
+
+	var names []string
+	if conf.JsonKey != `` && self.Has(conf.JsonKey) && !conf.allows(conf.JsonKey) {
+		names = append(names, conf.JsonKey)
+	}
+
+	for _, field := range fields {
+		if field.Kind == fieldNormal && !conf.allows(field.Name) && self.present(field) {
+			names = append(names, field.Name)
+		}
+	}
+	return errDisallowedKeys(names)
+}
+
+// Fields tagged `rd:"querystring"` consume all unknown keys.
+func (self Form) checkUnknown(fields []jsonField, conf *Config) error {
+	if hasFieldKind(fields, fieldQuery) {
+		return nil
+	}
+
+	var keys []string
+	for key := range self {
+		if !isKnownKey(key, fields, conf) {
+			keys = append(keys, key)
+		}
+	}
+	return errUnknownKeys(keys)
+}
+
+// Returns the key-values which don't correspond to any field.
+func (self Form) unknown(fields []jsonField, conf *Config) url.Values {
+	var out url.Values
+	for key, val := range self {
+		if isKnownKey(key, fields, conf) {
+			continue
+		}
+		if out == nil {
+			out = make(url.Values)
+		}
+		out[key] = val
+	}
+	return out
+}
+
+// Used for `rd.Config.Log`. Must log only names, never values.
+func (self Form) logFields(fields []jsonField, conf *Config) {
+	for _, field := range fields {
+		if field.Kind == fieldQuery && field.All {
+			conf.logf(`form field at index %v: receives all keys`, field.Path)
+		} else if field.Kind == fieldQuery {
+			conf.logf(`form field at index %v: receives unmatched keys`, field.Path)
+		} else if !conf.allows(field.Name) {
+			conf.logf(`form field %q: skipped, not allowed`, field.Name)
+		} else if self.present(field) {
+			conf.logf(`form field %q: matched`, field.Name)
+		} else {
+			conf.logf(`form field %q: skipped, no matching key`, field.Name)
+		}
+	}
+
+	keys := make([]string, 0, len(self))
+	for key := range self {
+		if !isKnownKey(key, fields, conf) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		conf.logf(`form key %q: no matching field`, key)
+	}
+}
+
+func isKnownKey(key string, fields []jsonField, conf *Config) bool {
+	return (conf.JsonKey != `` && key == conf.JsonKey) ||
+		hasJsonField(fields, key) ||
+		(conf.fold() && hasJsonFieldFold(fields, key)) ||
+		hasCombinerKey(fields, key) ||
+		hasMapKey(fields, key)
+}
+
+func (self Form) hasCombined(field jsonField) bool {
+	comb, ok := loadCombiner(field.Type)
+	return ok && comb.has(self, field.Name)
+}
+
+func hasCombinerKey(fields []jsonField, key string) bool {
+	for _, field := range fields {
+		if field.Kind != fieldNormal {
+			continue
+		}
+		comb, ok := loadCombiner(field.Type)
+		if ok && comb.isKey(field.Name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the form with keys normalized for matching against the fields.
+func (self Form) source(fields []jsonField, conf *Config) Form {
+	src := self.brackets(fields)
+	if conf.LowercaseKeys {
+		src = src.lower(fields)
+	} else if conf.CaseInsensitive {
+		src = src.fold(fields)
+	}
+	return src
+}
+
+/*
+Returns a form where bracket keys such as "items[]" and "items[0]" are merged
+into the plain key "items". See `rd.Form` for the ordering. Keys with other
+content in brackets, and indexed keys of map fields, are kept as-is. Doesn't
+modify the receiver, and allocates only when bracket keys are present.
+*/
+func (self Form) brackets(fields []jsonField) Form {
+	if !self.hasBrackets() {
+		return self
+	}
+
+	out := make(Form, len(self))
+	var indexed map[string][]formIndexed
+
+	for key, val := range self {
+		name, index, ok := bracketKey(key)
+		if ok && index >= 0 && isMapField(fields, name) {
+			ok = false
+		}
+
+		switch {
+		case !ok && out[key] == nil:
+			out[key] = val
+		case !ok:
+			out[key] = appendStrings(val, out[key])
+		case index < 0:
+			out[name] = appendStrings(out[name], val)
+		default:
+			if indexed == nil {
+				indexed = map[string][]formIndexed{}
+			}
+			indexed[name] = append(indexed[name], formIndexed{index, val})
+		}
+	}
+
+	for name, vals := range indexed {
+		sort.Slice(vals, func(one, two int) bool { return vals[one].Index < vals[two].Index })
+		for _, val := range vals {
+			out[name] = appendStrings(out[name], val.Vals)
+		}
+	}
+	return out
+}
+
+func (self Form) hasBrackets() bool {
+	for key := range self {
+		_, _, ok := bracketKey(key)
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+type formIndexed struct {
+	Index int
+	Vals  []string
+}
+
+/*
+Parses "name[]" or "name[index]", where index consists of decimal digits.
+For "name[]", the index is -1.
+*/
+func bracketKey(key string) (string, int, bool) {
+	if !strings.HasSuffix(key, `]`) {
+		return ``, 0, false
+	}
+
+	open := strings.LastIndexByte(key, '[')
+	if !(open > 0) {
+		return ``, 0, false
+	}
+
+	name, inner := key[:open], key[open+1:len(key)-1]
+	if inner == `` {
+		return name, -1, true
+	}
+
+	for _, char := range []byte(inner) {
+		if !digits.has(char) {
+			return ``, 0, false
+		}
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return ``, 0, false
+	}
+	return name, index, true
+}
+
+// Never mutates the inputs, which may be shared with the original form.
+func appendStrings(one, two []string) []string {
+	out := make([]string, 0, len(one)+len(two))
+	return append(append(out, one...), two...)
+}
+
+/*
+Used for `rd.Config.CaseInsensitive`. Returns a form where each field whose
+name doesn't exactly match any key is additionally mapped to the values of a
+key that matches case-insensitively, if any. Doesn't modify the receiver.
+*/
+func (self Form) fold(fields []jsonField) Form {
+	var index map[string][]string
+	var out Form
+
+	for _, field := range fields {
+		if field.Kind != fieldNormal || self.Has(field.Name) {
+			continue
+		}
+
+		if index == nil {
+			index = make(map[string][]string, len(self))
+			for key, val := range self {
+				index[strings.ToLower(key)] = val
+			}
+		}
+
+		val, ok := index[strings.ToLower(field.Name)]
+		if !ok {
+			continue
+		}
+
+		if out == nil {
+			out = make(Form, len(self)+1)
+			for key, val := range self {
+				out[key] = val
+			}
+		}
+		out[field.Name] = val
+	}
+
+	if out == nil {
+		return self
+	}
+	return out
+}
+
+/*
+Used for `rd.Config.LowercaseKeys`. Returns a form where each field is mapped
+to the combined values of all keys which match its name case-insensitively, in
+the sorted order of the keys. Indexes the keys by their lowercased versions
+once. Doesn't modify the receiver.
+*/
+func (self Form) lower(fields []jsonField) Form {
+	var index map[string][]string
+	var out Form
+
+	for _, field := range fields {
+		if field.Kind != fieldNormal {
+			continue
+		}
+
+		if index == nil {
+			index = make(map[string][]string, len(self))
+			for key := range self {
+				lower := strings.ToLower(key)
+				index[lower] = append(index[lower], key)
+			}
+		}
+
+		keys := index[strings.ToLower(field.Name)]
+		if len(keys) == 0 || (len(keys) == 1 && keys[0] == field.Name) {
+			continue
+		}
+
+		if out == nil {
+			out = make(Form, len(self)+1)
+			for key, val := range self {
+				out[key] = val
+			}
+		}
+		out[field.Name] = self.combine(keys)
+	}
+
+	if out == nil {
+		return self
+	}
+	return out
+}
+
+// Sorts the keys in-place. Doesn't copy the values of a single key.
+func (self Form) combine(keys []string) []string {
+	if len(keys) == 1 {
+		return self[keys[0]]
+	}
+
+	sort.Strings(keys)
+	var out []string
+	for _, key := range keys {
+		out = append(out, self[key]...)
+	}
+	return out
+}
+
+/*
+Fields convertible to `url.Values`, such as `rd.Form` or `map[string][]string`,
+receive the unmatched keys as-is. Other fields receive them encoded as a query.
+With the "all" option, matched keys are included.
+*/
+func (self Form) decodeQuery(root r.Value, field jsonField, fields []jsonField, conf *Config) error {
+	out := derefAllocAt(root, field.Path)
+
+	src := url.Values(self)
+	if !field.All {
+		src = self.unknown(fields, conf)
+	}
+
+	if out.Type().ConvertibleTo(typeValues) {
+		vals := make(url.Values, len(src))
+		for key, val := range src {
+			vals[key] = copyStrings(val)
+		}
+		out.Set(r.ValueOf(vals).Convert(out.Type()))
+		return nil
+	}
+	return parse(src.Encode(), out)
+}
+
+func (self Form) decodeField(root r.Value, field jsonField, conf *Config) error {
+	err := field.checkOpts()
+	if err != nil {
+		return err
+	}
+
+	if self.hasCombined(field) {
+		comb, _ := loadCombiner(field.Type)
+		return comb.decode(self, root, field, conf)
+	}
+
+	input, ok := self[field.Name]
+	input = conf.transform(field.Name, input)
+
+	if ok && isSliceEmpty(input) && conf.DefaultOnNull && !conf.SkipDefaults && field.Default != nil {
+		err := self.decodeDefault(root, field, *conf)
+		if err != nil {
+			return err
+		}
+	} else if ok && isSliceEmpty(input) {
+		if !conf.SkipNull {
+			zeroAt(root, field.Path)
+		}
+	} else if ok && conf.isEmptySentinel(input) && isSliceField(field.Type) {
+		err := decodeEmptyList(derefAllocAt(root, field.Path))
+		if err != nil {
+			return errField(field.Name, err, conf)
+		}
+	} else if ok {
+		err := self.decodeInput(root, field, input, conf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if isMapType(field.Type) && self.hasMapEntries(field.Name) {
+		return self.decodeMap(derefAllocAt(root, field.Path), field, conf)
+	}
+	return nil
+}
+
+func (self Form) decodeInput(root r.Value, field jsonField, input []string, conf *Config) error {
+	out := derefAllocAt(root, field.Path)
+
+	impl, _ := out.Addr().Interface().(SliceParser)
+	if impl != nil {
+		if field.Csv {
+			input = splitCsv(input)
+		}
+		return errField(field.Name, impl.ParseSlice(input), conf)
+	}
+
+	if conf.LenientBool && isBoolField(out.Type()) {
+		input = lenientBools(input)
+	}
+
+	if isListOut(out) {
+		if field.Csv {
+			input = splitCsv(input)
+		}
+		if conf.StrictTypes {
+			return errField(field.Name, parseSliceAll(input, out, field.opts()), conf)
+		}
+		return errField(field.Name, parseSlice(input, out, field.opts()), conf)
+	}
+
+	if conf.arity() {
+		err := checkArity(field.Name, input)
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.StrictTypes && len(input) > 1 {
+		return errField(field.Name, parseAll(input, out, field.opts()), conf)
+	}
+
+	// First wins, like `url.Values.Get`. See `rd.Form`.
+	return errField(field.Name, parseWith(input[0], out, field.opts()), conf)
+}
+
+/*
+Used for the "csv" tag option. Naively splits each value on commas, without
+support for quoting or escaping. Allocates only when splitting is needed.
+*/
+func splitCsv(src []string) []string {
+	var out []string
+	for i, val := range src {
+		if out == nil && !strings.Contains(val, `,`) {
+			continue
+		}
+		if out == nil {
+			out = make([]string, 0, len(src)+strings.Count(val, `,`))
+			out = append(out, src[:i]...)
+		}
+		out = append(out, strings.Split(val, `,`)...)
+	}
+
+	if out == nil {
+		return src
+	}
+	return out
+}
+
+/*
+Slices and arrays which can be parsed from a single value, such as `net.IP`,
+are decoded like scalars.
+*/
+func isListOut(val r.Value) bool {
+	return (val.Kind() == r.Slice || val.Kind() == r.Array) && !isScalarParser(val)
+}
+
+// Used for `rd.Config.LenientBool`. Types with custom parsing are excluded.
+func isBoolField(typ r.Type) bool {
+	if typ.Kind() == r.Slice || typ.Kind() == r.Array {
+		typ = typ.Elem()
+	}
+	typ = derefType(typ)
+	return typ.Kind() == r.Bool && !isScalarParser(r.New(typ).Elem())
+}
+
+/*
+Used for `rd.Config.LenientBool`. Replaces recognized values with "true" or
+"false", leaving others as-is, to be rejected by `parseBool`. Allocates only
+when replacing is needed.
+*/
+func lenientBools(src []string) []string {
+	var out []string
+	for i, val := range src {
+		norm := lenientBool(val)
+		if norm == val {
+			continue
+		}
+		if out == nil {
+			out = make([]string, len(src))
+			copy(out, src)
+		}
+		out[i] = norm
+	}
+
+	if out == nil {
+		return src
+	}
+	return out
+}
+
+func lenientBool(src string) string {
+	switch strings.ToLower(src) {
+	case `true`, `t`, `1`, `on`, `yes`:
+		return `true`
+	case `false`, `f`, `0`, `off`, `no`:
+		return `false`
+	default:
+		return src
+	}
+}
+
+// Used for `rd.Config.EmptySentinel`. Slices are decoded elementwise, see
+// `rd.Form.decodeInput`.
+func isSliceField(typ r.Type) bool {
+	typ = derefType(typ)
+	return (typ.Kind() == r.Slice && !isScalarParser(r.New(typ).Elem())) ||
+		r.PtrTo(typ).Implements(typeSliceParser)
+}
+
+// Used for `rd.Config.EmptySentinel`.
+func decodeEmptyList(out r.Value) error {
+	impl, _ := out.Addr().Interface().(SliceParser)
+	if impl != nil {
+		return impl.ParseSlice([]string{})
+	}
+	out.Set(r.MakeSlice(out.Type(), 0, 0))
+	return nil
+}
+
+// Used for `rd.Config.StrictArity` and `rd.Config.Strict`.
+func checkArity(key string, input []string) error {
+	if len(input) > 1 {
+		return fmt.Errorf(`expected at most one value for field %q, got %v`, key, len(input))
+	}
+	return nil
+}
+
+// True if the value can be parsed from a single string despite its kind.
+func isScalarParser(val r.Value) bool {
+	switch val.Addr().Interface().(type) {
+	case Parser, encoding.TextUnmarshaler, encoding.BinaryUnmarshaler:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+Decodes keys such as "name[key]" into the map. When a key has multiple values,
+the first one wins, like for other fields. Null values produce zero values.
+The options of the field apply only to values; see `jsonField.opts`.
+*/
+func (self Form) decodeMap(out r.Value, field jsonField, conf *Config) error {
+	typ := out.Type()
+
+	for key, input := range self {
+		sub, ok := mapSubKey(field.Name, key)
+		if !ok {
+			continue
+		}
+
+		if conf.arity() {
+			err := checkArity(key, input)
+			if err != nil {
+				return err
+			}
+		}
+
+		keyVal := r.New(typ.Key()).Elem()
+		err := parse(sub, keyVal)
+		if err != nil {
+			return err
+		}
+
+		input = conf.transform(field.Name, input)
+
+		val := r.New(typ.Elem()).Elem()
+		if !isSliceEmpty(input) {
+			err := parseWith(input[0], val, field.opts())
+			if err != nil {
+				return err
+			}
+		}
+
+		if out.IsNil() {
+			out.Set(r.MakeMap(typ))
+		}
+		out.SetMapIndex(keyVal, val)
+	}
+	return nil
+}
+
+func (self Form) hasMapEntries(name string) bool {
+	for key := range self {
+		if _, ok := mapSubKey(name, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// True if the form has any keys for the field, including keys combined via
+// `rd.RegisterCombiner` and keys of map entries.
+func (self Form) present(field jsonField) bool {
+	return self.Has(field.Name) || self.hasCombined(field) ||
+		(isMapType(field.Type) && self.hasMapEntries(field.Name))
+}
+
+// Parses the subkey of a map entry such as "name[key]".
+func mapSubKey(name, key string) (string, bool) {
+	if len(key) > len(name)+2 && strings.HasPrefix(key, name) &&
+		key[len(name)] == '[' && key[len(key)-1] == ']' {
+		return key[len(name)+1 : len(key)-1], true
+	}
+	return ``, false
+}
+
+func isMapType(typ r.Type) bool { return derefType(typ).Kind() == r.Map }
+
+func isMapField(fields []jsonField, name string) bool {
+	for _, field := range fields {
+		if field.Kind == fieldNormal && field.Name == name && isMapType(field.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMapKey(fields []jsonField, key string) bool {
+	for _, field := range fields {
+		if field.Kind != fieldNormal || !isMapType(field.Type) {
+			continue
+		}
+		if _, ok := mapSubKey(field.Name, key); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func reqQuery(req *http.Request) url.Values {
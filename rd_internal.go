@@ -1,17 +1,41 @@
 package rd
 
 import (
-	"mime"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
 	r "reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
 var (
-	typeBytes = r.TypeOf((*[]byte)(nil)).Elem()
+	typeBytes    = r.TypeOf((*[]byte)(nil)).Elem()
+	typeDuration = r.TypeOf((*time.Duration)(nil)).Elem()
+	typeIPNet    = r.TypeOf((*net.IPNet)(nil)).Elem()
+	typeTime     = r.TypeOf((*time.Time)(nil)).Elem()
+	typeUrl      = r.TypeOf((*url.URL)(nil)).Elem()
+	typeValues   = r.TypeOf((*url.Values)(nil)).Elem()
+
+	typeSliceParser     = r.TypeOf((*SliceParser)(nil)).Elem()
+	typeJsonUnmarshaler = r.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	typeTextUnmarshaler = r.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 /*
@@ -25,9 +49,291 @@ func reqHasBody(req *http.Request) bool {
 	return req != nil && req.Body != nil && req.ContentLength != 0
 }
 
-func reqContentType(req *http.Request) string {
-	val, _, _ := mime.ParseMediaType(req.Header.Get(Type))
-	return val
+/*
+Returns the beginning of the request body, up to the first non-whitespace
+character or up to a small limit, without consuming it: the body is replaced
+with a reader that yields the same data.
+*/
+func peekBody(req *http.Request) ([]byte, error) {
+	const limit = 512
+	body := req.Body
+	buf := make([]byte, 0, 64)
+	empty := 0
+
+	for len(buf) < limit {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+
+		size, err := body.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+size]
+
+		if jsonHead(buf) != 0 || errors.Is(err, io.EOF) {
+			break
+		}
+		if size > 0 || err != nil {
+			empty = 0
+		} else if empty++; empty >= maxEmptyReads {
+			err = io.ErrNoProgress
+		}
+		if err != nil {
+			req.Body = readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+			return buf, err
+		}
+	}
+
+	req.Body = readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+	return buf, nil
+}
+
+/*
+Like in "bufio", a reader which keeps returning no data and no error is treated
+as broken after this many consecutive reads, instead of being retried forever.
+*/
+const maxEmptyReads = 100
+
+var utf8Bom = []byte("\xef\xbb\xbf")
+
+// Some clients, usually on Windows, prepend a UTF-8 BOM to request bodies.
+func trimBom(src []byte) []byte { return bytes.TrimPrefix(src, utf8Bom) }
+
+/*
+Removes a leading UTF-8 BOM from the request body, if any. Other data is left
+unconsumed: the body is replaced with a reader that yields the same data.
+*/
+func stripBodyBom(req *http.Request) error {
+	body := req.Body
+	buf := make([]byte, len(utf8Bom))
+	size, err := io.ReadFull(body, buf)
+	buf = buf[:size]
+
+	if bytes.Equal(buf, utf8Bom) {
+		return nil
+	}
+
+	req.Body = readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
+
+/*
+Returns an error with HTTP status 400 if the request body is missing or has no
+data. Doesn't consume the body: the body is replaced with a reader that yields
+the same data.
+*/
+func requireBody(req *http.Request, typ string) error {
+	body := req.Body
+	if body == nil {
+		return errMissingBody(typ)
+	}
+
+	buf := make([]byte, 1)
+	size, err := io.ReadFull(body, buf)
+	if size > 0 {
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(buf), body), body}
+		return nil
+	}
+	if err != nil && err != io.EOF {
+		return errBadReq(err)
+	}
+	return errMissingBody(typ)
+}
+
+/*
+Replaces the request body with a reader which decompresses it according to the
+"Content-Encoding" header, and removes the header, which prevents repeated
+decompression. Codings are undone in the reverse order of their listing.
+Supports "gzip", "x-gzip", "deflate", and "identity". Like many servers, accepts
+both zlib-wrapped and raw streams for "deflate", since clients disagree on its
+meaning. Unsupported codings produce an error with HTTP status 415. The
+decompressed data is limited by `rd.DecompressLimit`, which guards against
+decompression bombs; see `errDecompressLimit`. Other limits on body size, such
+as `rd.JsonLimit`, also apply to the decompressed data.
+*/
+func decompressBody(req *http.Request) error {
+	encs := req.Header.Values(`Content-Encoding`)
+	if !(len(encs) > 0) {
+		return nil
+	}
+
+	encs = strings.Split(strings.Join(encs, `,`), `,`)
+	for i := range encs {
+		enc := strings.ToLower(strings.TrimSpace(encs[i]))
+		if !(enc == `` || enc == `identity` || enc == `gzip` || enc == `x-gzip` || enc == `deflate`) {
+			return errContentEncoding(enc)
+		}
+		encs[i] = enc
+	}
+
+	req.Header.Del(`Content-Encoding`)
+	if !reqHasBody(req) {
+		return nil
+	}
+
+	body := req.Body
+	var src io.Reader = body
+
+	for i := len(encs) - 1; i >= 0; i-- {
+		out, err := decompress(src, encs[i])
+		if errors.Is(err, io.EOF) {
+			src = bytes.NewReader(nil)
+			break
+		}
+		if err != nil {
+			return errBadReq(fmt.Errorf(`failed to decompress request body with content encoding %q: %w`, encs[i], err))
+		}
+		src = out
+	}
+
+	if DecompressLimit > 0 {
+		src = &inflateReader{src: src, limit: DecompressLimit, left: DecompressLimit}
+	}
+
+	req.Body = readCloser{src, body}
+	req.ContentLength = -1
+	return nil
+}
+
+/*
+Used by `decompressBody`. Like `http.MaxBytesReader`, fails once the data
+exceeds the limit, and remembers the failure, which allows to report it even
+when decoders obscure it by wrapping.
+*/
+type inflateReader struct {
+	src   io.Reader
+	limit int64
+	left  int64
+	err   error
+}
+
+func (self *inflateReader) Read(buf []byte) (int, error) {
+	if self.err != nil {
+		return 0, self.err
+	}
+
+	// One extra byte distinguishes data at the limit from larger data.
+	if int64(len(buf)) > self.left+1 {
+		buf = buf[:self.left+1]
+	}
+
+	size, err := self.src.Read(buf)
+	if int64(size) <= self.left {
+		self.left -= int64(size)
+		return size, err
+	}
+
+	size = int(self.left)
+	self.left = 0
+	self.err = Err{http.StatusRequestEntityTooLarge, fmt.Errorf(`decompressed request body exceeds the limit of %v bytes`, self.limit)}
+	return size, self.err
+}
+
+/*
+Must be deferred after `decompressBody`. If the decompressed body has exceeded
+`rd.DecompressLimit`, replaces the error, if any, with the error of the limit,
+which has HTTP status 413. Finds the reader through the wrappers added by
+`peekBody`.
+*/
+func errDecompressLimit(req *http.Request, err *error) {
+	if *err == nil || req == nil {
+		return
+	}
+
+	var body io.Closer = req.Body
+	for {
+		val, ok := body.(readCloser)
+		if !ok {
+			return
+		}
+
+		src, _ := val.Reader.(*inflateReader)
+		if src != nil && src.err != nil {
+			*err = src.err
+			return
+		}
+		body = val.Closer
+	}
+}
+
+func decompress(src io.Reader, enc string) (io.Reader, error) {
+	switch enc {
+	case `gzip`, `x-gzip`:
+		return gzip.NewReader(src)
+
+	case `deflate`:
+		buf := bufio.NewReader(src)
+		head, err := buf.Peek(2)
+		if err != nil && !(len(head) > 0) {
+			return nil, err
+		}
+		if isZlibHead(head) {
+			return zlib.NewReader(buf)
+		}
+		return flate.NewReader(buf), nil
+
+	default:
+		return src, nil
+	}
+}
+
+// See RFC 1950: compression method 8, and a header checksum divisible by 31.
+func isZlibHead(head []byte) bool {
+	return len(head) == 2 && head[0]&0x0f == 8 && (uint(head[0])<<8|uint(head[1]))%31 == 0
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+/*
+Replaces the request body with a reader which fails once the context is done,
+and closes the original body on cancellation, which interrupts pending reads,
+including reads of stalled network connections. The returned function must be
+deferred: it restores the original body, stops watching the context, and
+replaces the error, if any, with an error with HTTP status 408 if the context
+is done.
+*/
+func watchBody(ctx context.Context, req *http.Request) func(*error) {
+	body := req.Body
+	if body == nil || ctx.Done() == nil {
+		return func(*error) {}
+	}
+
+	req.Body = readCloser{ctxReader{ctx, body}, body}
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			body.Close()
+		case <-done:
+		}
+	}()
+
+	return func(err *error) {
+		close(done)
+		req.Body = body
+		if *err != nil && ctx.Err() != nil {
+			*err = Err{http.StatusRequestTimeout, ctx.Err()}
+		}
+	}
+}
+
+// Used by `watchBody`.
+type ctxReader struct {
+	ctx context.Context
+	src io.Reader
+}
+
+func (self ctxReader) Read(buf []byte) (int, error) {
+	err := self.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return self.src.Read(buf)
 }
 
 /*
@@ -70,6 +376,49 @@ func trans(err *error, fun func(error) error) {
 	}
 }
 
+// Converts an error panic into an error return. Other panics are propagated.
+func rec(err *error) {
+	val := recover()
+	if val == nil {
+		return
+	}
+
+	impl, _ := val.(error)
+	if impl == nil {
+		panic(val)
+	}
+	*err = impl
+}
+
+/*
+Converts any panic into an error. Used by public entry points, which must not
+panic on malformed input. Instances of `rd.Err` are kept as-is. Runtime errors
+and non-error values, such as those from "reflect", indicate programmer error,
+and get HTTP status 500. Other errors, such as those from internal parsers, get
+HTTP status 400.
+*/
+func rescue(err *error) {
+	val := recover()
+	if val != nil {
+		*err = panicErr(val)
+	}
+}
+
+func panicErr(val interface{}) error {
+	switch val := val.(type) {
+	case Err:
+		return val
+	case runtime.Error:
+		return errInternal(val)
+	case *r.ValueError:
+		return errInternal(val)
+	case error:
+		return errBadReq(val)
+	default:
+		return errInternal(fmt.Errorf(`unexpected panic: %v`, val))
+	}
+}
+
 func derefType(typ r.Type) r.Type {
 	for typ != nil && typ.Kind() == r.Ptr {
 		typ = typ.Elem()
@@ -77,6 +426,13 @@ func derefType(typ r.Type) r.Type {
 	return typ
 }
 
+func typeAt(typ r.Type, path []int) r.Type {
+	for _, index := range path {
+		typ = derefType(typ).Field(index).Type
+	}
+	return typ
+}
+
 func derefStruct(src r.Value) (r.Value, error) {
 	val := src
 
@@ -114,6 +470,26 @@ func derefAllocAt(val r.Value, path []int) r.Value {
 	return val
 }
 
+// Like `zeroAt`, doesn't allocate. Returns false if the path goes through a nil
+// pointer.
+func valueAt(val r.Value, path []int) (r.Value, bool) {
+	for _, index := range path {
+		for val.Kind() == r.Ptr {
+			if val.IsNil() {
+				return val, false
+			}
+			val = val.Elem()
+		}
+		val = val.Field(index)
+	}
+	return val, true
+}
+
+/*
+Zeroes only the field at the path. Non-nil pointers along the path are
+preserved, such as pre-populated embedded structs. When a pointer along the path
+is nil, the field is already effectively zero, and nothing is allocated.
+*/
 func zeroAt(val r.Value, path []int) {
 	for _, index := range path {
 		for val.Kind() == r.Ptr {
@@ -130,6 +506,99 @@ func zeroAt(val r.Value, path []int) {
 	}
 }
 
+/*
+Replaces non-nil pointers, maps, and slices in decodable struct fields with
+copies, recursively. Used for copying structs without sharing mutable state,
+since decoding may write into existing maps and slices. Fields which can't be
+decoded, such as unexported or tagged with "-", are left shared. Values already
+copied are reused, which preserves aliasing and terminates on cycles.
+*/
+func clonePtrs(val r.Value, tag string) {
+	cloner{tag, map[cloneKey]r.Value{}}.clone(val)
+}
+
+// State for `clonePtrs`.
+type cloner struct {
+	tag  string
+	seen map[cloneKey]r.Value // Copies of already visited values.
+}
+
+// Identifies a pointer, map, or slice by its target.
+type cloneKey struct {
+	typ r.Type
+	ptr uintptr
+	len int
+}
+
+func (self cloner) clone(val r.Value) {
+	switch val.Kind() {
+	case r.Ptr:
+		if val.IsNil() || !val.CanSet() || self.reuse(val, 0) {
+			return
+		}
+		ptr := r.New(val.Type().Elem())
+		self.seen[cloneKey{val.Type(), val.Pointer(), 0}] = ptr
+		ptr.Elem().Set(val.Elem())
+		val.Set(ptr)
+		self.clone(ptr.Elem())
+
+	case r.Map:
+		if val.IsNil() || !val.CanSet() || self.reuse(val, 0) {
+			return
+		}
+		out := r.MakeMapWithSize(val.Type(), val.Len())
+		self.seen[cloneKey{val.Type(), val.Pointer(), 0}] = out
+		entries := val.MapRange()
+		for entries.Next() {
+			elem := r.New(val.Type().Elem()).Elem()
+			elem.Set(entries.Value())
+			self.clone(elem)
+			out.SetMapIndex(entries.Key(), elem)
+		}
+		val.Set(out)
+
+	case r.Slice:
+		if val.IsNil() || !val.CanSet() || self.reuse(val, val.Len()) {
+			return
+		}
+		out := r.MakeSlice(val.Type(), val.Len(), val.Len())
+		self.seen[cloneKey{val.Type(), val.Pointer(), val.Len()}] = out
+		r.Copy(out, val)
+		val.Set(out)
+		for i := range iter(val.Len()) {
+			self.clone(val.Index(i))
+		}
+
+	case r.Array:
+		for i := range iter(val.Len()) {
+			self.clone(val.Index(i))
+		}
+
+	case r.Struct:
+		typ := val.Type()
+		for i := range iter(typ.NumField()) {
+			if isDecodable(typ.Field(i), self.tag) {
+				self.clone(val.Field(i))
+			}
+		}
+	}
+}
+
+// If the value was already copied, replaces it with the copy.
+func (self cloner) reuse(val r.Value, size int) bool {
+	out, ok := self.seen[cloneKey{val.Type(), val.Pointer(), size}]
+	if ok {
+		val.Set(out)
+	}
+	return ok
+}
+
+// Mirrors the field selection of `fieldWalk.field`.
+func isDecodable(field r.StructField, tag string) bool {
+	return isPublic(field.PkgPath) &&
+		(field.Anonymous || tagName(field, tag) != `` || rdTagHas(field, `querystring`))
+}
+
 func iter(count int) []struct{} { return make([]struct{}, count) }
 
 func tagIdent(tag string) string {
@@ -143,11 +612,141 @@ func tagIdent(tag string) string {
 	return tag
 }
 
-func jsonName(field r.StructField) string {
-	return tagIdent(field.Tag.Get(`json`))
+// Returns the options part of a tag such as "name,opt0,opt1".
+func tagOpts(tag string) string {
+	index := strings.IndexByte(tag, ',')
+	if index >= 0 {
+		return tag[index+1:]
+	}
+	return ``
+}
+
+func jsonName(field r.StructField) string { return tagName(field, `json`) }
+
+/*
+Accepts either a single tag such as "json", or a comma-separated priority list
+of tags such as "form,json", see `rd.Config.Tags`.
+*/
+func tagName(field r.StructField, tags string) string {
+	return tagIdent(tagGet(field, tags))
+}
+
+/*
+Returns the value of the first tag from a comma-separated priority list such as
+"form,json" which is present on the field, even if empty. This allows a
+preferred tag such as `form:"-"` to exclude a field which has fallback tags.
+*/
+func tagGet(field r.StructField, tags string) string {
+	for tags != `` {
+		tag := tags
+		index := strings.IndexByte(tags, ',')
+		if index >= 0 {
+			tag, tags = tags[:index], tags[index+1:]
+		} else {
+			tags = ``
+		}
+
+		val, ok := field.Tag.Lookup(tag)
+		if ok {
+			return val
+		}
+	}
+	return ``
+}
+
+/*
+Options of the "rd" field tag, which is specific to this package. Unlike the
+"json" tag, it doesn't have a name part; all comma-separated parts are options.
+For symmetry with "json", a leading comma is allowed.
+*/
+func rdTagHas(field r.StructField, opt string) bool {
+	return tagOptsHas(field.Tag.Get(`rd`), opt)
+}
+
+func tagOptsHas(src, opt string) bool {
+	_, ok := tagOptsVal(src, opt, false)
+	return ok
 }
 
-func resliceInts(val *[]int, length int) { *val = (*val)[:length] }
+/*
+Finds a tag option such as "key" or, when `eq` is true, such as "key=val", and
+returns the part after "=".
+*/
+func tagOptsVal(src, key string, eq bool) (string, bool) {
+	for len(src) > 0 {
+		var part string
+		index := strings.IndexByte(src, ',')
+		if index >= 0 {
+			part, src = src[:index], src[index+1:]
+		} else {
+			part, src = src, ``
+		}
+		if !eq && part == key {
+			return ``, true
+		}
+		if eq && strings.HasPrefix(part, key+`=`) {
+			return part[len(key)+1:], true
+		}
+	}
+	return ``, false
+}
+
+/*
+Integer base from the "rd" tag option such as "base=16", 10 by default, or -1 if
+invalid. See `parseOpts`.
+*/
+func rdTagBase(field r.StructField) int {
+	src, ok := tagOptsVal(field.Tag.Get(`rd`), `base`, true)
+	if !ok {
+		return 10
+	}
+
+	val, err := strconv.Atoi(src)
+	if err != nil || !isBase(val) {
+		return -1
+	}
+	return val
+}
+
+/*
+Input from the "default" tag such as `default:"1"`, or nil if the tag is
+missing. An empty tag produces a null input, which zeroes the field. See
+`rd.Form`.
+*/
+func tagDefault(field r.StructField) []string {
+	val, ok := field.Tag.Lookup(`default`)
+	if !ok {
+		return nil
+	}
+	return []string{val}
+}
+
+// Valid for `strconv.ParseInt`.
+func isBase(val int) bool { return val == 0 || (val >= 2 && val <= 36) }
+
+/*
+Unit of Unix timestamps from the "rd" tag option such as "unix=ms", seconds by
+default, or 0 if invalid. See `parseOpts`.
+*/
+func rdTagUnix(field r.StructField) time.Duration {
+	src, ok := tagOptsVal(field.Tag.Get(`rd`), `unix`, true)
+	if !ok {
+		return time.Second
+	}
+
+	switch src {
+	case `s`:
+		return time.Second
+	case `ms`:
+		return time.Millisecond
+	case `us`:
+		return time.Microsecond
+	case `ns`:
+		return time.Nanosecond
+	default:
+		return 0
+	}
+}
 
 func copyInts(src []int) []int {
 	if src == nil {
@@ -161,59 +760,325 @@ func copyInts(src []int) []int {
 func isPublic(pkgPath string) bool { return pkgPath == `` }
 
 type jsonField struct {
-	Name string
-	Path []int
+	Name     string
+	Path     []int
+	Type     r.Type
+	Kind     fieldKind
+	Nested   bool          // Belongs to a nested non-embedded struct. Used only for forms.
+	Prefixed bool          // Belongs to an embedded struct tagged `rd:"prefix=..."`. Used only for forms.
+	Required bool          // Has the "required" tag option. Used only for forms.
+	Base     int           // Integer base from the "rd" tag, see `rdTagBase`. Used only for forms.
+	Unix     time.Duration // Unit of Unix timestamps from the "rd" tag, see `rdTagUnix`. Used only for forms.
+	Layout   string        // Time layout from the "layout" tag. Used only for forms.
+	Csv      bool          // Has the "csv" tag option. Used only for forms.
+	Omit     bool          // Has the "omitempty" tag option. Used only for `rd.EncodeForm`.
+	All      bool          // Tagged `rd:"querystring,all"`, receives all keys. Used only for forms.
+	In       string        // Source from the "rd" tag such as "in=header". Used only for `rd.Binder`.
+	Default  []string      // Input from the "default" tag, see `tagDefault`. Used only for forms.
 }
 
+func (self jsonField) opts() parseOpts {
+	return parseOpts{self.Base, self.Unix, self.Layout}
+}
+
+// Invalid options are programmer errors, reported with HTTP status 500.
+func (self jsonField) checkOpts() error {
+	if !isBase(self.Base) {
+		return errInternal(fmt.Errorf(`invalid integer base in "rd" tag of field %q, expected 0 or 2 to 36`, self.Name))
+	}
+	if self.Unix == 0 {
+		return errInternal(fmt.Errorf(`invalid unit of Unix timestamps in "rd" tag of field %q, expected "s", "ms", "us", or "ns"`, self.Name))
+	}
+	return nil
+}
+
+// Kinds of special fields, which are not decoded from a single key.
+type fieldKind byte
+
+const (
+	fieldNormal fieldKind = iota
+
+	// Tagged `rd:"querystring"`. Receives unmatched keys, encoded as a query. With
+	// the "all" option, receives all keys.
+	fieldQuery
+)
+
 var jsonFieldCache sync.Map
 
+// Key for `jsonFieldCache`. The same type has different fields for different
+// tags. For priority lists of tags, the key is the comma-separated list.
+type fieldCacheKey struct {
+	Type r.Type
+	Tag  string
+}
+
+func loadJsonFields(typ r.Type) []jsonField { return loadTagFields(typ, `json`) }
+
+// Value of `jsonFieldCache`.
+type fieldCacheVal struct {
+	fields    []jsonField
+	ambiguous []string // Names shared by several fields, see `ambiguousNames`.
+}
+
+func loadTagFields(typ r.Type, tag string) []jsonField {
+	return loadFieldCache(typ, tag).fields
+}
+
+// Used for `rd.Config.RejectAmbiguous`.
+func loadAmbiguous(typ r.Type, tag string) []string {
+	return loadFieldCache(typ, tag).ambiguous
+}
+
 // Susceptible to "thundering herd" but much better than no caching.
-func loadJsonFields(typ r.Type) []jsonField {
+func loadFieldCache(typ r.Type, tag string) fieldCacheVal {
 	if typ == nil {
-		return nil
+		return fieldCacheVal{}
 	}
 
-	val, ok := jsonFieldCache.Load(typ)
+	key := fieldCacheKey{typ, tag}
+	val, ok := jsonFieldCache.Load(key)
 	if ok {
-		return val.([]jsonField)
+		return val.(fieldCacheVal)
 	}
 
-	out := jsonFields(typ)
-	jsonFieldCache.Store(typ, out)
+	fields := tagFields(typ, tag)
+	out := fieldCacheVal{fields, ambiguousNames(fields)}
+	jsonFieldCache.Store(key, out)
 	return out
 }
 
-func jsonFields(typ r.Type) (out []jsonField) {
-	path := make([]int, 0, 8)
-	for i := range iter(typ.NumField()) {
-		appendJsonFields(&out, &path, typ, i)
+// Returns the names of normal fields which occur several times, in the order of
+// their first occurrence.
+func ambiguousNames(fields []jsonField) (out []string) {
+	counts := make(map[string]int, len(fields))
+	for _, field := range fields {
+		if field.Kind == fieldNormal {
+			counts[field.Name]++
+		}
+	}
+
+	for _, field := range fields {
+		if field.Kind == fieldNormal && counts[field.Name] > 1 {
+			out = append(out, field.Name)
+			counts[field.Name] = 0
+		}
 	}
 	return
 }
 
-func appendJsonFields(buf *[]jsonField, path *[]int, typ r.Type, index int) {
-	defer resliceInts(path, len(*path))
-	*path = append(*path, index)
+func tagFields(typ r.Type, tag string) (out []jsonField) {
+	walk := fieldWalk{buf: &out, path: make([]int, 0, 8), tag: tag}
+	walk.fields(typ)
+	return
+}
+
+// State for `tagFields`, which walks struct fields recursively.
+type fieldWalk struct {
+	buf    *[]jsonField
+	path   []int
+	tag    string
+	prefix string   // Dotted path of the nested struct being walked, if any.
+	embed  string   // Name prefix from `rd:"prefix=..."` of embedded structs being walked.
+	stack  []r.Type // Nested struct types being walked.
+	in     string   // Source of the top-level field being walked, see `rd.Binder`.
+}
+
+func (self fieldWalk) fields(typ r.Type) {
+	for i := range iter(typ.NumField()) {
+		self.field(typ, i)
+	}
+}
+
+func (self fieldWalk) field(typ r.Type, index int) {
+	self.path = append(self.path, index)
 
 	field := typ.Field(index)
 	if !isPublic(field.PkgPath) {
 		return
 	}
 
-	name := jsonName(field)
+	if rdTagHas(field, `querystring`) {
+		if self.prefix == `` {
+			*self.buf = append(*self.buf, jsonField{
+				Path: copyInts(self.path),
+				Kind: fieldQuery,
+				All:  rdTagHas(field, `all`),
+			})
+		}
+		return
+	}
+
+	name := tagName(field, self.tag)
 	if name != `` {
-		*buf = append(*buf, jsonField{name, copyInts(*path)})
+		// Nested fields inherit the source of the top-level field.
+		if self.prefix == `` {
+			self.in, _ = tagOptsVal(field.Tag.Get(`rd`), `in`, true)
+		}
+
+		name = self.embed + name
+
+		*self.buf = append(*self.buf, jsonField{
+			Name:     self.prefix + name,
+			Path:     copyInts(self.path),
+			Type:     field.Type,
+			Nested:   self.prefix != ``,
+			Prefixed: self.embed != ``,
+			Required: tagOptsHas(tagOpts(tagGet(field, self.tag)), `required`),
+			Base:     rdTagBase(field),
+			Unix:     rdTagUnix(field),
+			Layout:   field.Tag.Get(`layout`),
+			Csv:      tagOptsHas(tagOpts(tagGet(field, self.tag)), `csv`),
+			Omit:     tagOptsHas(tagOpts(tagGet(field, self.tag)), `omitempty`),
+			In:       self.in,
+			Default:  tagDefault(field),
+		})
+		self.nested(field.Type, self.prefix+name)
 		return
 	}
 
 	if field.Anonymous {
 		typ := derefType(field.Type)
 		if typ.Kind() == r.Struct {
-			for i := range iter(typ.NumField()) {
-				appendJsonFields(buf, path, typ, i)
-			}
+			pre, _ := tagOptsVal(field.Tag.Get(`rd`), `prefix`, true)
+			self.embed += pre
+			self.fields(typ)
+		}
+	}
+}
+
+/*
+Fields of named non-embedded structs are also decoded from dotted keys, such as
+"inner.innerStr". The struct field itself is kept, because it may implement
+parsing interfaces. To avoid infinite recursion, each nested struct type is
+walked at most once per path: for recursive types, only one level of nesting is
+supported.
+*/
+func (self fieldWalk) nested(typ r.Type, name string) {
+	typ = derefType(typ)
+	if typ.Kind() != r.Struct || hasType(self.stack, typ) {
+		return
+	}
+
+	self.prefix = name + `.`
+	self.embed = ``
+	self.stack = append(self.stack[:len(self.stack):len(self.stack)], typ)
+	self.fields(typ)
+}
+
+func hasType(list []r.Type, typ r.Type) bool {
+	for _, val := range list {
+		if val == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJsonField(fields []jsonField, name string) bool {
+	for _, field := range fields {
+		if field.Kind == fieldNormal && field.Name == name {
+			return true
 		}
 	}
+	return false
+}
+
+func hasJsonFieldFold(fields []jsonField, name string) bool {
+	for _, field := range fields {
+		if field.Kind == fieldNormal && strings.EqualFold(field.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Used by `rd.DecodeFields` for decoders other than `rd.Form`. Returns the names
+of the top-level fields which match the given keys, preferring exact matches to
+case-insensitive ones, like "encoding/json". Ignores prefixes of embedded
+structs, which are unknown to "encoding/json".
+*/
+func matchFields(keys Set, val interface{}) Set {
+	typ := derefType(r.TypeOf(val))
+	if typ == nil || typ.Kind() != r.Struct {
+		return nil
+	}
+
+	var out Set
+	fields := loadJsonFields(typ)
+
+	for key := range keys {
+		field, ok := matchField(fields, key)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make(Set)
+		}
+		out.Add(field.Name)
+	}
+	return out
+}
+
+func matchField(fields []jsonField, key string) (jsonField, bool) {
+	var fold *jsonField
+	for i := range fields {
+		field := &fields[i]
+		if field.Kind != fieldNormal || field.Nested || field.Prefixed {
+			continue
+		}
+		if field.Name == key {
+			return *field, true
+		}
+		if fold == nil && strings.EqualFold(field.Name, key) {
+			fold = field
+		}
+	}
+	if fold == nil {
+		return jsonField{}, false
+	}
+	return *fold, true
+}
+
+func equalPath(one, two []int) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for i := range one {
+		if one[i] != two[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the top-level part of a field name such as "inner.innerStr".
+func rootName(name string) string {
+	index := strings.IndexByte(name, '.')
+	if index >= 0 {
+		return name[:index]
+	}
+	return name
+}
+
+func rootNames(set Set) Set {
+	var out Set
+	for name := range set {
+		if out == nil {
+			out = make(Set, len(set))
+		}
+		out.Add(rootName(name))
+	}
+	return out
+}
+
+func hasFieldKind(fields []jsonField, kind fieldKind) bool {
+	for _, field := range fields {
+		if field.Kind == kind {
+			return true
+		}
+	}
+	return false
 }
 
 func isSliceEmpty(val []string) bool {
@@ -223,3 +1088,53 @@ func isSliceEmpty(val []string) bool {
 func typeBits(typ r.Type) int {
 	return int(typ.Size() * 8)
 }
+
+/*
+Decodes via an arbitrary unmarshaling function into a JSON-compatible
+intermediary representation, then decodes that via "encoding/json". This makes
+the output use the "json" field tag, consistently with the rest of this
+package, regardless of which tags are supported by the third-party library.
+*/
+func unmarshalJsonCompat(fun Unmarshal, src []byte, out interface{}) error {
+	var val interface{}
+	err := fun(src, &val)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(jsonCompat(val))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, out)
+}
+
+/*
+Some libraries, such as older versions of YAML decoders, decode maps as
+`map[interface{}]interface{}`, which is not supported by "encoding/json".
+*/
+func jsonCompat(src interface{}) interface{} {
+	switch src := src.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(src))
+		for key, val := range src {
+			out[fmt.Sprint(key)] = jsonCompat(val)
+		}
+		return out
+
+	case map[string]interface{}:
+		for key, val := range src {
+			src[key] = jsonCompat(val)
+		}
+		return src
+
+	case []interface{}:
+		for i, val := range src {
+			src[i] = jsonCompat(val)
+		}
+		return src
+
+	default:
+		return src
+	}
+}
@@ -0,0 +1,128 @@
+package rd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	r "reflect"
+)
+
+func decodeCsv(src []byte, out interface{}) error {
+	val := r.ValueOf(out)
+	if val.Kind() != r.Ptr || val.IsNil() || val.Elem().Kind() != r.Slice ||
+		derefType(val.Elem().Type().Elem()).Kind() != r.Struct {
+		return errInternal(fmt.Errorf(`unable to decode %q into %T, expected a non-nil pointer to a slice of structs`, TypeCsv, out))
+	}
+
+	// Decoded into a new slice, which leaves the output unchanged on failure.
+	list := val.Elem()
+	buf := r.MakeSlice(list.Type(), 0, 0)
+	typ := list.Type().Elem()
+
+	dec := csvReader(src)
+	head, err := dec.Read()
+	if errors.Is(err, io.EOF) {
+		list.Set(buf)
+		return nil
+	}
+	if err != nil {
+		return errBadReq(err)
+	}
+
+	cols := csvColumns(head, loadTagFields(derefType(typ), `json`))
+	for _, field := range cols {
+		if field != nil {
+			err := field.checkOpts()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		row, err := dec.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errBadReq(err)
+		}
+
+		elem := r.New(typ).Elem()
+		err = decodeCsvRow(elem, cols, row)
+		if err != nil {
+			return errBadReq(fmt.Errorf(`failed to decode row %v: %w`, buf.Len(), err))
+		}
+		buf = r.Append(buf, elem)
+	}
+
+	list.Set(buf)
+	return nil
+}
+
+func csvReader(src []byte) *csv.Reader {
+	dec := csv.NewReader(bytes.NewReader(trimBom(src)))
+	dec.ReuseRecord = true
+	return dec
+}
+
+// Returns the field for each header column, with nil for unmatched columns.
+func csvColumns(head []string, fields []jsonField) []*jsonField {
+	out := make([]*jsonField, len(head))
+	var seen Set
+
+	for i, name := range head {
+		if seen.Has(name) {
+			continue
+		}
+
+		for j := range fields {
+			field := &fields[j]
+			if field.Kind == fieldNormal && field.Name == name {
+				out[i] = field
+				break
+			}
+		}
+
+		if seen == nil {
+			seen = make(Set, len(head))
+		}
+		seen.Add(name)
+	}
+	return out
+}
+
+// Empty cells are null, leaving the zero value of the new element.
+func decodeCsvRow(elem r.Value, cols []*jsonField, row []string) error {
+	for i, cell := range row {
+		field := cols[i]
+		if field == nil || cell == `` {
+			continue
+		}
+
+		err := parseWith(cell, derefAllocAt(elem, field.Path), field.opts())
+		if err != nil {
+			return fmt.Errorf(`failed to decode field %q: %w`, field.Name, err)
+		}
+	}
+	return nil
+}
+
+// Input should be empty or valid CSV. Only the header is read.
+func parseCsvSet(src []byte) Set {
+	head, err := csvReader(src).Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		panic(errBadReq(err))
+	}
+
+	out := make(Set, len(head))
+	for _, name := range head {
+		out.Add(name)
+	}
+	return out
+}
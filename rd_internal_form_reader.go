@@ -0,0 +1,214 @@
+package rd
+
+/*
+Streaming parser of URL-encoded forms, used by `rd.DecodeFormReader`. Scans the
+input one key-value pair at a time, without buffering the entire body, and
+without allocating values which are not kept. Validates every pair the same way
+as `url.ParseQuery`, including pairs which are not kept, so that skipping a key
+doesn't make invalid input acceptable.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+/*
+Limit on the size of the entire body, matching the limit used by
+`(*http.Request).ParseForm`, which applies to scanned bytes, including keys and
+values which are not kept.
+*/
+const formReaderLimit = 10 << 20
+
+/*
+Limit on the size of a single raw key. Keys are buffered even when they're not
+kept, because they must be read in full before deciding.
+*/
+const formReaderKeyLimit = 64 << 10
+
+// What to keep for a key in `parseFormReader`.
+type formKeep byte
+
+const (
+	formKeepNone formKeep = iota
+	formKeepKey
+	formKeepAll
+)
+
+func parseFormReader(src io.Reader, keep func(string) formKeep) (Form, error) {
+	par := formPar{src: bufio.NewReader(src), keep: keep}
+
+	err := par.bom()
+	if err != nil {
+		return nil, errBadReq(err)
+	}
+
+	for {
+		more, err := par.pair()
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return par.out, nil
+		}
+	}
+}
+
+// Short for "form parser".
+type formPar struct {
+	src  *bufio.Reader
+	keep func(string) formKeep
+	key  []byte // Raw key of the current pair, reused between pairs.
+	val  []byte // Raw value of the current pair, reused between pairs.
+	size int    // Total amount of scanned bytes.
+	out  Form
+}
+
+func (self *formPar) bom() error {
+	head, err := self.src.Peek(len(utf8Bom))
+	if bytes.Equal(head, utf8Bom) {
+		_, err = self.src.Discard(len(utf8Bom))
+		return err
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+/*
+Reads one key-value pair, up to the next "&" or the end of input. Returns false
+at the end of input.
+*/
+func (self *formPar) pair() (bool, error) {
+	self.key = self.key[:0]
+	self.val = self.val[:0]
+
+	var esc escState
+	var semi bool
+	var hasVal bool
+	mode := formKeepNone
+
+	for {
+		char, err := self.src.ReadByte()
+		if err == io.EOF {
+			if len(self.key) == 0 && !hasVal {
+				return false, nil
+			}
+			return false, self.add(mode, hasVal, semi, esc)
+		}
+		if err != nil {
+			return false, errBadReq(err)
+		}
+
+		self.size++
+		if self.size > formReaderLimit {
+			return false, Err{http.StatusRequestEntityTooLarge, fmt.Errorf(`form body exceeds the limit of %v bytes`, formReaderLimit)}
+		}
+
+		if char == '&' {
+			if len(self.key) == 0 && !hasVal {
+				return true, nil
+			}
+			return true, self.add(mode, hasVal, semi, esc)
+		}
+
+		semi = semi || char == ';'
+		esc.next(char)
+
+		if hasVal {
+			if mode == formKeepAll {
+				self.val = append(self.val, char)
+			}
+		} else if char == '=' {
+			hasVal = true
+			mode = self.mode(semi, esc)
+		} else if len(self.key) < formReaderKeyLimit {
+			self.key = append(self.key, char)
+		} else {
+			return false, Err{http.StatusRequestEntityTooLarge, fmt.Errorf(`form key exceeds the limit of %v bytes`, formReaderKeyLimit)}
+		}
+	}
+}
+
+// Called after the key has been fully read.
+func (self *formPar) mode(semi bool, esc escState) formKeep {
+	if semi || !esc.valid() {
+		return formKeepNone
+	}
+	key, err := url.QueryUnescape(bytesString(self.key))
+	if err != nil {
+		return formKeepNone
+	}
+	return self.keep(key)
+}
+
+func (self *formPar) add(mode formKeep, hasVal, semi bool, esc escState) error {
+	if semi {
+		return errBadReq(fmt.Errorf(`invalid semicolon separator in query`))
+	}
+	if !esc.valid() {
+		return errBadReq(fmt.Errorf(`invalid URL escape in form body`))
+	}
+	if !hasVal {
+		mode = self.mode(semi, esc)
+	}
+	if mode == formKeepNone {
+		return nil
+	}
+
+	key, err := url.QueryUnescape(string(self.key))
+	if err != nil {
+		return errBadReq(err)
+	}
+
+	if self.out == nil {
+		self.out = Form{}
+	}
+	if mode == formKeepKey {
+		if _, ok := self.out[key]; !ok {
+			self.out[key] = nil
+		}
+		return nil
+	}
+
+	val, err := url.QueryUnescape(string(self.val))
+	if err != nil {
+		return errBadReq(err)
+	}
+
+	self.out[key] = append(self.out[key], val)
+	return nil
+}
+
+/*
+Validates percent-escapes byte by byte, like `url.QueryUnescape`, without
+buffering: "%" must be followed by two hex digits.
+*/
+type escState struct {
+	pending byte // Amount of hex digits expected after "%".
+	invalid bool
+}
+
+func (self *escState) next(char byte) {
+	if self.pending > 0 {
+		if !isHex(char) {
+			self.invalid = true
+		}
+		self.pending--
+		return
+	}
+	if char == '%' {
+		self.pending = 2
+	}
+}
+
+func (self escState) valid() bool { return !self.invalid && self.pending == 0 }
+
+func isHex(char byte) bool {
+	return digits.has(char) || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
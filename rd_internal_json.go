@@ -17,8 +17,10 @@ Why?
 
 import (
 	"fmt"
-	"io"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
@@ -30,27 +32,136 @@ func parseSet(src string) Set {
 	return par.out
 }
 
+/*
+Like `parseSet`, but returns an error instead of panicking, and treats
+duplicate top-level keys as an error.
+*/
+func parseSetUnique(src string) (_ Set, err error) {
+	defer rec(&err)
+	par := par{src: src, uni: true}
+	par.top()
+	return par.out, nil
+}
+
+/*
+Like `parseSet`, but returns an error instead of panicking, and validates the
+entire input, which must be exactly one JSON value of any kind, optionally
+surrounded by whitespace. Unlike the other modes, this checks the details which
+don't affect the detection of keys: escape sequences, control characters in
+strings, leading zeros in numbers, and trailing data. Collects nothing.
+*/
+func parseValid(src string) (err error) {
+	defer rec(&err)
+	par := par{src: src, valid: true}
+	par.any()
+	if par.next() {
+		panic(par.err())
+	}
+	return nil
+}
+
+/*
+Like `parseSetUnique`, but only checks for errors, collecting keys into a
+pooled set; see `setPool`.
+*/
+func checkSetUnique(src string) (err error) {
+	set := getSet()
+	defer putSet(set)
+	defer rec(&err)
+
+	par := par{src: src, uni: true, out: set}
+	par.top()
+	return nil
+}
+
+/*
+Like `parseSet`, but returns an error instead of panicking, and reports whether
+the key is among the top-level keys, collecting them into a pooled set; see
+`setPool`.
+*/
+func hasKey(src string, key string) (_ bool, err error) {
+	set := getSet()
+	defer putSet(set)
+	defer rec(&err)
+
+	par := par{src: src, out: set}
+	par.top()
+	return set.Has(key), nil
+}
+
+// Like `parseSet`, but allows a trailing comma before `}` or `]` at any level.
+func parseSetLenient(src string) Set {
+	par := par{src: src, lax: true}
+	par.top()
+	return par.out
+}
+
+// Like `parseSet`, but collects dotted paths of object keys at all levels.
+func parseSetNested(src string) Set {
+	par := par{src: src, nest: true}
+	par.top()
+	return par.out
+}
+
+/*
+Like `parseSet`, but returns an error instead of panicking, and collects the
+raw text of each top-level key and value instead of building a set.
+*/
+func parseSpans(src string) (_ []jsonSpan, err error) {
+	defer rec(&err)
+	par := par{src: src, raw: true}
+	par.top()
+	return par.spans, nil
+}
+
+// Raw text of a top-level key-value pair. The key includes the quotes.
+type jsonSpan struct {
+	Key string
+	Val string
+}
+
 // Short for "parser".
 type par struct {
-	src string // Short for "source".
-	pos int    // Short for "position".
-	lvl int    // Short for "level".
-	out Set    // Short for "output".
+	src   string     // Short for "source".
+	pos   int        // Short for "position".
+	lvl   int        // Short for "level".
+	out   Set        // Short for "output".
+	uni   bool       // Short for "unique".
+	raw   bool       // Collect spans instead of keys.
+	valid bool       // Validate everything, collecting nothing.
+	lax   bool       // Allow trailing commas.
+	nest  bool       // Collect dotted paths of keys at all levels.
+	pre   string     // Dotted path of the current object, when collecting paths.
+	last  string     // Last key: quoted when collecting spans, dotted when collecting paths.
+	spans []jsonSpan // Top-level key-value pairs, when collecting spans.
 }
 
 func (self *par) top() {
-	if self.next() && self.peek() == '{' {
+	if self.bom() && self.next() && self.peek() == '{' {
 		self.pos++
 		self.obj()
 	}
 }
 
+/*
+Skips a leading UTF-8 BOM, which some clients prepend to JSON. Returns false for
+an incomplete BOM, which is not JSON, treated like other non-objects.
+*/
+func (self *par) bom() bool {
+	if strings.HasPrefix(self.src, string(utf8Bom)) {
+		self.pos += len(utf8Bom)
+		return true
+	}
+	return !(self.more() && self.peek() == utf8Bom[0])
+}
+
 func (self *par) any() {
 	self.next()
 	char := self.peek()
 
 	if digits.has(char) {
 		self.pos++
+		self.zero(char)
 		self.num()
 		return
 	}
@@ -144,7 +255,18 @@ func (self *par) obj() {
 		}
 
 	afterColon:
-		self.any()
+		if self.raw && self.lvl == 1 {
+			pos := self.pos
+			self.any()
+			self.spans = append(self.spans, jsonSpan{self.last, self.src[pos:self.pos]})
+		} else if self.nest {
+			pre := self.pre
+			self.pre = self.last + `.`
+			self.any()
+			self.pre = pre
+		} else {
+			self.any()
+		}
 		mode = afterValue
 		continue
 
@@ -171,21 +293,48 @@ func (self *par) obj() {
 			mode = beforeKey
 			continue
 		}
+		if self.lax && self.peek() == '}' {
+			self.pos++
+			self.lvl--
+			return
+		}
 		panic(self.err())
 
 	unreachable:
 		panic(errUnreachable)
 	}
 
-	panic(errJsonEof)
+	panic(self.errEof())
 }
 
 func (self *par) key() {
 	pos := self.pos
 	self.str()
-	if self.lvl == 1 {
-		self.add(self.src[pos : self.pos-1])
+
+	if self.nest {
+		self.last = self.pre + self.unescape(pos)
+		self.add(self.last)
+		return
+	}
+
+	if self.valid || self.lvl != 1 {
+		return
+	}
+	if self.raw {
+		self.last = self.src[pos-1 : self.pos]
+		return
+	}
+	self.add(self.unescape(pos))
+}
+
+// Decodes the string which starts at the given position and ends just before
+// the current position.
+func (self *par) unescape(pos int) string {
+	out, err := jsonUnescape(self.src[pos : self.pos-1])
+	if err != nil {
+		panic(newJsonSyntaxError(self.src, pos, err))
 	}
+	return out
 }
 
 func (self *par) arr() {
@@ -241,6 +390,11 @@ func (self *par) arr() {
 		}
 
 	afterComma:
+		if self.lax && self.peek() == ']' {
+			self.pos++
+			self.lvl--
+			return
+		}
 		self.any()
 		mode = afterVal
 		continue
@@ -249,7 +403,7 @@ func (self *par) arr() {
 		panic(errUnreachable)
 	}
 
-	panic(errJsonEof)
+	panic(self.errEof())
 }
 
 func (self *par) str() {
@@ -262,29 +416,139 @@ func (self *par) str() {
 			self.pos++
 			self.esc()
 		default:
+			if self.valid && self.peek() < ' ' {
+				panic(newJsonSyntaxError(self.src, self.pos, fmt.Errorf(`invalid control character %q in string`, self.peek())))
+			}
 			self.skipChar()
 		}
 	}
-	panic(errJsonEof)
+	panic(self.errEof())
+}
+
+/*
+Skipping a single byte after a backslash is enough for detecting the closing
+quote character. Unicode escape codes such as \u0000 are skipped as regular
+characters. Top-level keys are decoded separately; see `jsonUnescape`. When
+validating, the escape sequence must be well-formed.
+*/
+func (self *par) esc() {
+	if !self.valid {
+		self.skip()
+		return
+	}
+
+	pos := self.pos - 1
+	if jsonEscapes[self.peek()] != 0 {
+		self.skip()
+		return
+	}
+	if self.peek() != 'u' {
+		panic(newJsonSyntaxError(self.src, pos, errJsonEscape(self.src[pos:self.pos+1])))
+	}
+	if _, ok := jsonHex(self.src[pos:]); ok {
+		self.pos = pos + 6
+		return
+	}
+	panic(newJsonSyntaxError(self.src, pos, errJsonEscape(self.src[pos:])))
 }
 
 /*
-Semi-placeholder. Doesn't support decoding Unicode escape codes such as
-\u0000, but does support detecting and handling them. Skipping a single byte
-after a backslash should be enough for our purposes, because we only care
-about detecting the closing quote character, and don't need to convert code
-sequences to characters.
+Decodes escape sequences in the content of a JSON string, without quotes,
+including \uXXXX and UTF-16 surrogate pairs. Like "encoding/json", replaces
+invalid surrogates with U+FFFD. Allocates only when the input contains escape
+sequences.
 */
-func (self *par) esc() { self.skip() }
+func jsonUnescape(src string) (string, error) {
+	if strings.IndexByte(src, '\\') < 0 {
+		return src, nil
+	}
+
+	buf := make([]byte, 0, len(src))
+
+	for len(src) > 0 {
+		index := strings.IndexByte(src, '\\')
+		if index < 0 {
+			buf = append(buf, src...)
+			break
+		}
+		buf = append(buf, src[:index]...)
+		src = src[index:]
+
+		if len(src) < 2 {
+			return ``, errJsonEscape(src)
+		}
+
+		char := jsonEscapes[src[1]]
+		if char != 0 {
+			buf = append(buf, char)
+			src = src[2:]
+			continue
+		}
+
+		if src[1] != 'u' {
+			return ``, errJsonEscape(src[:2])
+		}
+
+		val, ok := jsonHex(src)
+		if !ok {
+			return ``, errJsonEscape(src)
+		}
+		src = src[6:]
+
+		if utf16.IsSurrogate(val) {
+			next, ok := jsonHex(src)
+			if ok {
+				pair := utf16.DecodeRune(val, next)
+				if pair != utf8.RuneError {
+					val = pair
+					src = src[6:]
+				}
+			}
+			if utf16.IsSurrogate(val) {
+				val = utf8.RuneError
+			}
+		}
+
+		buf = utf8.AppendRune(buf, val)
+	}
+
+	return bytesString(buf), nil
+}
+
+// Decodes the hex digits of a \uXXXX escape sequence at the start of the input.
+func jsonHex(src string) (rune, bool) {
+	if len(src) < 6 || src[0] != '\\' || src[1] != 'u' {
+		return 0, false
+	}
+	val, err := strconv.ParseUint(src[2:6], 16, 16)
+	return rune(val), err == nil
+}
+
+func errJsonEscape(src string) error {
+	const limit = 6
+	if len(src) > limit {
+		src = src[:limit]
+	}
+	return fmt.Errorf(`invalid escape sequence %q`, src)
+}
 
 func (self *par) beforeNum() {
-	if !digits.has(self.peek()) {
+	char := self.peek()
+	if !digits.has(char) {
 		panic(self.err())
 	}
 	self.pos++
+	self.zero(char)
 	self.num()
 }
 
+// When validating, rejects leading zeros such as "01", given the first digit.
+func (self *par) zero(char byte) {
+	if self.valid && char == '0' && self.more() && digits.has(self.peek()) {
+		panic(self.err())
+	}
+}
+
 func (self *par) num() {
 	for self.more() {
 		char := self.peek()
@@ -389,7 +653,8 @@ func (self *par) more() bool {
 
 func (self *par) next() bool {
 	for self.pos < len(self.src) {
-		if whitespace.has(self.src[self.pos]) {
+		char := self.src[self.pos]
+		if whitespace.has(char) && !(self.valid && char == '\v') {
 			self.pos++
 			continue
 		}
@@ -404,7 +669,7 @@ func (self *par) rest() string {
 
 func (self *par) peek() byte {
 	if !self.more() {
-		panic(errJsonEof)
+		panic(self.errEof())
 	}
 	return self.src[self.pos]
 }
@@ -418,22 +683,85 @@ func (self *par) skipChar() {
 
 func (self *par) add(key string) {
 	if self.out == nil {
-		self.out = make(Set, 16)
+		self.out = make(Set, setCap(len(self.src)))
+	}
+	if self.uni && self.out.Has(key) {
+		panic(fmt.Errorf(`duplicate JSON key %q`, key))
 	}
 	self.out.Add(key)
 }
 
-func (self *par) err() error {
-	rest := strings.TrimSpace(self.rest())
+/*
+Sets reused by internal operations which need a set only temporarily, such as
+`rd.Has` and the duplicate key check of `rd.Config.Strict`. The parser itself
+is not pooled, because it doesn't escape to the heap. Thread-safety contract: a
+pooled set is used by one goroutine, never leaves the function which obtained
+it, and is cleared before being returned to the pool, which also releases the
+keys, which may reference the JSON input. Sets returned by public methods, such
+as `rd.Json.Set`, are owned by callers and never pooled. Decoding forms needs
+no scratch buffers: field paths are cached with the field metadata, and decoding
+into existing fields doesn't allocate.
+*/
+var setPool = sync.Pool{New: func() interface{} { return make(Set, setPoolCap) }}
 
-	if len(rest) > 0 {
-		return fmt.Errorf(
-			`invalid JSON syntax in position %v: unexpected %q`,
-			self.pos, rest,
-		)
+// Larger sets are dropped rather than pooled, to avoid retaining memory after
+// occasional large inputs.
+const setPoolCap, setPoolMax = 16, 1024
+
+func getSet() Set { return setPool.Get().(Set) }
+
+func putSet(val Set) {
+	if len(val) > setPoolMax {
+		return
+	}
+	for key := range val {
+		delete(val, key)
 	}
+	setPool.Put(val)
+}
+
+/*
+Initial set capacity, estimated from the input length. Growing a map repeatedly
+is more expensive than allocating it upfront. A top-level key-value pair rarely
+takes less than 24 bytes, including whitespace, which makes this roughly an
+upper bound for typical inputs. Small inputs get no hint, because small maps
+need no preallocation, and a hint would only make them larger. The limit
+prevents over-allocating for inputs dominated by large values, where the
+estimate is far too high; larger objects grow as usual.
+*/
+func setCap(size int) int {
+	const min, max, pairSize = 8, 256, 24
+	size /= pairSize
+	if size <= min {
+		return 0
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+func (self *par) err() error {
+	if strings.TrimSpace(self.rest()) == `` {
+		return self.errEof()
+	}
+	return newJsonSyntaxError(self.src, self.pos, nil)
+}
+
+func (self *par) errEof() error {
+	return newJsonSyntaxError(self.src, len(self.src), nil)
+}
 
-	return fmt.Errorf(`unexpected JSON %w in position %v`, io.EOF, self.pos)
+// Simple escape sequences, by the character after the backslash.
+var jsonEscapes = [256]byte{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
 }
 
 type charset [256]bool
@@ -463,3 +791,9 @@ var (
 	exps       = new(charset).addStr(`Ee`)
 	signs      = new(charset).addStr(`+-`)
 )
+
+// Used by `rd.Validate` to check the first non-whitespace character of a body.
+var (
+	jsonHeads   = new(charset).addSet(digits).addStr(`{["-tfn`)
+	markupHeads = new(charset).addStr(`{[<`)
+)
@@ -0,0 +1,397 @@
+package rd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	r "reflect"
+	"strings"
+	"sync"
+)
+
+/*
+Implements `rd.Json.DecodeWith` for settings that require modifying the
+top-level values of a JSON object before handing them off to "encoding/json".
+For outputs other than struct pointers, and for JSON other than objects, this
+falls back on regular decoding.
+*/
+func (self Json) decodePass(out interface{}, conf *Config) error {
+	typ := derefType(r.TypeOf(out))
+	if typ == nil || typ.Kind() != r.Struct {
+		return errJsonPos(self, jsonUnmarshal(self, out, conf))
+	}
+
+	var dict map[string]json.RawMessage
+	if json.Unmarshal(self, &dict) != nil || dict == nil {
+		return errJsonPos(self, jsonUnmarshal(self, out, conf))
+	}
+
+	var lists []jsonList
+	var nulls []jsonField
+
+	fields := loadJsonFields(typ)
+	keys, err := jsonDictKeys(self, dict, fields)
+	if err != nil {
+		return errJsonPos(self, jsonUnmarshal(self, out, conf))
+	}
+
+	for _, field := range fields {
+		// Prefixes are unknown to "encoding/json".
+		if field.Kind != fieldNormal || field.Nested || field.Prefixed {
+			continue
+		}
+
+		key, ok := keys[field.Name]
+		if !ok {
+			continue
+		}
+		val := dict[key]
+
+		if conf.ZeroNull && isJsonNull(val) {
+			nulls = append(nulls, field)
+			delete(dict, key)
+			continue
+		}
+
+		fieldTyp := derefType(typeAt(typ, field.Path))
+
+		if conf.JsonSliceParser && isJsonSliceParser(fieldTyp) {
+			vals, err := jsonStrings(val)
+			if err != nil {
+				return fmt.Errorf(`unable to decode field %q: %w`, field.Name, err)
+			}
+			lists = append(lists, jsonList{field, vals, isJsonNull(val)})
+			delete(dict, key)
+			continue
+		}
+
+		if conf.Coerce {
+			val = coerceJson(val, fieldTyp)
+		}
+		if conf.JsonNumberString {
+			val = stringifyJsonNumbers(val, fieldTyp)
+		}
+		dict[key] = val
+	}
+
+	src, err := json.Marshal(dict)
+	if err != nil {
+		return err
+	}
+
+	err = jsonUnmarshal(src, out, conf)
+	if err != nil || (lists == nil && nulls == nil) {
+		return err
+	}
+
+	root, err := derefStruct(r.ValueOf(out))
+	if err != nil {
+		return err
+	}
+
+	for _, field := range nulls {
+		zeroAt(root, field.Path)
+	}
+
+	for _, list := range lists {
+		if list.Null {
+			zeroAt(root, list.Field.Path)
+			continue
+		}
+
+		err := derefAllocAt(root, list.Field.Path).Addr().Interface().(SliceParser).ParseSlice(list.Vals)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Used for `rd.Config.JsonSliceParser`.
+type jsonList struct {
+	Field jsonField
+	Vals  []string
+	Null  bool
+}
+
+func isJsonSliceParser(typ r.Type) bool {
+	ptr := r.PtrTo(typ)
+	return ptr.Implements(typeSliceParser) && !ptr.Implements(typeJsonUnmarshaler)
+}
+
+/*
+Converts a JSON array of scalars, or a single scalar, to strings, treating them
+like form values. See `rd.Config.JsonSliceParser`.
+*/
+func jsonStrings(src json.RawMessage) ([]string, error) {
+	if isJsonNull(src) {
+		return nil, nil
+	}
+
+	var vals []json.RawMessage
+	if jsonHead(src) == '[' {
+		err := json.Unmarshal(src, &vals)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		vals = []json.RawMessage{src}
+	}
+
+	out := make([]string, 0, len(vals))
+	for _, val := range vals {
+		switch jsonHead(val) {
+		case '"':
+			var str string
+			err := json.Unmarshal(val, &str)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, str)
+		case 'n':
+			out = append(out, ``)
+		case '{', '[':
+			return nil, fmt.Errorf(`expected array of scalars, got element %s`, val)
+		default:
+			out = append(out, string(bytes.TrimSpace(val)))
+		}
+	}
+	return out, nil
+}
+
+/*
+Like `json.Unmarshal`, but when rejecting unknown keys, rejects unknown fields
+at any depth, via `json.Decoder`. Trailing data is rejected either way.
+*/
+func jsonUnmarshal(src []byte, out interface{}, conf *Config) error {
+	if !conf.rejectUnknown() {
+		return json.Unmarshal(src, out)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(out)
+	if err != nil {
+		return err
+	}
+
+	if jsonHead(src[dec.InputOffset():]) != 0 {
+		return fmt.Errorf(`invalid JSON syntax in position %v: unexpected data after top-level value`, dec.InputOffset())
+	}
+	return nil
+}
+
+func coerceJson(src json.RawMessage, typ r.Type) json.RawMessage {
+	if isJsonNull(src) {
+		return src
+	}
+
+	if isListType(typ) {
+		if jsonHead(src) == '[' {
+			return src
+		}
+
+		out := make(json.RawMessage, 0, len(src)+2)
+		out = append(out, '[')
+		out = append(out, src...)
+		out = append(out, ']')
+		return out
+	}
+
+	if jsonHead(src) != '[' {
+		return src
+	}
+
+	var vals []json.RawMessage
+	if json.Unmarshal(src, &vals) == nil && len(vals) == 1 {
+		return vals[0]
+	}
+	return src
+}
+
+/*
+Used for `rd.Config.JsonNumberString`. Converts JSON numbers to strings, for
+string fields, and for elements of lists of strings. Other values are left
+as-is, and rejected by "encoding/json" as usual.
+*/
+func stringifyJsonNumbers(src json.RawMessage, typ r.Type) json.RawMessage {
+	if isJsonStringType(typ) {
+		if isJsonNumber(src) {
+			return quoteJsonNumber(src)
+		}
+		return src
+	}
+
+	if !isListType(typ) || !isJsonStringType(derefType(typ.Elem())) || jsonHead(src) != '[' {
+		return src
+	}
+
+	var vals []json.RawMessage
+	if json.Unmarshal(src, &vals) != nil {
+		return src
+	}
+
+	var changed bool
+	for i, val := range vals {
+		if isJsonNumber(val) {
+			vals[i] = quoteJsonNumber(val)
+			changed = true
+		}
+	}
+	if !changed {
+		return src
+	}
+
+	out, err := json.Marshal(vals)
+	if err != nil {
+		return src
+	}
+	return out
+}
+
+func isJsonStringType(typ r.Type) bool {
+	ptr := r.PtrTo(typ)
+	return typ.Kind() == r.String &&
+		!ptr.Implements(typeJsonUnmarshaler) &&
+		!ptr.Implements(typeTextUnmarshaler)
+}
+
+func isJsonNumber(src []byte) bool {
+	head := jsonHead(src)
+	return head == '-' || digits.has(head)
+}
+
+// Numbers don't contain characters which require escaping.
+func quoteJsonNumber(src json.RawMessage) json.RawMessage {
+	src = bytes.TrimSpace(src)
+	out := make(json.RawMessage, 0, len(src)+2)
+	out = append(out, '"')
+	out = append(out, src...)
+	out = append(out, '"')
+	return out
+}
+
+/*
+Byte slices are decoded from strings by "encoding/json", and so are types with
+custom unmarshaling, such as `net.IP`.
+*/
+func isListType(typ r.Type) bool {
+	kind := typ.Kind()
+	if !(kind == r.Array || (kind == r.Slice && typ != typeBytes)) {
+		return false
+	}
+	ptr := r.PtrTo(typ)
+	return !ptr.Implements(typeJsonUnmarshaler) && !ptr.Implements(typeTextUnmarshaler)
+}
+
+func jsonHead(src []byte) byte {
+	for _, char := range src {
+		if !whitespace.has(char) {
+			return char
+		}
+	}
+	return 0
+}
+
+func isJsonNull(src []byte) bool {
+	return jsonHead(src) == 'n'
+}
+
+var jsonStdNameCache sync.Map
+
+/*
+Names of struct fields, as seen by "encoding/json": tagged fields use the tag
+name, untagged exported fields use the Go name, and fields of embedded structs
+are promoted unless the embedded field is tagged. Unlike `loadJsonFields`,
+this includes untagged fields. Susceptible to "thundering herd" but much better
+than no caching.
+*/
+func loadJsonStdNames(typ r.Type) []string {
+	val, ok := jsonStdNameCache.Load(typ)
+	if ok {
+		return val.([]string)
+	}
+
+	var out []string
+	appendJsonStdNames(&out, typ)
+	jsonStdNameCache.Store(typ, out)
+	return out
+}
+
+func appendJsonStdNames(buf *[]string, typ r.Type) {
+	for i := range iter(typ.NumField()) {
+		field := typ.Field(i)
+		tag := field.Tag.Get(`json`)
+		if tag == `-` {
+			continue
+		}
+
+		name := tagIdent(tag)
+		if name == `` && field.Anonymous {
+			typ := derefType(field.Type)
+			if typ.Kind() == r.Struct {
+				appendJsonStdNames(buf, typ)
+				continue
+			}
+		}
+
+		if !isPublic(field.PkgPath) {
+			continue
+		}
+		if name == `` {
+			name = field.Name
+		}
+		*buf = append(*buf, name)
+	}
+}
+
+/*
+Maps field names to the keys of top-level JSON values. Like "encoding/json",
+matches keys to fields exactly when possible, and case-insensitively otherwise.
+When several keys match one field, only the last one in the source is kept, and
+the others are deleted, which means the same value wins as in regular decoding.
+The order of keys is unknown to the map, and is taken from the source.
+*/
+func jsonDictKeys(src Json, dict map[string]json.RawMessage, fields []jsonField) (map[string]string, error) {
+	spans, err := parseSpans(bytesString(src))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, len(dict))
+	var matched [][2]string // Pairs of field name and key.
+
+	for _, span := range spans {
+		key, err := jsonKey(span.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := matchField(fields, key)
+		if ok {
+			out[field.Name] = key
+			matched = append(matched, [2]string{field.Name, key})
+		}
+	}
+
+	for _, pair := range matched {
+		if out[pair[0]] != pair[1] {
+			delete(dict, pair[1])
+		}
+	}
+	return out, nil
+}
+
+// Like "encoding/json", matches field names case-insensitively.
+func hasNameFold(names []string, name string) bool {
+	for _, val := range names {
+		if strings.EqualFold(val, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a quoted JSON key to a Go string, decoding escapes if necessary.
+func jsonKey(src string) (string, error) { return jsonUnescape(src[1 : len(src)-1]) }
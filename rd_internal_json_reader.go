@@ -0,0 +1,346 @@
+package rd
+
+/*
+Streaming variant of the JSON parser in "rd_internal_json.go", used by
+`rd.ParseSetReader`. Reads the input in fixed-size chunks, without ever holding
+more than one chunk and the current top-level key. Keys are accumulated in a
+reusable buffer, which makes chunk boundaries irrelevant: a key may be split
+across any amount of reads. Accepts the same inputs and produces the same keys
+as `parseSet`.
+*/
+
+import "io"
+
+func parseSetReader(src io.Reader) (_ Set, err error) {
+	defer rec(&err)
+	par := readPar{src: src, line: 1}
+	par.top()
+	return par.out, nil
+}
+
+// Short for "reader parser".
+type readPar struct {
+	src   io.Reader
+	buf   []byte // Reusable chunk buffer.
+	chunk []byte // Current chunk, a prefix of `.buf`.
+	index int    // Index of the next byte in the chunk.
+	eof   bool   // True if the reader is exhausted.
+	pos   int    // Byte offset of the next byte in the input.
+	line  int    // Line number of the next byte, starting at 1.
+	start int    // Byte offset of the start of the current line.
+	lvl   int    // Short for "level".
+	key   []byte // Content of the current top-level key, without quotes.
+	out   Set
+}
+
+func (self *readPar) top() {
+	if self.bom() && self.next() && self.peek() == '{' {
+		self.skip()
+		self.obj()
+	}
+}
+
+// Like `par.bom`. The BOM may be split across chunks.
+func (self *readPar) bom() bool {
+	for i, char := range utf8Bom {
+		if !self.more() || self.peek() != char {
+			return i == 0
+		}
+		self.skip()
+	}
+	return true
+}
+
+func (self *readPar) any() {
+	self.next()
+	char := self.peek()
+
+	if digits.has(char) {
+		self.skip()
+		self.num()
+		return
+	}
+
+	switch char {
+	case '{':
+		self.skip()
+		self.obj()
+	case '[':
+		self.skip()
+		self.arr()
+	case '"':
+		self.skip()
+		self.str(false)
+	case 'n':
+		self.skip()
+		self.ident(`ull`)
+	case 't':
+		self.skip()
+		self.ident(`rue`)
+	case 'f':
+		self.skip()
+		self.ident(`alse`)
+	case '-':
+		self.skip()
+		if !digits.has(self.peek()) {
+			panic(self.err())
+		}
+		self.skip()
+		self.num()
+	default:
+		panic(self.err())
+	}
+}
+
+func (self *readPar) obj() {
+	self.lvl++
+	defer func() { self.lvl-- }()
+
+	self.next()
+	if self.peek() == '}' {
+		self.skip()
+		return
+	}
+
+	for {
+		self.next()
+		if self.peek() != '"' {
+			panic(self.err())
+		}
+		self.skip()
+		self.addKey()
+
+		self.next()
+		if self.peek() != ':' {
+			panic(self.err())
+		}
+		self.skip()
+		self.any()
+
+		self.next()
+		switch self.peek() {
+		case '}':
+			self.skip()
+			return
+		case ',':
+			self.skip()
+		default:
+			panic(self.err())
+		}
+	}
+}
+
+func (self *readPar) addKey() {
+	if self.lvl != 1 {
+		self.str(false)
+		return
+	}
+
+	pos, line, start := self.pos, self.line, self.start
+	self.key = self.key[:0]
+	self.str(true)
+
+	// Copying is required because the buffer is reused.
+	key, err := jsonUnescape(string(self.key))
+	if err != nil {
+		panic(self.errAt(pos, line, start, err))
+	}
+
+	if self.out == nil {
+		self.out = make(Set, setCap(0))
+	}
+	self.out.Add(key)
+}
+
+func (self *readPar) arr() {
+	self.next()
+	if self.peek() == ']' {
+		self.skip()
+		return
+	}
+
+	for {
+		self.any()
+
+		self.next()
+		switch self.peek() {
+		case ']':
+			self.skip()
+			return
+		case ',':
+			self.skip()
+		default:
+			panic(self.err())
+		}
+	}
+}
+
+// Consumes the rest of a string, including the closing quote. If `keep` is
+// true, appends the content, with escape sequences as-is, to `.key`.
+func (self *readPar) str(keep bool) {
+	for {
+		char := self.read()
+		if char == '"' {
+			return
+		}
+		if keep {
+			self.key = append(self.key, char)
+		}
+
+		// See `par.esc`.
+		if char == '\\' {
+			char = self.read()
+			if keep {
+				self.key = append(self.key, char)
+			}
+		}
+	}
+}
+
+// Same grammar as `par.num`, with the first digit already consumed.
+func (self *readPar) num() {
+	self.digits()
+
+	if self.more() && self.peek() == '.' {
+		self.skip()
+		if !digits.has(self.peek()) {
+			panic(self.err())
+		}
+		self.digits()
+	}
+
+	if self.more() && exps.has(self.peek()) {
+		self.skip()
+		if signs.has(self.peek()) {
+			self.skip()
+		}
+		if !digits.has(self.peek()) {
+			panic(self.err())
+		}
+		self.digits()
+	}
+
+	self.delim()
+}
+
+func (self *readPar) digits() {
+	for self.more() && digits.has(self.peek()) {
+		self.skip()
+	}
+}
+
+// Like `par.ident`, reports mismatches at the start of the suffix.
+func (self *readPar) ident(suffix string) {
+	pos, line, start := self.pos, self.line, self.start
+
+	for i := 0; i < len(suffix); i++ {
+		if !self.more() || self.peek() != suffix[i] {
+			panic(self.errAt(pos, line, start, nil))
+		}
+		self.skip()
+	}
+	self.delim()
+}
+
+// Scalars must be followed by a delimiter or the end of input.
+func (self *readPar) delim() {
+	if self.more() && !delims.has(self.peek()) {
+		panic(self.err())
+	}
+}
+
+func (self *readPar) next() bool {
+	for self.more() {
+		if !whitespace.has(self.peek()) {
+			return true
+		}
+		self.skip()
+	}
+	return false
+}
+
+func (self *readPar) more() bool {
+	return self.index < len(self.chunk) || self.fill()
+}
+
+func (self *readPar) peek() byte {
+	if !self.more() {
+		panic(self.err())
+	}
+	return self.chunk[self.index]
+}
+
+func (self *readPar) read() byte {
+	char := self.peek()
+	self.skip()
+	return char
+}
+
+// Must be called only after peeking.
+func (self *readPar) skip() {
+	char := self.chunk[self.index]
+	self.index++
+	self.pos++
+	if char == '\n' {
+		self.line++
+		self.start = self.pos
+	}
+}
+
+// Reads the next chunk. Returns false at the end of input.
+func (self *readPar) fill() bool {
+	if self.buf == nil {
+		self.buf = make([]byte, readChunkSize)
+	}
+
+	for empty := 1; !self.eof; empty++ {
+		size, err := self.src.Read(self.buf)
+		if err == io.EOF {
+			self.eof = true
+		} else if err != nil {
+			panic(err)
+		}
+
+		if size > 0 {
+			self.chunk, self.index = self.buf[:size], 0
+			return true
+		}
+		if !self.eof && empty >= maxEmptyReads {
+			panic(io.ErrNoProgress)
+		}
+	}
+	return false
+}
+
+const readChunkSize = 4096
+
+func (self *readPar) err() error {
+	return self.errAt(self.pos, self.line, self.start, nil)
+}
+
+// Consumes the input after the current position, up to the snippet limit, which
+// is fine because the parsing stops at the first error.
+func (self *readPar) errAt(pos, line, start int, cause error) error {
+	var buf []byte
+	for len(buf) < jsonSnippetLimit && self.more() {
+		chunk := self.chunk[self.index:]
+		if size := jsonSnippetLimit - len(buf); len(chunk) > size {
+			chunk = chunk[:size]
+		}
+		buf = append(buf, chunk...)
+		self.index += len(chunk)
+	}
+
+	snip := string(buf)
+	if cause == nil {
+		cause = jsonSyntaxCause(snip)
+	}
+
+	return JsonSyntaxError{
+		Pos:     pos,
+		Line:    line,
+		Col:     pos - start + 1,
+		Snippet: snip,
+		Cause:   cause,
+	}
+}
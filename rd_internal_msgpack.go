@@ -0,0 +1,225 @@
+package rd
+
+/*
+Simple msgpack scanner for `rd.Msgpack.Set`. Similar to the JSON parser used by
+`rd.Json.Set`: collects top-level keys and skips all other data without
+decoding it.
+*/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Input should be empty or valid msgpack.
+// Output is the set of top-level string keys.
+func parseMsgpackSet(src string) Set {
+	par := msgpackPar{src: src}
+	par.top()
+	return par.out
+}
+
+type msgpackPar struct {
+	src string
+	pos int
+	out Set
+}
+
+func (self *msgpackPar) top() {
+	if !self.more() {
+		return
+	}
+
+	size, ok := self.mapHead()
+	if !ok {
+		return
+	}
+
+	for ; size > 0; size-- {
+		key, ok := self.str()
+		if ok {
+			self.add(key)
+		} else {
+			self.any()
+		}
+		self.any()
+	}
+}
+
+// If the next value is a map, consumes its header and returns the amount of
+// key-value pairs.
+func (self *msgpackPar) mapHead() (int, bool) {
+	char := self.peek()
+
+	switch {
+	case char >= 0x80 && char <= 0x8f:
+		self.pos++
+		return int(char & 0x0f), true
+	case char == 0xde:
+		self.pos++
+		return self.uint(2), true
+	case char == 0xdf:
+		self.pos++
+		return self.uint(4), true
+	default:
+		return 0, false
+	}
+}
+
+// If the next value is a string, consumes and returns it.
+func (self *msgpackPar) str() (string, bool) {
+	char := self.peek()
+
+	var size int
+	switch {
+	case char >= 0xa0 && char <= 0xbf:
+		self.pos++
+		size = int(char & 0x1f)
+	case char == 0xd9:
+		self.pos++
+		size = self.uint(1)
+	case char == 0xda:
+		self.pos++
+		size = self.uint(2)
+	case char == 0xdb:
+		self.pos++
+		size = self.uint(4)
+	default:
+		return ``, false
+	}
+
+	start := self.pos
+	self.skip(size)
+	return self.src[start:self.pos], true
+}
+
+// Skips one arbitrary value.
+func (self *msgpackPar) any() {
+	char := self.peek()
+
+	switch {
+	case char <= 0x7f || char >= 0xe0 || char == 0xc0 || char == 0xc2 || char == 0xc3:
+		self.pos++
+
+	case char >= 0x80 && char <= 0x8f:
+		self.pos++
+		self.items(2 * int(char&0x0f))
+
+	case char >= 0x90 && char <= 0x9f:
+		self.pos++
+		self.items(int(char & 0x0f))
+
+	case char >= 0xa0 && char <= 0xbf:
+		self.pos++
+		self.skip(int(char & 0x1f))
+
+	case char == 0xc4, char == 0xd9:
+		self.pos++
+		self.skip(self.uint(1))
+	case char == 0xc5, char == 0xda:
+		self.pos++
+		self.skip(self.uint(2))
+	case char == 0xc6, char == 0xdb:
+		self.pos++
+		self.skip(self.uint(4))
+
+	// Extensions have a type byte after the size.
+	case char == 0xc7:
+		self.pos++
+		self.skip(self.uint(1) + 1)
+	case char == 0xc8:
+		self.pos++
+		self.skip(self.uint(2) + 1)
+	case char == 0xc9:
+		self.pos++
+		self.skip(self.uint(4) + 1)
+	case char >= 0xd4 && char <= 0xd8:
+		self.pos++
+		self.skip((1 << (char - 0xd4)) + 1)
+
+	case char == 0xca:
+		self.pos++
+		self.skip(4)
+	case char == 0xcb:
+		self.pos++
+		self.skip(8)
+
+	// Unsigned and signed integers: 0xcc-0xcf and 0xd0-0xd3.
+	case char >= 0xcc && char <= 0xd3:
+		self.pos++
+		self.skip(1 << ((char - 0xcc) % 4))
+
+	case char == 0xdc:
+		self.pos++
+		self.items(self.uint(2))
+	case char == 0xdd:
+		self.pos++
+		self.items(self.uint(4))
+	case char == 0xde:
+		self.pos++
+		self.items(2 * self.uint(2))
+	case char == 0xdf:
+		self.pos++
+		self.items(2 * self.uint(4))
+
+	default:
+		panic(self.err())
+	}
+}
+
+func (self *msgpackPar) items(count int) {
+	for ; count > 0; count-- {
+		self.any()
+	}
+}
+
+// Reads a big-endian unsigned integer of the given byte width.
+func (self *msgpackPar) uint(width int) int {
+	start := self.pos
+	self.skip(width)
+	src := self.src[start:self.pos]
+
+	switch width {
+	case 1:
+		return int(src[0])
+	case 2:
+		return int(binary.BigEndian.Uint16(stringToBytesUnsafe(src)))
+	default:
+		return int(binary.BigEndian.Uint32(stringToBytesUnsafe(src)))
+	}
+}
+
+func (self *msgpackPar) skip(size int) {
+	if size < 0 || size > len(self.src)-self.pos {
+		self.pos = len(self.src)
+		panic(self.err())
+	}
+	self.pos += size
+}
+
+func (self *msgpackPar) more() bool { return self.pos < len(self.src) }
+
+func (self *msgpackPar) peek() byte {
+	if !self.more() {
+		panic(self.err())
+	}
+	return self.src[self.pos]
+}
+
+func (self *msgpackPar) add(key string) {
+	if self.out == nil {
+		self.out = make(Set, 16)
+	}
+	self.out.Add(key)
+}
+
+func (self *msgpackPar) err() error {
+	if self.more() {
+		return errBadReq(fmt.Errorf(
+			`invalid msgpack syntax in position %v: unexpected byte 0x%02x`,
+			self.pos, self.src[self.pos],
+		))
+	}
+	return errBadReq(fmt.Errorf(`unexpected msgpack %w in position %v`, io.EOF, self.pos))
+}
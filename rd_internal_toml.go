@@ -0,0 +1,374 @@
+package rd
+
+/*
+Simple TOML parser for `rd.Toml.Set`. Similar to the JSON parser used by
+`rd.Json.Set`: collects top-level keys and discards all other data.
+*/
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Input should be empty or valid TOML.
+// Output is the set of top-level keys.
+func parseTomlSet(src string) Set {
+	par := tomlPar{src: src}
+	par.top()
+	return par.out
+}
+
+type tomlPar struct {
+	src string
+	pos int
+	tab bool // True after the first table header.
+	out Set
+}
+
+func (self *tomlPar) top() {
+	for {
+		self.space()
+		if !self.more() {
+			return
+		}
+		if self.peek() == '[' {
+			self.header()
+		} else {
+			self.pair()
+		}
+	}
+}
+
+func (self *tomlPar) header() {
+	self.pos++
+	arr := self.more() && self.peek() == '['
+	if arr {
+		self.pos++
+	}
+
+	self.blank()
+	self.add(self.key())
+	self.tab = true
+	self.keyRest()
+	self.blank()
+
+	self.expect(']')
+	if arr {
+		self.expect(']')
+	}
+	self.eol()
+}
+
+func (self *tomlPar) pair() {
+	key := self.key()
+	if !self.tab {
+		self.add(key)
+	}
+	self.keyValRest()
+	self.eol()
+}
+
+// Parses the rest of a key-value pair after the first key segment.
+func (self *tomlPar) keyValRest() {
+	self.keyRest()
+	self.blank()
+	self.expect('=')
+	self.blank()
+	self.val()
+}
+
+// Parses one segment of a possibly-dotted key.
+func (self *tomlPar) key() string {
+	switch self.peek() {
+	case '"':
+		start := self.pos
+		self.pos++
+		self.basicStr()
+
+		out, err := strconv.Unquote(self.src[start:self.pos])
+		if err != nil {
+			panic(self.err())
+		}
+		return out
+
+	case '\'':
+		self.pos++
+		start := self.pos
+		self.literalStr()
+		return self.src[start : self.pos-1]
+
+	default:
+		start := self.pos
+		for self.more() && tomlBare.has(self.peek()) {
+			self.pos++
+		}
+		if self.pos == start {
+			panic(self.err())
+		}
+		return self.src[start:self.pos]
+	}
+}
+
+// Parses the remaining segments of a dotted key.
+func (self *tomlPar) keyRest() {
+	for {
+		self.blank()
+		if !self.more() || self.peek() != '.' {
+			return
+		}
+		self.pos++
+		self.blank()
+		self.key()
+	}
+}
+
+func (self *tomlPar) val() {
+	switch self.peek() {
+	case '"':
+		if strings.HasPrefix(self.rest(), `"""`) {
+			self.pos += 3
+			self.multiStr(`"""`, true)
+		} else {
+			self.pos++
+			self.basicStr()
+		}
+
+	case '\'':
+		if strings.HasPrefix(self.rest(), `'''`) {
+			self.pos += 3
+			self.multiStr(`'''`, false)
+		} else {
+			self.pos++
+			self.literalStr()
+		}
+
+	case '[':
+		self.pos++
+		self.arr()
+
+	case '{':
+		self.pos++
+		self.inlineTab()
+
+	default:
+		self.scalar()
+	}
+}
+
+func (self *tomlPar) basicStr() {
+	for self.more() {
+		switch self.peek() {
+		case '"':
+			self.pos++
+			return
+		case '\\':
+			self.pos += 2
+		case '\n':
+			panic(self.err())
+		default:
+			self.pos++
+		}
+	}
+	panic(self.err())
+}
+
+func (self *tomlPar) literalStr() {
+	for self.more() {
+		switch self.peek() {
+		case '\'':
+			self.pos++
+			return
+		case '\n':
+			panic(self.err())
+		default:
+			self.pos++
+		}
+	}
+	panic(self.err())
+}
+
+// Up to two quotes adjacent to the closing delimiter belong to the content.
+func (self *tomlPar) multiStr(delim string, esc bool) {
+	for self.more() {
+		if esc && self.peek() == '\\' {
+			self.pos += 2
+			continue
+		}
+
+		if strings.HasPrefix(self.rest(), delim) {
+			self.pos += len(delim)
+			for i := 0; i < 2 && self.more() && self.peek() == delim[0]; i++ {
+				self.pos++
+			}
+			return
+		}
+		self.pos++
+	}
+	panic(self.err())
+}
+
+func (self *tomlPar) arr() {
+	for {
+		self.space()
+		if self.peek() == ']' {
+			self.pos++
+			return
+		}
+
+		self.val()
+		self.space()
+
+		switch self.peek() {
+		case ',':
+			self.pos++
+		case ']':
+			self.pos++
+			return
+		default:
+			panic(self.err())
+		}
+	}
+}
+
+func (self *tomlPar) inlineTab() {
+	for {
+		self.space()
+		if self.peek() == '}' {
+			self.pos++
+			return
+		}
+
+		self.key()
+		self.keyValRest()
+		self.space()
+
+		switch self.peek() {
+		case ',':
+			self.pos++
+		case '}':
+			self.pos++
+			return
+		default:
+			panic(self.err())
+		}
+	}
+}
+
+/*
+Numbers, booleans, dates and times. We don't need to validate them, just find
+the end. The only special case is the optional space between the date and the
+time in a datetime.
+*/
+func (self *tomlPar) scalar() {
+	start := self.pos
+	for self.more() && !tomlDelims.has(self.peek()) {
+		self.pos++
+	}
+	if self.pos == start {
+		panic(self.err())
+	}
+
+	if isTomlDate(self.src[start:self.pos]) &&
+		strings.HasPrefix(self.rest(), ` `) &&
+		len(self.rest()) > 1 && digits.has(self.rest()[1]) {
+		self.pos++
+		self.scalar()
+	}
+}
+
+func isTomlDate(val string) bool {
+	return len(val) == 10 && val[4] == '-' && val[7] == '-'
+}
+
+// Skips spaces, tabs, newlines and comments.
+func (self *tomlPar) space() {
+	for self.more() {
+		switch self.peek() {
+		case ' ', '\t', '\r', '\n':
+			self.pos++
+		case '#':
+			self.comment()
+		default:
+			return
+		}
+	}
+}
+
+// Skips spaces and tabs.
+func (self *tomlPar) blank() {
+	for self.more() && (self.peek() == ' ' || self.peek() == '\t') {
+		self.pos++
+	}
+}
+
+func (self *tomlPar) comment() {
+	index := strings.IndexByte(self.rest(), '\n')
+	if index < 0 {
+		self.pos = len(self.src)
+	} else {
+		self.pos += index
+	}
+}
+
+func (self *tomlPar) eol() {
+	self.blank()
+	if !self.more() {
+		return
+	}
+
+	switch self.peek() {
+	case '#':
+		self.comment()
+	case '\r', '\n':
+	default:
+		panic(self.err())
+	}
+}
+
+func (self *tomlPar) expect(char byte) {
+	if self.peek() != char {
+		panic(self.err())
+	}
+	self.pos++
+}
+
+func (self *tomlPar) more() bool { return self.pos < len(self.src) }
+
+func (self *tomlPar) rest() string {
+	if self.pos >= len(self.src) {
+		return ``
+	}
+	return self.src[self.pos:]
+}
+
+func (self *tomlPar) peek() byte {
+	if !self.more() {
+		panic(self.err())
+	}
+	return self.src[self.pos]
+}
+
+func (self *tomlPar) add(key string) {
+	if self.out == nil {
+		self.out = make(Set, 16)
+	}
+	self.out.Add(key)
+}
+
+func (self *tomlPar) err() error {
+	rest := strings.TrimSpace(self.rest())
+	if len(rest) > 0 {
+		return errBadReq(fmt.Errorf(
+			`invalid TOML syntax in position %v: unexpected %q`,
+			self.pos, rest,
+		))
+	}
+	return errBadReq(fmt.Errorf(`unexpected TOML %w in position %v`, io.EOF, self.pos))
+}
+
+var (
+	tomlBare   = new(charset).addStr(`ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-`)
+	tomlDelims = new(charset).addStr(" \t\r\n,]}#")
+)
@@ -0,0 +1,42 @@
+package rd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Input should be empty or valid XML.
+// Output is the set of element names at the second level.
+func parseXmlSet(src []byte) Set {
+	dec := xml.NewDecoder(bytes.NewReader(src))
+	var out Set
+	var lvl int
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			if lvl > 0 {
+				panic(errBadReq(io.ErrUnexpectedEOF))
+			}
+			return out
+		}
+		if err != nil {
+			panic(errBadReq(err))
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			lvl++
+			if lvl == 2 {
+				if out == nil {
+					out = make(Set, setCap(0))
+				}
+				out.Add(tok.Name.Local)
+			}
+		case xml.EndElement:
+			lvl--
+		}
+	}
+}
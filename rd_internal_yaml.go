@@ -0,0 +1,139 @@
+package rd
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+Collects top-level keys of the first YAML document. See `rd.Yaml.Set` for the
+limitations.
+*/
+func parseYamlSet(src string) (out Set) {
+	started := false
+
+	for len(src) > 0 {
+		var line string
+		index := strings.IndexByte(src, '\n')
+		if index >= 0 {
+			line, src = src[:index], src[index+1:]
+		} else {
+			line, src = src, ``
+		}
+		line = strings.TrimSuffix(line, "\r")
+
+		if strings.HasPrefix(line, `---`) && isYamlMarkerEnd(line[3:]) {
+			if started {
+				return
+			}
+			started = true
+			continue
+		}
+
+		if strings.HasPrefix(line, `...`) && isYamlMarkerEnd(line[3:]) {
+			if started {
+				return
+			}
+			continue
+		}
+
+		if line == `` || whitespace.has(line[0]) {
+			continue
+		}
+
+		switch line[0] {
+		case '#':
+			continue
+		case '%':
+			if !started {
+				continue
+			}
+		}
+
+		started = true
+
+		key, ok := yamlKey(line)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make(Set, 16)
+		}
+		out.Add(key)
+	}
+	return
+}
+
+func isYamlMarkerEnd(rest string) bool {
+	return rest == `` || whitespace.has(rest[0])
+}
+
+// Parses the key of a top-level mapping entry, if the line starts with one.
+func yamlKey(line string) (string, bool) {
+	switch line[0] {
+	case '"':
+		end := yamlQuoteEnd(line, '"')
+		if end < 0 {
+			return ``, false
+		}
+		key, err := strconv.Unquote(line[:end+1])
+		if err != nil || !isYamlColon(line[end+1:]) {
+			return ``, false
+		}
+		return key, true
+
+	case '\'':
+		end := yamlQuoteEnd(line, '\'')
+		if end < 0 || !isYamlColon(line[end+1:]) {
+			return ``, false
+		}
+		return strings.ReplaceAll(line[1:end], `''`, `'`), true
+
+	case '-', '?', '[', '{', '|', '>', '!', '&', '*', '@', '`':
+		if line[0] == '-' && len(line) > 1 && !whitespace.has(line[1]) {
+			break
+		}
+		return ``, false
+	}
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ':':
+			if isYamlColon(line[i:]) {
+				return strings.TrimRight(line[:i], " \t"), true
+			}
+		case '#':
+			if i > 0 && whitespace.has(line[i-1]) {
+				return ``, false
+			}
+		}
+	}
+	return ``, false
+}
+
+// Input must start with the opening quote.
+func yamlQuoteEnd(line string, quote byte) int {
+	for i := 1; i < len(line); i++ {
+		char := line[i]
+
+		if quote == '"' && char == '\\' {
+			i++
+			continue
+		}
+
+		if char == quote {
+			if quote == '\'' && i+1 < len(line) && line[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// Input should start with optional spaces, followed by a colon.
+func isYamlColon(rest string) bool {
+	rest = strings.TrimLeft(rest, " \t")
+	return strings.HasPrefix(rest, `:`) && isYamlMarkerEnd(rest[1:])
+}
@@ -1,9 +1,16 @@
 package rd
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	r "reflect"
+	"sort"
+	"sync"
 )
 
 /*
@@ -16,9 +23,20 @@ type Json []byte
 
 /*
 Fully downloads the request body and stores it as-is, without any modification
-or validation. Used by `rd.Download`.
+or validation, except for removing a leading UTF-8 BOM, which some clients
+prepend to the body. Used by `rd.Download`. The size of the body is limited by
+`rd.JsonLimit`, which is unbounded by default; see `rd.Json.DownloadLimited`.
 */
 func (self *Json) Download(req *http.Request) error {
+	return self.DownloadLimited(req, JsonLimit)
+}
+
+/*
+Downloads the request body, like `rd.Json.Download`, reading at most the given
+amount of bytes. When the body is larger, returns an error with HTTP status
+413, without consuming the rest of the body. Zero or negative means unbounded.
+*/
+func (self *Json) DownloadLimited(req *http.Request, limit int64) error {
 	if req == nil {
 		self.Zero()
 		return nil
@@ -30,15 +48,39 @@ func (self *Json) Download(req *http.Request) error {
 		return nil
 	}
 
-	out, err := io.ReadAll(body)
+	var src io.Reader = body
+	if limit > 0 {
+		// One extra byte distinguishes a body at the limit from a larger one.
+		src = io.LimitReader(body, limit+1)
+	}
+
+	out, err := io.ReadAll(src)
 	if err != nil {
 		return errBadReq(err)
 	}
 
-	*self = out
+	if limit > 0 && int64(len(out)) > limit {
+		return Err{http.StatusRequestEntityTooLarge, fmt.Errorf(`request body exceeds the limit of %v bytes`, limit)}
+	}
+
+	*self = trimBom(out)
 	return nil
 }
 
+/*
+Downloads the request body, like `rd.Json.Download`, aborting when the given
+context is done. See `rd.DownloadContext`.
+*/
+func (self *Json) DownloadContext(ctx context.Context, req *http.Request) (err error) {
+	if req == nil {
+		self.Zero()
+		return nil
+	}
+
+	defer watchBody(ctx, req)(&err)
+	return self.Download(req)
+}
+
 /*
 Implement `json.Unmarshaler` by storing the input as-is, exactly like
 `json.RawMessage`. This allows to include `rd.Json` into other data structures,
@@ -64,10 +106,165 @@ func (self *Json) Zero() {
 
 /*
 Implement `rd.Decoder` by calling `json.Unmarshal`. The output must be a non-nil
-pointer to an arbitrary Go value.
+pointer to an arbitrary Go value. Unlike `rd.Form`, this supports
+dynamically-typed `interface{}` fields, which receive maps, slices and other
+values, as defined by "encoding/json", and `json.RawMessage` fields, which
+receive the exact JSON text of the value. Errors mention the byte position in
+the JSON. Syntax errors are `rd.JsonSyntaxError`, which also provides the line,
+column, and a snippet, and wraps `*json.SyntaxError`; type errors wrap
+`*json.UnmarshalTypeError`. Use `errors.As` to extract them.
 */
 func (self Json) Decode(out interface{}) error {
-	return errBadReq(json.Unmarshal(self, out))
+	return errBadReq(errJsonPos(self, json.Unmarshal(self, out)))
+}
+
+/*
+Decodes into an arbitrary output, like `rd.Json.Decode`, using the provided
+settings. See `rd.Config`. Settings affecting JSON apply only to the top-level
+fields of struct outputs, and require an additional pass over the JSON.
+*/
+func (self Json) DecodeWith(out interface{}, conf Config) (err error) {
+	defer rescue(&err)
+
+	if conf.Strict {
+		err := checkSetUnique(bytesString(self))
+		if err != nil {
+			return errBadReq(err)
+		}
+	}
+
+	if conf.RejectUnknown {
+		err := self.checkUnknown(out)
+		if err != nil {
+			return errBadReq(err)
+		}
+	}
+
+	// The pass may modify the JSON, which invalidates the positions.
+	if conf.jsonPass() {
+		return errBadReq(self.decodePass(out, &conf))
+	}
+	return errBadReq(errJsonPos(self, jsonUnmarshal(self, out, &conf)))
+}
+
+/*
+Used for `rd.Config.RejectUnknown`. Lists all unknown top-level keys, matched
+like in `rd.Json.DecodeResidual`. Unknown keys of nested objects are rejected
+by "encoding/json" during decoding; see `jsonUnmarshal`.
+*/
+func (self Json) checkUnknown(out interface{}) error {
+	typ := derefType(r.TypeOf(out))
+	if typ == nil || typ.Kind() != r.Struct || jsonHead(self) != '{' {
+		return nil
+	}
+
+	spans, err := parseSpans(bytesString(self))
+	if err != nil {
+		return err
+	}
+
+	names := loadJsonStdNames(typ)
+	var keys []string
+
+	for _, span := range spans {
+		key, err := jsonKey(span.Key)
+		if err != nil {
+			return err
+		}
+		if !hasNameFold(names, key) {
+			keys = append(keys, key)
+		}
+	}
+	return errUnknownKeys(keys)
+}
+
+/*
+Decodes into an arbitrary output, like `rd.Json.Decode`, and returns the
+residual: a JSON object with the top-level key-value pairs which don't
+correspond to any field of the output struct, preserving their order and exact
+text. Useful for forward-compatible APIs which log or forward unknown data.
+Keys are matched against fields by the rules of "encoding/json", including
+case-insensitive matching and untagged fields. For outputs other than struct
+pointers, and for JSON other than objects, the residual is always nil. When all
+keys are known, the residual is also nil.
+*/
+func (self Json) DecodeResidual(out interface{}) (Json, error) {
+	err := self.Decode(out)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := derefType(r.TypeOf(out))
+	if typ == nil || typ.Kind() != r.Struct || jsonHead(self) != '{' {
+		return nil, nil
+	}
+
+	spans, err := parseSpans(bytesString(self))
+	if err != nil {
+		return nil, errBadReq(err)
+	}
+
+	names := loadJsonStdNames(typ)
+	var buf []byte
+
+	for _, span := range spans {
+		key, err := jsonKey(span.Key)
+		if err != nil {
+			return nil, errBadReq(err)
+		}
+		if hasNameFold(names, key) {
+			continue
+		}
+
+		if buf == nil {
+			buf = append(buf, '{')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, span.Key...)
+		buf = append(buf, ':')
+		buf = append(buf, span.Val...)
+	}
+
+	if buf == nil {
+		return nil, nil
+	}
+	return append(buf, '}'), nil
+}
+
+/*
+Splits concatenated JSON into successive top-level values, such as objects sent
+back-to-back by streaming clients, and calls the function with each value, in
+order. Values may be separated by arbitrary whitespace or none. Stops at the
+end of input; empty input doesn't call the function. Errors returned by the
+function are propagated as-is, stopping the iteration. Malformed JSON produces
+an error with HTTP status 400, after calling the function for all preceding
+values. Each value is passed to the function as a separate copy:
+
+	err := rd.Json(src).DecodeMulti(func(src rd.Json) error {
+		var val SomeType
+		err := src.Decode(&val)
+		...
+	})
+*/
+func (self Json) DecodeMulti(fun func(Json) error) error {
+	dec := json.NewDecoder(bytes.NewReader(self))
+
+	for {
+		var val json.RawMessage
+		err := dec.Decode(&val)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errBadReq(err)
+		}
+
+		err = fun(Json(val))
+		if err != nil {
+			return err
+		}
+	}
 }
 
 // Implement `rd.Haserer` by calling `rd.Json.Set`.
@@ -76,7 +273,11 @@ func (self Json) Haser() Haser { return self.Set() }
 /*
 Implement `rd.Setter`. Returns an instance of `rd.Set` with the keys of the
 top-level object in the JSON text. Assumes that JSON is either valid or
-completely empty (only whitespace). Panics on malformed JSON.
+completely empty (only whitespace). A leading UTF-8 BOM is skipped. JSON other
+than an object, such as an array, produces an empty set without being fully
+validated, just like empty JSON; use `rd.Json.Valid` to tell them apart. Panics
+on malformed JSON, with an error wrapping `rd.JsonSyntaxError`. To handle
+malformed JSON without panicking, use `rd.Json.TrySet`.
 
 Unlike other decoders provided by this package, `rd.Json.Haser` is not a free
 cast; it has to re-parse the JSON to build the set of top-level object keys. It
@@ -89,7 +290,159 @@ an unsafe cast from `[]byte` to `string`. Parts of the resulting string are
 used as map keys. Mutating the JSON slice after calling this method will result
 in undefined behavior. Mutating the resulting set is perfectly safe.
 */
-func (self Json) Set() Set { return parseSet(bytesString(self)) }
+func (self Json) Set() Set {
+	out, err := self.TrySet()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+/*
+Like `rd.Json.Set`, but also includes the keys of nested objects, as dotted
+paths. For example, `{"inner": {"innerStr": 10}}` produces the keys "inner" and
+"inner.innerStr". Arrays don't add path segments: for objects inside arrays,
+keys are recorded under the path of the array, so `{"list": [{"one": 10}]}`
+produces "list" and "list.one". Uses the same streaming parser as
+`rd.Json.Set`, with the same requirements, and panics with the same errors.
+
+Unlike `rd.Json.Set`, this allocates a string for each nested key.
+*/
+func (self Json) SetNested() Set {
+	out, err := trySet(parseSetNested, bytesString(self))
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+/*
+Like `rd.Json.Set`, but tolerates a trailing comma before `}` or `]`, at any
+level, such as in `{"one": [10, 20,], "two": 30,}`. Useful for introspecting
+hand-written or otherwise non-compliant inputs. Multiple or leading commas are
+still rejected. The default `rd.Json.Set` remains strict, matching
+"encoding/json", which also rejects trailing commas when decoding.
+*/
+func (self Json) SetLenient() Set {
+	out, err := trySet(parseSetLenient, bytesString(self))
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+/*
+Like `rd.Json.Set`, but instead of panicking on malformed JSON, returns an
+error with HTTP status 400, wrapping `rd.JsonSyntaxError`. Useful for
+distinguishing malformed client input from programmer errors.
+*/
+func (self Json) TrySet() (Set, error) { return trySet(parseSet, bytesString(self)) }
+
+/*
+Checks that the JSON text is valid, without decoding it. Returns nil or an
+error with HTTP status 400, wrapping `rd.JsonSyntaxError` which describes the
+first problem and its position. Unlike `rd.Json.Set`, which only collects
+top-level keys and assumes that the JSON is valid, this validates the entire
+input, which must be exactly one JSON value, optionally surrounded by
+whitespace. Like `json.Valid`, treats empty input as invalid. Uses the same
+parser as `rd.Json.Set`, and allocates only for errors. Useful as a cheap
+pre-check before decoding.
+*/
+func (self Json) Valid() (err error) {
+	defer trans(&err, errBadReq)
+	return parseValid(bytesString(self))
+}
+
+/*
+Like `rd.Json.TrySet`, but also returns an error with HTTP status 400 when a
+top-level key occurs more than once. Keys are compared after decoding escape
+sequences, so `"one"` and `"\u006fne"` are duplicates. Duplicate keys are
+ambiguous: "encoding/json" silently uses the last value, while other parsers
+may use the first, which can be exploited to smuggle data past validation.
+Duplicate keys in nested objects are not detected. Also used by
+`rd.Json.DecodeWith` with `rd.Config.Strict`.
+*/
+func (self Json) SetStrict() (_ Set, err error) {
+	defer trans(&err, errBadReq)
+	return parseSetUnique(bytesString(self))
+}
+
+/*
+Returns a decoder which wraps this JSON and computes its set of top-level keys
+at most once, on the first call to `.Haser`, `.Set`, or `.TrySet`, reusing the
+result afterwards. Useful for handlers which check key presence repeatedly, or
+in addition to decoding. `rd.Json` itself remains free of caching and side
+effects.
+*/
+func (self Json) Cached() *CachedJson { return &CachedJson{src: self} }
+
+/*
+Memoizing wrapper around `rd.Json`, returned by `rd.Json.Cached`. Implements
+`rd.Dec`. Decoding delegates to `rd.Json` as-is, and the set of top-level keys
+is computed once and shared between calls. Safe for concurrent use, as long as
+callers don't mutate the resulting set, which is shared; copy it via
+`rd.Set.Union` before mutating. Must be used by pointer, and must not be copied
+after first use.
+*/
+type CachedJson struct {
+	src  Json
+	once sync.Once
+	set  Set
+	err  error
+}
+
+// Returns the underlying JSON.
+func (self *CachedJson) Json() Json { return self.src }
+
+// Implement `rd.Decoder` via `rd.Json.Decode`.
+func (self *CachedJson) Decode(out interface{}) error { return self.src.Decode(out) }
+
+// Decodes via `rd.Json.DecodeWith`.
+func (self *CachedJson) DecodeWith(out interface{}, conf Config) error {
+	return self.src.DecodeWith(out, conf)
+}
+
+// Implement `rd.Haserer` by returning the cached set. See `rd.CachedJson.Set`.
+func (self *CachedJson) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Like `rd.Json.Set`, but computes the set only once, and
+returns the same set on every call. Panics on malformed JSON, every time.
+*/
+func (self *CachedJson) Set() Set {
+	out, err := self.TrySet()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Like `rd.Json.TrySet`, but computes the set or error only once.
+func (self *CachedJson) TrySet() (Set, error) {
+	self.once.Do(func() { self.set, self.err = self.src.TrySet() })
+	return self.set, self.err
+}
+
+/*
+Like `rd.Json.TrySet`, but reads the JSON from the given reader, without
+buffering all of it in memory. Memory usage is limited to one buffered chunk,
+the current top-level key, and the resulting set. Useful for computing the
+key set of large request bodies while streaming them. Keys may be split across
+any amount of reads. Malformed JSON produces an error with HTTP status 400,
+wrapping `rd.JsonSyntaxError`. Errors from the reader are also returned with
+status 400. The reader is consumed up to the end of the top-level value, and
+possibly further due to buffering.
+*/
+func ParseSetReader(src io.Reader) (_ Set, err error) {
+	defer trans(&err, errBadReq)
+	return parseSetReader(src)
+}
+
+func trySet(fun func(string) Set, src string) (_ Set, err error) {
+	defer trans(&err, errBadReq)
+	defer rec(&err)
+	return fun(src), nil
+}
 
 /*
 Simple string set backed by a Go map. Implements `rd.Haser`. Generated by
@@ -111,3 +464,177 @@ func (self Set) Add(val string) { self[val] = struct{}{} }
 
 // Deletes the value from the set.
 func (self Set) Del(val string) { delete(self, val) }
+
+// Returns the amount of values in the set. Nil is an empty set.
+func (self Set) Len() int { return len(self) }
+
+/*
+Returns the values of the set as a new slice, sorted in ascending order,
+regardless of insertion order. Returns nil for an empty set. Useful for logging
+and for deterministic output, such as in error responses.
+*/
+func (self Set) Keys() []string {
+	if !(len(self) > 0) {
+		return nil
+	}
+
+	out := make([]string, 0, len(self))
+	for key := range self {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+/*
+Implement `json.Marshaler`, encoding the set as an array of strings, sorted like
+in `rd.Set.Keys`, rather than an object with empty values. Nil encodes as
+"null", and an empty set encodes as "[]". Useful for reporting the received
+keys in responses and test fixtures.
+*/
+func (self Set) MarshalJSON() ([]byte, error) {
+	if self == nil {
+		return []byte(`null`), nil
+	}
+
+	keys := self.Keys()
+	if keys == nil {
+		keys = []string{}
+	}
+	return json.Marshal(keys)
+}
+
+/*
+Implement `json.Unmarshaler`, decoding an array of strings, the inverse of
+`rd.Set.MarshalJSON`. Replaces the existing values, if any. Duplicates are
+allowed. Null produces a nil set.
+*/
+func (self *Set) UnmarshalJSON(src []byte) error {
+	var keys []string
+	err := json.Unmarshal(src, &keys)
+	if err != nil {
+		return err
+	}
+
+	if keys == nil {
+		*self = nil
+		return nil
+	}
+
+	out := make(Set, len(keys))
+	for _, key := range keys {
+		out.Add(key)
+	}
+	*self = out
+	return nil
+}
+
+/*
+Returns a new set with the values present in either set. Like the other set
+operations, this treats nil as an empty set, never modifies either set, and
+always returns a non-nil set.
+*/
+func (self Set) Union(val Set) Set {
+	out := make(Set, len(self)+len(val))
+	for key := range self {
+		out.Add(key)
+	}
+	for key := range val {
+		out.Add(key)
+	}
+	return out
+}
+
+// Returns a new set with the values present in both sets. See `rd.Set.Union`.
+func (self Set) Intersect(val Set) Set {
+	one, two := self, val
+	if len(one) > len(two) {
+		one, two = two, one
+	}
+
+	out := make(Set, len(one))
+	for key := range one {
+		if two.Has(key) {
+			out.Add(key)
+		}
+	}
+	return out
+}
+
+/*
+Returns a new set with the values present in the receiver but not in the
+argument. See `rd.Set.Union`.
+*/
+func (self Set) Diff(val Set) Set {
+	out := make(Set, len(self))
+	for key := range self {
+		if !val.Has(key) {
+			out.Add(key)
+		}
+	}
+	return out
+}
+
+// True if both sets have the same values. Nil equals an empty set.
+func (self Set) Equal(val Set) bool {
+	if len(self) != len(val) {
+		return false
+	}
+	for key := range self {
+		if !val.Has(key) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Converts an arbitrary `rd.Haser` to `rd.Set`. If the input is already an
+`rd.Set`, returns it as-is. If the input implements `rd.Setter`, returns the
+result of its `.Set` method. Otherwise returns nil, because an arbitrary
+`rd.Haser` can only answer membership questions and can't be enumerated.
+*/
+func AsSet(val Haser) Set {
+	switch val := val.(type) {
+	case Set:
+		return val
+	case Setter:
+		return val.Set()
+	default:
+		return nil
+	}
+}
+
+/*
+Used for `rd.TypeNdjson`. Decodes every JSON value from the reader into a new
+element of the output slice. Whitespace between values, including newlines, is
+ignored, which also allows pretty-printed JSON values. Decodes into a new
+slice, which replaces the output only on success, leaving the output unchanged
+on failure.
+*/
+func decodeNdjson(src io.Reader, out interface{}) error {
+	val := r.ValueOf(out)
+	if val.Kind() != r.Ptr || val.IsNil() || val.Elem().Kind() != r.Slice {
+		return errInternal(fmt.Errorf(`unable to decode %q into %T, expected a non-nil slice pointer`, TypeNdjson, out))
+	}
+
+	list := val.Elem()
+	buf := r.MakeSlice(list.Type(), 0, 0)
+	typ := list.Type().Elem()
+	dec := json.NewDecoder(src)
+
+	for {
+		elem := r.New(typ)
+		err := dec.Decode(elem.Interface())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errBadReq(fmt.Errorf(`failed to decode record %v: %w`, buf.Len(), err))
+		}
+		buf = r.Append(buf, elem.Elem())
+	}
+
+	list.Set(buf)
+	return nil
+}
@@ -0,0 +1,140 @@
+package rd
+
+import "net/http"
+
+// Which source wins in `rd.Merged` when a key is present in both.
+type Precedence byte
+
+const (
+	// Values from the body override values from the URL query.
+	BodyWins Precedence = iota
+
+	// Values from the URL query override values from the body.
+	QueryWins
+)
+
+/*
+Decoder which combines the request body with the URL query, returned by
+`rd.DownloadMerged`. Keys missing from one source fall back to the other, and
+keys present in both are taken from the source that wins according to `.Prec`.
+The query is decoded like `rd.Form`. Haser and set include the keys of both
+sources.
+
+When the body is also a form, the sources are merged key by key into a single
+form, which is decoded once. Otherwise, both sources are decoded into the same
+output, one after another, relying on the fact that other decoders leave the
+fields of missing keys unchanged. The "default" tag and the "required" tag
+option of form fields apply only to keys missing from both sources, where
+top-level keys of the body are matched to fields like in `rd.DecodeFields`.
+For outputs other than structs, the query must be empty, and is skipped.
+
+Useful for endpoints which take filters in the URL query and payload in the
+body. When the source of each field is fixed, prefer `rd.Binder`, which also
+prevents clients from overriding fields via the other source.
+*/
+type Merged struct {
+	Body  Dec
+	Query Form
+	Prec  Precedence
+}
+
+/*
+Implement `rd.Decoder`. Decodes the losing source first, then the winning
+source, stopping at the first error.
+*/
+func (self Merged) Decode(out interface{}) error {
+	body, ok := self.Body.(Form)
+	if ok {
+		return self.mergeForms(body).Decode(out)
+	}
+
+	if self.Prec == QueryWins {
+		err := self.decodeBody(out)
+		if err != nil {
+			return err
+		}
+		return self.decodeQuery(out)
+	}
+
+	err := self.decodeQuery(out)
+	if err != nil {
+		return err
+	}
+	return self.decodeBody(out)
+}
+
+func (self Merged) decodeBody(out interface{}) error {
+	if self.Body == nil {
+		return nil
+	}
+	return self.Body.Decode(out)
+}
+
+/*
+Fields provided by the body are excluded from defaults and required checks of
+the query, which are thus performed once, regardless of the decoding order.
+*/
+func (self Merged) decodeQuery(out interface{}) error {
+	var other Set
+	if self.Body != nil {
+		other = matchFields(self.Body.Set(), out)
+	}
+	return self.Query.decodeWith(out, &Config{}, other)
+}
+
+// Values of the winning source replace values of the same key.
+func (self Merged) mergeForms(body Form) Form {
+	win, lose := body, self.Query
+	if self.Prec == QueryWins {
+		win, lose = lose, win
+	}
+
+	out := make(Form, len(win)+len(lose))
+	for key, val := range lose {
+		out[key] = val
+	}
+	for key, val := range win {
+		out[key] = val
+	}
+	return out
+}
+
+// Implement `rd.Haserer`.
+func (self Merged) Haser() Haser { return self }
+
+// Implement `rd.Haser`. True if the key is present in either source.
+func (self Merged) Has(key string) bool {
+	return self.Query.Has(key) || (self.Body != nil && decHas(self.Body, key))
+}
+
+// Implement `rd.Setter`. Returns the union of the keys of both sources.
+func (self Merged) Set() Set {
+	var body Set
+	if self.Body != nil {
+		body = self.Body.Set()
+	}
+	return body.Union(self.Query.Set())
+}
+
+/*
+Downloads the request body like `rd.Download`, and returns `rd.Merged` which
+combines it with the URL query, using the given precedence. When the request
+has no body and no content type, `rd.Download` already uses the URL query, and
+this returns that decoder as-is.
+*/
+func DownloadMerged(req *http.Request, prec Precedence) (_ Dec, err error) {
+	defer rescue(&err)
+
+	if req == nil {
+		return decEmpty{}, nil
+	}
+
+	dec, err := Download(req)
+	if err != nil {
+		return nil, err
+	}
+	if ContentType(req) == `` {
+		return dec, nil
+	}
+	return Merged{Body: dec, Query: Form(reqQuery(req)), Prec: prec}, nil
+}
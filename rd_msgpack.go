@@ -0,0 +1,49 @@
+package rd
+
+import "net/http"
+
+/*
+Implements `rd.Decoder` for msgpack via the unmarshaling function registered for
+`rd.TypeMsgpack`; see `rd.Register`. Decoding uses the "json" field tag, just
+like the other decoders in this package, by going through an intermediary
+JSON-compatible representation. Supports arbitrary output types, not just
+structs.
+*/
+type Msgpack []byte
+
+/*
+Fully downloads the request body and stores it as-is, without any modification
+or validation. Used by `rd.Download`.
+*/
+func (self *Msgpack) Download(req *http.Request) error {
+	return (*Json)(self).Download(req)
+}
+
+// Clears the slice, preserving the capacity if any.
+func (self *Msgpack) Zero() { (*Json)(self).Zero() }
+
+/*
+Implement `rd.Decoder` by calling the unmarshaling function registered for
+`rd.TypeMsgpack`. Returns an error if there is none. The output must be a
+non-nil pointer to an arbitrary Go value.
+*/
+func (self Msgpack) Decode(out interface{}) error {
+	return unmarshalRegistered(TypeMsgpack, self, out)
+}
+
+// Implement `rd.Haserer` by calling `rd.Msgpack.Set`.
+func (self Msgpack) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Returns an instance of `rd.Set` with the string keys of
+the top-level map. Walks only the map headers and skips nested values by their
+encoded lengths, without decoding them. Uses a scanner bundled with this
+package, and doesn't require registering an unmarshaler. If the top-level value
+is not a map, the set is empty. Non-string keys are ignored. Assumes that the
+input is either valid msgpack or completely empty. Panics on malformed
+msgpack.
+
+Just like `rd.Json.Set`, this assumes that `rd.Msgpack` is immutable. Mutating
+the slice after calling this method will result in undefined behavior.
+*/
+func (self Msgpack) Set() Set { return parseMsgpackSet(bytesString(self)) }
@@ -3,38 +3,62 @@ package rd
 import (
 	"encoding"
 	"fmt"
+	"net"
+	"net/url"
 	r "reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 /*
 Missing feature of the standard library: parse arbitrary strings into arbitrary
 Go values. Used internally by `rd.Form.Decode`. Exported for enterprising
-users. Adapted from "github.com/mitranim/untext". The output must be a settable
-non-pointer. Its original value is ignored/overwritten. If the output
-implements `rd.SliceParser`, the corresponding method is invoked automatically.
-Otherwise it must be a slice of some concrete type, where each element is
-parsed via `rd.Parse`. Unlike "encoding/json", this doesn't support parsing
-into dynamically-typed `interface{}` values.
+users. Adapted from "github.com/mitranim/untext". The output must be settable.
+Its original value is ignored/overwritten. If the output is a pointer, possibly
+multi-level, nil inputs zero the pointer, and other inputs are parsed into the
+value it points to, allocating as needed. If the output implements
+`rd.SliceParser`, the corresponding method is invoked automatically. Otherwise
+it must be a slice or array of some concrete type, where each element is parsed
+via `rd.Parse`. Elements may also be pointers, possibly multi-level, which are
+allocated for each element. For arrays, the amount of inputs must match the
+array length, otherwise this produces an error; nil inputs zero the output,
+like for slices. Unlike "encoding/json", this doesn't support parsing into
+dynamically-typed `interface{}` values. Never panics; invalid outputs produce
+errors.
 */
-func ParseSlice(inputs []string, out r.Value) error {
+func ParseSlice(inputs []string, out r.Value) (err error) {
+	defer rescue(&err)
+
+	if out.Kind() == r.Ptr {
+		if inputs == nil {
+			out.Set(r.Zero(out.Type()))
+			return nil
+		}
+		out = derefAlloc(out)
+	}
+
 	impl, _ := out.Addr().Interface().(SliceParser)
 	if impl != nil {
 		return impl.ParseSlice(inputs)
 	}
-	return parseSlice(inputs, out)
+	return parseSlice(inputs, out, parseDefault)
 }
 
-func parseSlice(inputs []string, out r.Value) error {
+// The options apply to each element, see `parseWith`.
+func parseSlice(inputs []string, out r.Value, opts parseOpts) error {
 	if inputs == nil {
 		out.Set(r.Zero(out.Type()))
 		return nil
 	}
 
-	buf := r.MakeSlice(out.Type(), len(inputs), len(inputs))
+	buf, err := makeList(out.Type(), len(inputs))
+	if err != nil {
+		return err
+	}
 
 	for i, input := range inputs {
-		err := Parse(input, derefAlloc(buf.Index(i)))
+		err := parseWith(input, derefAlloc(buf.Index(i)), opts)
 		if err != nil {
 			return err
 		}
@@ -45,18 +69,174 @@ func parseSlice(inputs []string, out r.Value) error {
 	return nil
 }
 
+// Like `parseSlice`, but parses every element even after failures, collecting
+// all errors.
+func parseSliceAll(inputs []string, out r.Value, opts parseOpts) error {
+	buf, err := makeList(out.Type(), len(inputs))
+	if err != nil {
+		return err
+	}
+
+	var errs Errs
+	for i, input := range inputs {
+		err := parseWith(input, derefAlloc(buf.Index(i)), opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return errs.Err()
+	}
+
+	out.Set(buf)
+	return nil
+}
+
+/*
+Makes a slice of the given length, or a zero array, which must have the given
+length. Arrays are parsed into a copy, which is assigned only after all
+elements are parsed, like slices.
+*/
+func makeList(typ r.Type, size int) (r.Value, error) {
+	if typ.Kind() != r.Array {
+		return r.MakeSlice(typ, size, size), nil
+	}
+	if typ.Len() != size {
+		return r.Value{}, fmt.Errorf(`failed to parse %v values into %v: expected %v values`, size, typ, typ.Len())
+	}
+	return r.New(typ).Elem(), nil
+}
+
+/*
+Parses the first input into the output, like `parseWith`, and validates the
+other inputs by parsing them into throwaway values, collecting all errors.
+*/
+func parseAll(inputs []string, out r.Value, opts parseOpts) error {
+	var errs Errs
+	for i, input := range inputs {
+		tar := out
+		if i > 0 {
+			tar = r.New(out.Type()).Elem()
+		}
+
+		err := parseWith(input, tar, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.Err()
+}
+
 /*
 Missing feature of the standard library: parse arbitrary text into arbitrary Go
 value. Used internally by `rd.Form.Decode`. Exported for enterprising users.
 Adapted from "github.com/mitranim/untext". The output must be a settable
 non-pointer. Its original value is ignored/overwritten. If the output
 implements `rd.Parser` or `encoding.TextUnmarshaler`, the corresponding method
-is invoked automatically. Otherwise the output must be a "well-known" Go type:
-number, bool, string, or byte slice. Unlike "encoding/json", this doesn't
-support parsing into dynamically-typed `interface{}` values.
+is invoked automatically. Otherwise, if the output implements
+`encoding.BinaryUnmarshaler`, the input bytes are passed as-is, assuming that
+the input is already in the binary form, without any decoding such as base64.
+Otherwise the output must be a "well-known" Go type: number, bool, string, byte
+slice, `time.Duration`, `net.IPNet`, or `url.URL`, which is parsed via
+`url.Parse`, accepting relative references. IP addresses such as `net.IP`,
+`netip.Addr`, and `netip.Prefix` implement `encoding.TextUnmarshaler`, which
+takes priority over the byte slice fallback for `net.IP`, and so do `big.Int`
+and `big.Float`. `net.IPNet` is parsed via `net.ParseCIDR`. Numbers of all
+kinds, including unsigned integers, may have a leading "+". Integers are
+decimal; `rd.Form` supports other bases via the "rd" field tag, such as
+`rd:"base=0"` for Go-style prefixes such as "0xff". Durations are parsed via
+`time.ParseDuration`, such as "30s" or "1h15m"; purely numeric inputs are
+treated as integer nanoseconds. For `time.Time`, purely numeric inputs such as
+"1700000000" are treated as Unix timestamps in seconds, producing times in UTC,
+bypassing `encoding.TextUnmarshaler`, which expects RFC 3339; `rd.Form` supports
+other units via the "rd" field tag, such as `rd:"unix=ms"` for milliseconds.
+Unlike "encoding/json", this doesn't support parsing into dynamically-typed
+`interface{}` values. Never panics; invalid outputs produce errors.
+*/
+func Parse(input string, out r.Value) (err error) {
+	defer rescue(&err)
+	return parse(input, out)
+}
+
+func parse(input string, out r.Value) error { return parseWith(input, out, parseDefault) }
+
+/*
+Shortcut for `rd.Parse` without reflection boilerplate: parses the input into a
+new value of the given type and returns it. Unlike `rd.Parse`, the type may be a
+pointer, possibly multi-level, which is allocated. On error, returns the zero
+value. Example:
+
+	val, err := rd.ParseInto[time.Duration](`30s`)
+*/
+func ParseInto[T any](input string) (T, error) {
+	var val T
+	err := Parse(input, derefAlloc(r.ValueOf(&val).Elem()))
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return val, nil
+}
+
+/*
+Shortcut for `rd.ParseSlice` without reflection boilerplate: parses each input
+into an element of a new slice and returns it. Nil inputs produce a nil slice.
+Elements may be pointers, possibly multi-level. On error, returns nil. Example:
+
+	vals, err := rd.ParseSliceInto[int]([]string{`10`, `20`})
+*/
+func ParseSliceInto[T any](inputs []string) ([]T, error) {
+	var val []T
+	err := ParseSlice(inputs, r.ValueOf(&val).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+/*
+Options for `parseWith`, from the "rd" tag of a field. The zero value is not
+valid; see `parseDefault`.
 */
-func Parse(input string, out r.Value) error {
+type parseOpts struct {
+	// Integer base, as defined by `strconv.ParseInt`. Base 0 auto-detects
+	// Go-style prefixes such as "0x", "0o", "0b", and a leading "0" for octal.
+	// Other types ignore the base. See `rdTagBase`.
+	Base int
+
+	// Unit of Unix timestamps parsed into `time.Time`. See `rdTagUnix`.
+	Unix time.Duration
+
+	// Layout for `time.Parse`, used for `time.Time` when non-empty, from the
+	// "layout" tag.
+	Layout string
+}
+
+// Used by `rd.Parse` and for fields without options.
+var parseDefault = parseOpts{Base: 10, Unix: time.Second}
+
+// Like `parse`, but with the given options.
+func parseWith(input string, out r.Value, opts parseOpts) error {
 	ptr := out.Addr().Interface()
+	typ := out.Type()
+
+	// Takes priority over `encoding.TextUnmarshaler`. An explicit layout also
+	// takes priority over Unix timestamps, because it may be purely numeric, such
+	// as "20060102".
+	if typ == typeTime && opts.Layout != `` {
+		val, err := time.Parse(opts.Layout, input)
+		out.Set(r.ValueOf(val))
+		return errParse(err, input, typ)
+	}
+	if typ == typeTime && isDigits(input) {
+		return parseUnix(input, out, opts.Unix)
+	}
+
+	// `url.URL` implements `encoding.BinaryUnmarshaler` via `url.Parse`, but the
+	// binary form is an implementation detail.
+	if typ == typeUrl {
+		return parseUrl(input, out)
+	}
 
 	parser, _ := ptr.(Parser)
 	if parser != nil {
@@ -68,17 +248,31 @@ func Parse(input string, out r.Value) error {
 		return unmarshaler.UnmarshalText(stringToBytesUnsafe(input))
 	}
 
-	typ := out.Type()
+	// Assumes that the input is already in the binary form.
+	binary, _ := ptr.(encoding.BinaryUnmarshaler)
+	if binary != nil {
+		return binary.UnmarshalBinary(stringToBytesUnsafe(input))
+	}
+
+	if typ.AssignableTo(typeDuration) {
+		return parseDuration(input, out)
+	}
+
+	if typ == typeIPNet {
+		return parseIPNet(input, out)
+	}
+
 	kind := typ.Kind()
 
 	switch kind {
 	case r.Int8, r.Int16, r.Int32, r.Int64, r.Int:
-		val, err := strconv.ParseInt(input, 10, typeBits(typ))
+		val, err := strconv.ParseInt(input, opts.Base, typeBits(typ))
 		out.SetInt(val)
 		return errParse(err, input, typ)
 
 	case r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uint:
-		val, err := strconv.ParseUint(input, 10, typeBits(typ))
+		// Unlike `strconv.ParseInt`, this rejects a leading "+".
+		val, err := strconv.ParseUint(strings.TrimPrefix(input, `+`), opts.Base, typeBits(typ))
 		out.SetUint(val)
 		return errParse(err, input, typ)
 
@@ -94,6 +288,11 @@ func Parse(input string, out r.Value) error {
 		out.SetString(input)
 		return nil
 
+	// Unlike "encoding/json", text inputs have no type information. Supported by
+	// `rd.Json`, which uses "encoding/json" directly.
+	case r.Interface:
+		return fmt.Errorf(`failed to parse %q into %v: dynamically-typed interface values are not supported`, input, typ)
+
 	default:
 		if typ.ConvertibleTo(typeBytes) {
 			// Unavoidable copy?
@@ -105,6 +304,78 @@ func Parse(input string, out r.Value) error {
 	}
 }
 
+/*
+Used for `time.Time`. The result is in UTC, rather than in the local time zone
+used by `time.Unix`, which makes it independent from the environment.
+*/
+func parseUnix(input string, out r.Value, unit time.Duration) error {
+	val, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		return errParse(err, input, out.Type())
+	}
+
+	var tar time.Time
+	switch unit {
+	case time.Millisecond:
+		tar = time.UnixMilli(val)
+	case time.Microsecond:
+		tar = time.UnixMicro(val)
+	case time.Nanosecond:
+		tar = time.Unix(0, val)
+	default:
+		tar = time.Unix(val, 0)
+	}
+
+	out.Set(r.ValueOf(tar.UTC()))
+	return nil
+}
+
+func isDigits(src string) bool {
+	for i := 0; i < len(src); i++ {
+		if !digits.has(src[i]) {
+			return false
+		}
+	}
+	return src != ``
+}
+
+// Note: `time.ParseDuration` rejects unitless numbers other than "0".
+func parseDuration(input string, out r.Value) error {
+	val, err := strconv.ParseInt(input, 10, 64)
+	if err == nil {
+		out.SetInt(val)
+		return nil
+	}
+
+	dur, err := time.ParseDuration(input)
+	out.SetInt(int64(dur))
+	return errParse(err, input, out.Type())
+}
+
+/*
+Used for `net.IPNet`, which, unlike `net.IP`, doesn't implement
+`encoding.TextUnmarshaler`. Like `net.ParseCIDR`, the IP is masked to the
+network, such as "10.0.0.0/8" for "10.0.0.1/8"; use `netip.Prefix` to keep the
+host address.
+*/
+func parseIPNet(input string, out r.Value) error {
+	_, val, err := net.ParseCIDR(input)
+	if err != nil {
+		return errParse(err, input, out.Type())
+	}
+	out.Set(r.ValueOf(*val))
+	return nil
+}
+
+func parseUrl(input string, out r.Value) error {
+	val, err := url.Parse(input)
+	if err != nil {
+		return errParse(err, input, out.Type())
+	}
+	out.Set(r.ValueOf(*val))
+	return nil
+}
+
 // Note: `strconv.ParseBool` is too permissive for our taste.
 func parseBool(input string, out r.Value) error {
 	switch input {
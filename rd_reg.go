@@ -0,0 +1,177 @@
+package rd
+
+import (
+	"fmt"
+	"net/http"
+	r "reflect"
+	"sync"
+)
+
+/*
+Signature of unmarshaling functions such as `json.Unmarshal`. Used for formats
+whose parsers are not bundled with this package. See `rd.Register`.
+*/
+type Unmarshal func([]byte, interface{}) error
+
+var unmarshalReg sync.Map
+
+/*
+Registers an unmarshaling function for the given media type, enabling support
+for that type in `rd.Decode` and `rd.Download`. This allows to support formats
+such as TOML without forcing a dependency on every user of this package.
+Example:
+
+	import "github.com/BurntSushi/toml"
+
+	func init() { rd.Register(rd.TypeToml, toml.Unmarshal) }
+
+Supported media types:
+
+	* `rd.TypeToml`                       -> `rd.Toml`
+	* `rd.TypeYaml`, `rd.TypeYamlText`    -> `rd.Yaml`
+	* `rd.TypeMsgpack`, `rd.TypeMsgpackX` -> `rd.Msgpack`
+
+Media types which are aliases of each other, such as `rd.TypeYaml` and
+`rd.TypeYamlText`, share the registration. Panics for other media types,
+because this package wouldn't know which decoder type to use for them.
+Registering nil removes the registration. Should be called during
+initialization; safe for concurrent use regardless.
+*/
+func Register(typ string, fun Unmarshal) {
+	key := regType(typ)
+	if key == `` {
+		panic(errInternal(fmt.Errorf(`unable to register unmarshaler for unsupported content type %q`, typ)))
+	}
+
+	if fun == nil {
+		unmarshalReg.Delete(key)
+	} else {
+		unmarshalReg.Store(key, fun)
+	}
+}
+
+// Returns the unmarshaling function registered via `rd.Register`, if any.
+func Registered(typ string) Unmarshal {
+	val, _ := unmarshalReg.Load(regType(typ))
+	fun, _ := val.(Unmarshal)
+	return fun
+}
+
+// Like `rd.Registered`, but for YAML, falls back on `rd.YamlUnmarshal`.
+func loadUnmarshal(typ string) Unmarshal {
+	fun := Registered(typ)
+	if fun == nil && regType(typ) == TypeYaml && YamlUnmarshal != nil {
+		return YamlUnmarshal
+	}
+	return fun
+}
+
+// Returns the canonical media type used as the registry key.
+func regType(typ string) string {
+	switch typ {
+	case TypeToml:
+		return TypeToml
+	case TypeYaml, TypeYamlText:
+		return TypeYaml
+	case TypeMsgpack, TypeMsgpackX:
+		return TypeMsgpack
+	default:
+		return ``
+	}
+}
+
+func downloadRegistered(req *http.Request, typ string) (Dec, error) {
+	if loadUnmarshal(typ) == nil {
+		return nil, errContentType(typ)
+	}
+
+	switch regType(typ) {
+	case TypeToml:
+		var dec Toml
+		err := dec.Download(req)
+		return dec, err
+
+	case TypeYaml:
+		var dec Yaml
+		err := dec.Download(req)
+		return dec, err
+
+	case TypeMsgpack:
+		var dec Msgpack
+		err := dec.Download(req)
+		return dec, err
+
+	default:
+		return nil, errUnreachable
+	}
+}
+
+func unmarshalRegistered(typ string, src []byte, out interface{}) error {
+	fun := loadUnmarshal(typ)
+	if fun == nil {
+		return errInternal(fmt.Errorf(`missing unmarshaler for content type %q, see rd.Register`, typ))
+	}
+	return errBadReq(unmarshalJsonCompat(fun, src, out))
+}
+
+var postDecodeReg sync.Map
+
+/*
+Registers a function which `rd.Decode` and `rd.DecodeWith` call on the output
+after decoding it successfully, when the output is a value of the given type
+or a pointer to it. Useful for transforms of the whole decoded value, such as
+normalizing fields or computing derived values, including for types defined
+in other packages, which can't implement interfaces. Example:
+
+	rd.RegisterPostDecode(reflect.TypeOf(Input{}), func(val reflect.Value) error {
+		input := val.Addr().Interface().(*Input)
+		input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+		return nil
+	})
+
+The function receives the addressable dereferenced output, regardless of the
+content type. It's not called when decoding fails, or when decoding is skipped
+for a nil request or output, and is not used by decoder types such as
+`rd.Form`. Errors without an HTTP status are treated as bad requests, with
+HTTP status 400. Panics are converted to errors, like in `rd.Decode`.
+
+Ordering relative to `rd.Validate`: validation examines only the request, and
+is meant to be called before decoding, and therefore always runs before any
+post-decode function. Post-decode functions see only the decoded output, and
+run once per call of `rd.Decode` or `rd.DecodeWith`, after all fields have
+been decoded.
+
+Registering nil removes the registration. Should be called during
+initialization; safe for concurrent use regardless.
+*/
+func RegisterPostDecode(typ r.Type, fun func(r.Value) error) {
+	if typ == nil {
+		panic(errInternal(fmt.Errorf(`unable to register post-decode function for nil type`)))
+	}
+
+	if fun == nil {
+		postDecodeReg.Delete(typ)
+	} else {
+		postDecodeReg.Store(typ, fun)
+	}
+}
+
+func postDecode(out interface{}) error {
+	val := r.ValueOf(out)
+	for val.Kind() == r.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	fun, _ := postDecodeReg.Load(val.Type())
+	if fun == nil {
+		return nil
+	}
+
+	if !val.CanAddr() {
+		return errInternal(fmt.Errorf(`unable to apply post-decode function to non-pointer output of type %v`, val.Type()))
+	}
+	return errBadReq(fun.(func(r.Value) error)(val))
+}
@@ -0,0 +1,48 @@
+package rd
+
+import "net/http"
+
+/*
+Implements `rd.Decoder` for TOML via the unmarshaling function registered for
+`rd.TypeToml`; see `rd.Register`. Decoding uses the "json" field tag, just like
+the other decoders in this package, by going through an intermediary
+JSON-compatible representation. Supports arbitrary output types, not just
+structs.
+*/
+type Toml []byte
+
+/*
+Fully downloads the request body and stores it as-is, without any modification
+or validation. Used by `rd.Download`.
+*/
+func (self *Toml) Download(req *http.Request) error {
+	return (*Json)(self).Download(req)
+}
+
+// Clears the slice, preserving the capacity if any.
+func (self *Toml) Zero() { (*Json)(self).Zero() }
+
+/*
+Implement `rd.Decoder` by calling the unmarshaling function registered for
+`rd.TypeToml`. Returns an error if there is none. The output must be a non-nil
+pointer to an arbitrary Go value.
+*/
+func (self Toml) Decode(out interface{}) error {
+	return unmarshalRegistered(TypeToml, self, out)
+}
+
+// Implement `rd.Haserer` by calling `rd.Toml.Set`.
+func (self Toml) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Returns an instance of `rd.Set` with the top-level keys
+of the TOML document: keys of top-level key-value pairs, including the first
+segments of dotted keys, and the first segments of table headers. Uses a
+custom parser bundled with this package, and doesn't require registering an
+unmarshaler. Assumes that TOML is either valid or completely empty. Panics on
+malformed TOML.
+
+Just like `rd.Json.Set`, this assumes that `rd.Toml` is immutable. Mutating the
+TOML slice after calling this method will result in undefined behavior.
+*/
+func (self Toml) Set() Set { return parseTomlSet(bytesString(self)) }
@@ -0,0 +1,52 @@
+package rd
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+/*
+Implements `rd.Decoder` via `xml.Unmarshal`. Unlike other decoders in this
+package, decoding uses the "xml" field tag rather than "json", because the
+output is decoded by "encoding/xml" directly; see that package for the rules.
+The root element corresponds to the output itself. Supports arbitrary output
+types supported by "encoding/xml", not just structs.
+*/
+type Xml []byte
+
+/*
+Fully downloads the request body and stores it as-is, like
+`rd.Json.Download`. Used by `rd.Download`.
+*/
+func (self *Xml) Download(req *http.Request) error {
+	return (*Json)(self).Download(req)
+}
+
+// Downloads the request body, like `rd.Json.DownloadLimited`.
+func (self *Xml) DownloadLimited(req *http.Request, limit int64) error {
+	return (*Json)(self).DownloadLimited(req, limit)
+}
+
+// Clears the slice, preserving the capacity if any.
+func (self *Xml) Zero() { (*Json)(self).Zero() }
+
+/*
+Implement `rd.Decoder` by calling `xml.Unmarshal`. The output must be a non-nil
+pointer to an arbitrary Go value. Malformed XML produces an error with HTTP
+status 400.
+*/
+func (self Xml) Decode(out interface{}) error {
+	return errBadReq(xml.Unmarshal(self, out))
+}
+
+// Implement `rd.Haserer` by calling `rd.Xml.Set`.
+func (self Xml) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Returns an instance of `rd.Set` with the local names of
+the child elements of the root element, which correspond to the top-level
+fields of the output, ignoring namespaces and attributes. Assumes that XML is
+either valid or completely empty. Panics on malformed XML, with an error with
+HTTP status 400.
+*/
+func (self Xml) Set() Set { return parseXmlSet(self) }
@@ -0,0 +1,72 @@
+package rd
+
+import "net/http"
+
+/*
+Optional YAML unmarshaling function, used by `rd.Yaml`, `rd.Decode`, and
+`rd.Download` when no function is registered for `rd.TypeYaml` via
+`rd.Register`, which takes priority. Nil by default, which means YAML is
+unsupported. Setting this variable keeps this package dependency-free, just
+like registering. Should be set during initialization; unlike `rd.Register`,
+this is not safe for concurrent use. Example:
+
+	import "gopkg.in/yaml.v3"
+
+	func init() { rd.YamlUnmarshal = yaml.Unmarshal }
+*/
+var YamlUnmarshal func([]byte, interface{}) error
+
+/*
+Implements `rd.Decoder` for YAML via the unmarshaling function registered for
+`rd.TypeYaml`, or otherwise `rd.YamlUnmarshal`; see `rd.Register`. Decoding
+uses the "json" field tag, just like the other decoders in this package, by
+going through an intermediary JSON-compatible representation. This works with
+YAML libraries which decode mappings either as `map[string]interface{}` or as
+`map[interface{}]interface{}`. Supports arbitrary output types, not just
+structs. The YAML library is injected by registering its unmarshaling function
+or by setting `rd.YamlUnmarshal`, which keeps this package dependency-free.
+Example:
+
+	import "gopkg.in/yaml.v3"
+
+	func init() { rd.Register(rd.TypeYaml, yaml.Unmarshal) }
+*/
+type Yaml []byte
+
+/*
+Fully downloads the request body and stores it as-is, without any modification
+or validation. Used by `rd.Download`.
+*/
+func (self *Yaml) Download(req *http.Request) error {
+	return (*Json)(self).Download(req)
+}
+
+// Clears the slice, preserving the capacity if any.
+func (self *Yaml) Zero() { (*Json)(self).Zero() }
+
+/*
+Implement `rd.Decoder` by calling the unmarshaling function registered for
+`rd.TypeYaml`, or otherwise `rd.YamlUnmarshal`. Returns an error if there is
+none. The output must be a non-nil
+pointer to an arbitrary Go value.
+*/
+func (self Yaml) Decode(out interface{}) error {
+	return unmarshalRegistered(TypeYaml, self, out)
+}
+
+// Implement `rd.Haserer` by calling `rd.Yaml.Set`.
+func (self Yaml) Haser() Haser { return self.Set() }
+
+/*
+Implement `rd.Setter`. Returns an instance of `rd.Set` with the keys of the
+top-level block mapping in the first YAML document. Uses a simple line-based
+scanner bundled with this package, and doesn't require registering an
+unmarshaler. Keys are recognized only when they start at the beginning of a
+line, which is always the case for top-level block mappings. Top-level flow
+mappings such as `{one: two}` and complex keys introduced by `?` are not
+supported, and produce no keys. Never panics.
+
+Just like `rd.Json.Set`, this assumes that `rd.Yaml` is immutable. Mutating the
+YAML slice after calling this method will result in undefined behavior.
+*/
+func (self Yaml) Set() Set { return parseYamlSet(bytesString(self)) }
@@ -3,6 +3,8 @@ package rd_test
 import (
 	"encoding/json"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/mitranim/rd"
@@ -40,6 +42,75 @@ func BenchmarkQuery_Parse_Decode(b *testing.B) {
 	}
 }
 
+func BenchmarkHas_json(b *testing.B) {
+	for range iter(b.N) {
+		_, err := rd.Has(Req{}.Post().BodyJson(testOuterSimpleJson).Ptr(), `outerStr`)
+		try(err)
+	}
+}
+
+func BenchmarkJson_DecodeWith_Strict(b *testing.B) {
+	dec := rd.Json(testOuterSimpleJson)
+	conf := rd.Config{Strict: true}
+	var tar Outer
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		try(dec.DecodeWith(&tar, conf))
+	}
+}
+
+func BenchmarkForm_Set_Has(b *testing.B) {
+	src := benchFormLarge()
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		set := src.Set()
+		boolNop(set.Has(`key_10`) && set.Has(`key_20`))
+	}
+}
+
+func BenchmarkForm_HasAll(b *testing.B) {
+	src := benchFormLarge()
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		boolNop(src.HasAll(`key_10`, `key_20`))
+	}
+}
+
+func benchFormLarge() rd.Form {
+	out := make(rd.Form, 256)
+	for i := range iter(256) {
+		out[`key_`+strconv.Itoa(i)] = []string{strconv.Itoa(i)}
+	}
+	return out
+}
+
+var boolNop = func(bool) {}
+
+func BenchmarkForm_large_ParseQuery_Decode(b *testing.B) {
+	src := url.Values(benchFormLarge()).Encode()
+	var tar Outer
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		vals, err := url.ParseQuery(src)
+		try(err)
+		try(rd.Form(vals).Decode(&tar))
+	}
+}
+
+func BenchmarkForm_large_DecodeFormReader(b *testing.B) {
+	src := url.Values(benchFormLarge()).Encode()
+	var tar Outer
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		try(rd.DecodeFormReader(strings.NewReader(src), &tar, rd.Config{}))
+	}
+}
+
 func BenchmarkSet_construct(b *testing.B) {
 	for range iter(b.N) {
 		haserNop(set(testSetKeys...))
@@ -189,3 +260,65 @@ const jsonSrcMixed = `{
 	"a79857": {"b762d0": [-12e+34],  "5850fe": [-12e+34]},
 	"7b19ef": {"d50fff": ["47975f"], "e833b5": ["47975f"]}
 }`
+
+func Benchmark_json_parse_huge_stdlib(b *testing.B) {
+	src := []byte(jsonSrcHuge)
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		parseSetWithStdlib(src)
+	}
+}
+
+func Benchmark_json_parse_huge_ours(b *testing.B) {
+	dec := rd.Json(jsonSrcHuge)
+	eq(b, jsonSrcHugeLen, len(dec.Set()))
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		dec.Haser()
+	}
+}
+
+func Benchmark_json_parse_huge_reader(b *testing.B) {
+	var src strings.Reader
+
+	for range iter(b.N) {
+		src.Reset(jsonSrcHuge)
+		_, err := rd.ParseSetReader(&src)
+		try(err)
+	}
+}
+
+// Object dominated by a large value, which must not inflate the set.
+func Benchmark_json_parse_large_value_ours(b *testing.B) {
+	dec := rd.Json(jsonSrcLargeValue)
+	b.ResetTimer()
+
+	for range iter(b.N) {
+		dec.Haser()
+	}
+}
+
+var jsonSrcLargeValue = `{"one": "` + strings.Repeat(`a`, 1<<20) + `", "two": null}`
+
+const jsonSrcHugeLen = 4096
+
+// Object with many top-level keys, with values of mixed types.
+var jsonSrcHuge = func() string {
+	vals := []string{`null`, `true`, `-12.34`, `"47975f"`, `[12, "47975f"]`, `{"fc087b": null}`}
+
+	var buf strings.Builder
+	buf.WriteString(`{`)
+	for i := range iter(jsonSrcHugeLen) {
+		if i > 0 {
+			buf.WriteString(`,`)
+		}
+		buf.WriteString("\n\t\"key_")
+		buf.WriteString(strconv.Itoa(i))
+		buf.WriteString(`": `)
+		buf.WriteString(vals[i%len(vals)])
+	}
+	buf.WriteString("\n}")
+	return buf.String()
+}()
@@ -2,13 +2,22 @@ package rd_test
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	r "reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,6 +30,7 @@ var (
 	typeString     = r.TypeOf((*string)(nil)).Elem()
 	typeBytes      = r.TypeOf((*[]byte)(nil)).Elem()
 	typeTime       = r.TypeOf((*time.Time)(nil)).Elem()
+	typeDuration   = r.TypeOf((*time.Duration)(nil)).Elem()
 	typeTimeParser = r.TypeOf((*TimeParser)(nil)).Elem()
 )
 
@@ -152,6 +162,12 @@ type TarPair struct {
 	Two []int `json:"two"`
 }
 
+type TarDynamic struct {
+	Any interface{}     `json:"any"`
+	Raw json.RawMessage `json:"raw"`
+	Str string          `json:"str"`
+}
+
 type TarInt struct {
 	Val int `json:"val"`
 }
@@ -170,6 +186,12 @@ type TarSlicePtrInt struct {
 
 func ptrInt(val int) *int { return &val }
 
+func ptrUint8(val uint8) *uint8 { return &val }
+
+func ptrBool(val bool) *bool { return &val }
+
+func ptrFloat64(val float64) *float64 { return &val }
+
 var testNums = []int{0, 1, 2, 3, 4, 8, 16, 32}
 
 var nopQueries = []url.Values{
@@ -237,6 +259,13 @@ func try(err error) {
 	}
 }
 
+func tryVal[A any](val A, err error) A {
+	try(err)
+	return val
+}
+
+func tryErr[A any](_ A, err error) error { return err }
+
 type Req http.Request
 
 func (self Req) Init() Req {
@@ -309,12 +338,61 @@ func (self Req) BodyMulti(src url.Values) Req {
 	return self.Type(typ).BodyReader(reader)
 }
 
+func (self Req) Encoding(val string) Req {
+	self = self.Init()
+	self.Header.Set(`Content-Encoding`, val)
+	return self
+}
+
 func (self Req) Type(val string) Req {
 	self = self.Init()
 	self.Header.Set(rd.Type, val)
 	return self
 }
 
+func gzipString(src string) string {
+	var buf bytes.Buffer
+	wri := gzip.NewWriter(&buf)
+	_, err := wri.Write([]byte(src))
+	try(err)
+	try(wri.Close())
+	return buf.String()
+}
+
+func zlibString(src string) string {
+	var buf bytes.Buffer
+	wri := zlib.NewWriter(&buf)
+	_, err := wri.Write([]byte(src))
+	try(err)
+	try(wri.Close())
+	return buf.String()
+}
+
+func flateString(src string) string {
+	var buf bytes.Buffer
+	wri, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	try(err)
+	_, err = wri.Write([]byte(src))
+	try(err)
+	try(wri.Close())
+	return buf.String()
+}
+
+// Multipart body with one text field and one file.
+func multipartWithFile(field, val, file, content string) (string, io.Reader) {
+	var buf bytes.Buffer
+	wri := multipart.NewWriter(&buf)
+	try(wri.WriteField(field, val))
+
+	part, err := wri.CreateFormFile(file, file+`.txt`)
+	try(err)
+	_, err = io.WriteString(part, content)
+	try(err)
+	try(wri.Close())
+
+	return wri.FormDataContentType(), &buf
+}
+
 func queryToMultipart(src url.Values) (string, io.Reader) {
 	var buf bytes.Buffer
 	wri := multipart.NewWriter(&buf)
@@ -378,3 +456,439 @@ func (self *SliceParserStruct) ParseSlice(vals []string) error {
 	self.Inner = out
 	return nil
 }
+
+type HaserFunc func(string) bool
+
+func (self HaserFunc) Has(val string) bool { return self(val) }
+
+/*
+Minimal TOML unmarshaler for testing `rd.Register` and `rd.Toml`. Supports only
+single-level tables and values which are also valid JSON.
+*/
+func tomlUnmarshal(src []byte, out interface{}) error {
+	top := map[string]interface{}{}
+	tab := top
+
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if line == `` || strings.HasPrefix(line, `#`) {
+			continue
+		}
+
+		if strings.HasPrefix(line, `[`) {
+			tab = map[string]interface{}{}
+			top[strings.Trim(line, `[]`)] = tab
+			continue
+		}
+
+		index := strings.Index(line, `=`)
+		if index < 0 {
+			return fmt.Errorf(`invalid TOML line %q`, line)
+		}
+
+		var val interface{}
+		err := json.Unmarshal([]byte(line[index+1:]), &val)
+		if err != nil {
+			return err
+		}
+		tab[strings.TrimSpace(line[:index])] = val
+	}
+
+	*out.(*interface{}) = top
+	return nil
+}
+
+const testOuterToml = `
+# Comment.
+embedStr = "embed val"
+embedNum = 10
+outerStr = "outer val"
+
+[inner]
+innerStr = "inner val"
+innerNum = 20
+`
+
+func withRegistered(typ string, fun rd.Unmarshal) func() {
+	prev := rd.Registered(typ)
+	rd.Register(typ, fun)
+	return func() { rd.Register(typ, prev) }
+}
+
+func panics(t testing.TB, msg string, fun func()) {
+	t.Helper()
+	val := catchAny(fun)
+
+	if val == nil {
+		t.Fatalf(`expected a panic with %q, got none`, msg)
+	}
+
+	str := fmt.Sprint(val)
+	if !strings.Contains(str, msg) {
+		t.Fatalf(`expected a panic with a message containing %q, got %q`, msg, str)
+	}
+}
+
+func catchAny(fun func()) (val interface{}) {
+	defer func() { val = recover() }()
+	fun()
+	return
+}
+
+/*
+Minimal YAML unmarshaler for testing `rd.Yaml`. Supports only mappings nested
+up to one level, with scalar values which are also valid JSON. Produces maps
+with interface keys, like some YAML libraries do.
+*/
+func yamlUnmarshal(src []byte, out interface{}) error {
+	top := map[interface{}]interface{}{}
+	var tab map[interface{}]interface{}
+
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.TrimSpace(line) == `` {
+			continue
+		}
+
+		index := strings.Index(line, `:`)
+		if index < 0 {
+			return fmt.Errorf(`invalid YAML line %q`, line)
+		}
+
+		key := strings.TrimSpace(line[:index])
+		rest := strings.TrimSpace(line[index+1:])
+		nested := strings.HasPrefix(line, ` `)
+
+		if !nested && rest == `` {
+			tab = map[interface{}]interface{}{}
+			top[key] = tab
+			continue
+		}
+
+		var val interface{}
+		err := json.Unmarshal([]byte(rest), &val)
+		if err != nil {
+			return err
+		}
+
+		if nested {
+			tab[key] = val
+		} else {
+			top[key] = val
+		}
+	}
+
+	*out.(*interface{}) = top
+	return nil
+}
+
+const testOuterYaml = `
+embedStr: "embed val"
+embedNum: 10
+inner:
+  innerStr: "inner val"
+  innerNum: 20
+outerStr: "outer val"
+`
+
+// Recursive type for verifying that nested fields are walked without infinite
+// recursion.
+// Array which implements `rd.Parser`, for verifying that such arrays are
+// parsed from single values.
+type TarArrayParser [2]byte
+
+func (self *TarArrayParser) Parse(src string) error {
+	copy(self[:], src)
+	return nil
+}
+
+type TarNode struct {
+	Val  string   `json:"val"`
+	Next *TarNode `json:"next"`
+}
+
+// Combines year, month, and day into `time.Time`, for testing
+// `rd.RegisterCombiner`. All empty means nil.
+func combineDate(src []string) (interface{}, error) {
+	if strings.Join(src, ``) == `` {
+		return nil, nil
+	}
+	return time.Parse(`2006-1-2`, strings.Join(src, `-`))
+}
+
+// Used for verifying that panics in user-defined methods are converted to
+// errors.
+type PanicParser struct{}
+
+func (*PanicParser) Parse(src string) error { panic(src) }
+
+type PanicSliceParser struct{}
+
+func (*PanicSliceParser) ParseSlice(src []string) error { panic(fmt.Errorf(`%v`, src)) }
+
+// Used for verifying that errors in user-defined methods mention the field.
+var errEmail = errors.New(`invalid email`)
+
+type EmailParser string
+
+func (self *EmailParser) Parse(src string) error {
+	if !strings.Contains(src, `@`) {
+		return errEmail
+	}
+	*self = EmailParser(src)
+	return nil
+}
+
+type EmailSliceParser []EmailParser
+
+func (self *EmailSliceParser) ParseSlice(src []string) error {
+	for _, val := range src {
+		var email EmailParser
+		err := email.Parse(val)
+		if err != nil {
+			return err
+		}
+		*self = append(*self, email)
+	}
+	return nil
+}
+
+func errStatus(t testing.TB, exp int, err error) {
+	t.Helper()
+
+	var tar rd.Err
+	if !errors.As(err, &tar) {
+		t.Fatalf(`expected an error of type rd.Err, got %#v`, err)
+	}
+	eq(t, exp, tar.Status)
+}
+
+type JsonPanic struct{}
+
+func (*JsonPanic) UnmarshalJSON(src []byte) error { panic(string(src)) }
+
+// Ordered map of key-value pairs for `msgpackEncode`.
+type msgpackMap [][2]interface{}
+
+/*
+Minimal msgpack encoder for testing `rd.Msgpack`. Supports only the types used
+in tests, always choosing the shortest encoding.
+*/
+func msgpackEncode(src interface{}) []byte {
+	var buf []byte
+	msgpackAppend(&buf, src)
+	return buf
+}
+
+func msgpackAppend(buf *[]byte, src interface{}) {
+	switch src := src.(type) {
+	case nil:
+		*buf = append(*buf, 0xc0)
+
+	case bool:
+		if src {
+			*buf = append(*buf, 0xc3)
+		} else {
+			*buf = append(*buf, 0xc2)
+		}
+
+	case int:
+		if src >= 0 && src <= 0x7f || src < 0 && src >= -32 {
+			*buf = append(*buf, byte(src))
+		} else {
+			*buf = append(*buf, 0xd3)
+			*buf = msgpackUint(*buf, uint64(src), 8)
+		}
+
+	case float64:
+		*buf = append(*buf, 0xcb)
+		*buf = msgpackUint(*buf, math.Float64bits(src), 8)
+
+	case string:
+		msgpackHead(buf, len(src), 0xa0, 31, 0xd9, 0xda)
+		*buf = append(*buf, src...)
+
+	case []byte:
+		msgpackHead(buf, len(src), 0, -1, 0xc4, 0xc5)
+		*buf = append(*buf, src...)
+
+	case []interface{}:
+		msgpackHead(buf, len(src), 0x90, 15, 0, 0xdc)
+		for _, val := range src {
+			msgpackAppend(buf, val)
+		}
+
+	case msgpackMap:
+		msgpackHead(buf, len(src), 0x80, 15, 0, 0xde)
+		for _, pair := range src {
+			msgpackAppend(buf, pair[0])
+			msgpackAppend(buf, pair[1])
+		}
+
+	default:
+		panic(fmt.Errorf(`unsupported msgpack input %#v`, src))
+	}
+}
+
+func msgpackHead(buf *[]byte, size int, fix byte, fixMax int, head8, head16 byte) {
+	switch {
+	case size <= fixMax:
+		*buf = append(*buf, fix|byte(size))
+	case size <= 0xff && head8 != 0:
+		*buf = append(*buf, head8, byte(size))
+	default:
+		*buf = append(*buf, head16)
+		*buf = msgpackUint(*buf, uint64(size), 2)
+	}
+}
+
+// Appends a big-endian unsigned integer of the given byte width.
+func msgpackUint(buf []byte, val uint64, width int) []byte {
+	for i := width - 1; i >= 0; i-- {
+		buf = append(buf, byte(val>>(8*i)))
+	}
+	return buf
+}
+
+/*
+Minimal msgpack unmarshaler for testing `rd.Register` and `rd.Msgpack`. Supports
+only the output of `msgpackEncode`.
+*/
+func msgpackUnmarshal(src []byte, out interface{}) (err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			err = fmt.Errorf(`invalid msgpack: %v`, val)
+		}
+	}()
+
+	val, rest := msgpackDecode(src)
+	if len(rest) > 0 {
+		return fmt.Errorf(`invalid msgpack: unexpected trailing data`)
+	}
+	*out.(*interface{}) = val
+	return nil
+}
+
+func msgpackDecode(src []byte) (interface{}, []byte) {
+	char, src := src[0], src[1:]
+
+	switch {
+	case char <= 0x7f:
+		return int64(char), src
+	case char >= 0xe0:
+		return int64(int8(char)), src
+	case char >= 0x80 && char <= 0x8f:
+		return msgpackDecodeMap(src, int(char&0x0f))
+	case char >= 0x90 && char <= 0x9f:
+		return msgpackDecodeArr(src, int(char&0x0f))
+	case char >= 0xa0 && char <= 0xbf:
+		size := int(char & 0x1f)
+		return string(src[:size]), src[size:]
+	}
+
+	switch char {
+	case 0xc0:
+		return nil, src
+	case 0xc2:
+		return false, src
+	case 0xc3:
+		return true, src
+	case 0xc4:
+		size := int(src[0])
+		return src[1 : 1+size], src[1+size:]
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(src)), src[8:]
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(src)), src[8:]
+	case 0xd9:
+		size := int(src[0])
+		return string(src[1 : 1+size]), src[1+size:]
+	case 0xda:
+		size := int(binary.BigEndian.Uint16(src))
+		return string(src[2 : 2+size]), src[2+size:]
+	case 0xdc:
+		return msgpackDecodeArr(src[2:], int(binary.BigEndian.Uint16(src)))
+	case 0xde:
+		return msgpackDecodeMap(src[2:], int(binary.BigEndian.Uint16(src)))
+	default:
+		panic(fmt.Errorf(`unsupported msgpack byte 0x%02x`, char))
+	}
+}
+
+func msgpackDecodeArr(src []byte, size int) (interface{}, []byte) {
+	out := make([]interface{}, size)
+	for i := range out {
+		out[i], src = msgpackDecode(src)
+	}
+	return out, src
+}
+
+func msgpackDecodeMap(src []byte, size int) (interface{}, []byte) {
+	out := make(map[string]interface{}, size)
+	for ; size > 0; size-- {
+		var key, val interface{}
+		key, src = msgpackDecode(src)
+		val, src = msgpackDecode(src)
+		out[fmt.Sprint(key)] = val
+	}
+	return out, src
+}
+
+var testOuterMsgpack = msgpackEncode(msgpackMap{
+	{`embedStr`, `embed val`},
+	{`embedNum`, 10},
+	{`inner`, msgpackMap{
+		{`innerStr`, `inner val`},
+		{`innerNum`, 20},
+	}},
+	{`outerStr`, `outer val`},
+})
+
+// Yields the prefix, then blocks until closed, like a stalled connection.
+type StallBody struct {
+	src  io.Reader
+	done chan struct{}
+	once sync.Once
+}
+
+func newStallBody(prefix string) *StallBody {
+	return &StallBody{src: strings.NewReader(prefix), done: make(chan struct{})}
+}
+
+func (self *StallBody) Read(buf []byte) (int, error) {
+	size, _ := self.src.Read(buf)
+	if size > 0 {
+		return size, nil
+	}
+	<-self.done
+	return 0, errors.New(`read on closed body`)
+}
+
+func (self *StallBody) Close() error {
+	self.once.Do(func() { close(self.done) })
+	return nil
+}
+
+func (self *StallBody) Closed() bool {
+	select {
+	case <-self.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Never yields any data nor any error, like a broken reader.
+type EmptyReader struct{}
+
+func (EmptyReader) Read([]byte) (int, error) { return 0, nil }
+
+// Implements only `encoding.BinaryUnmarshaler`.
+type BinaryKey [4]byte
+
+func (self *BinaryKey) UnmarshalBinary(src []byte) error {
+	if len(src) != len(self) {
+		return fmt.Errorf(`expected %v bytes, got %v`, len(self), len(src))
+	}
+	copy(self[:], src)
+	return nil
+}
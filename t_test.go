@@ -1,10 +1,24 @@
 package rd_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
 	"net/url"
+	"os"
 	r "reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/mitranim/rd"
@@ -40,6 +54,29 @@ func TestParse_num(t *testing.T) {
 	}
 }
 
+func TestParse_num_plus(t *testing.T) {
+	for _, typ := range numTypes {
+		eq(t, 10, parseNew(`+10`, typ).Convert(typeInt).Interface())
+		eq(t, 0, parseNew(`+0`, typ).Convert(typeInt).Interface())
+
+		for _, src := range []string{`+`, `++10`, `+-10`, `-+10`, ` +10`} {
+			errs(t, fmt.Sprintf(`failed to parse %q into %v`, src, typ), rd.Parse(src, r.New(typ).Elem()))
+		}
+	}
+
+	eq(t, time.Duration(10), parseNew(`+10`, typeDuration).Interface())
+	eq(t, time.Second, parseNew(`+1s`, typeDuration).Interface())
+
+	type Tar struct {
+		Uint uint64 `json:"uint" rd:"base=0"`
+		Int  int    `json:"int"  rd:"base=0"`
+	}
+
+	var tar Tar
+	try(rd.Form{`uint`: {`+0x10`}, `int`: {`+0b11`}}.Decode(&tar))
+	eq(t, Tar{Uint: 16, Int: 3}, tar)
+}
+
 func TestParse_bool(t *testing.T) {
 	testOk := func(exp bool, src string) {
 		t.Helper()
@@ -96,6 +133,47 @@ func TestParse_bytes(t *testing.T) {
 	test(`f0c1ea163f6f4d839b74889438dcb1d5`)
 }
 
+func TestParse_duration(t *testing.T) {
+	test := func(exp time.Duration, src string) {
+		t.Helper()
+		eq(t, exp, parseNew(src, typeDuration).Interface())
+	}
+
+	test(0, `0`)
+	test(0, `0s`)
+	test(30*time.Second, `30s`)
+	test(-30*time.Second, `-30s`)
+	test(time.Hour+15*time.Minute, `1h15m`)
+	test(1500*time.Millisecond, `1.5s`)
+	test(10, `10`)
+	test(-10, `-10`)
+	test(10, `10ns`)
+
+	for _, src := range []string{``, `garbage`, `10 s`, `1.5`, `s`} {
+		errs(
+			t,
+			fmt.Sprintf(`failed to parse %q into time.Duration`, src),
+			rd.Parse(src, r.New(typeDuration).Elem()),
+		)
+	}
+
+	t.Run(`slice`, func(t *testing.T) {
+		var tar []time.Duration
+		try(rd.ParseSlice([]string{`30s`, `10`, `1m`}, r.ValueOf(&tar).Elem()))
+		eq(t, []time.Duration{30 * time.Second, 10, time.Minute}, tar)
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar struct {
+			One time.Duration   `json:"one"`
+			Two []time.Duration `json:"two"`
+		}
+		try(rd.Form{`one`: {`30s`}, `two`: {`1m`, `2h`}}.Decode(&tar))
+		eq(t, 30*time.Second, tar.One)
+		eq(t, []time.Duration{time.Minute, 2 * time.Hour}, tar.Two)
+	})
+}
+
 func TestParse_unmarshaler(t *testing.T) {
 	testOk := func(src string, exp time.Time) {
 		t.Helper()
@@ -109,6 +187,266 @@ func TestParse_unmarshaler(t *testing.T) {
 	testParseFail(t, `garbage`, typeTime, `cannot parse`)
 }
 
+func TestParse_ip(t *testing.T) {
+	t.Run(`net.IP`, func(t *testing.T) {
+		var tar net.IP
+		try(rd.Parse(`10.0.0.1`, r.ValueOf(&tar).Elem()))
+		eq(t, net.ParseIP(`10.0.0.1`), tar)
+
+		try(rd.Parse(`::1`, r.ValueOf(&tar).Elem()))
+		eq(t, net.IPv6loopback, tar)
+
+		errs(t, `invalid IP address: garbage`, rd.Parse(`garbage`, r.ValueOf(&tar).Elem()))
+	})
+
+	t.Run(`net.IPNet`, func(t *testing.T) {
+		var tar net.IPNet
+		try(rd.Parse(`10.0.0.1/8`, r.ValueOf(&tar).Elem()))
+		eq(t, net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}, tar)
+
+		errs(t, `failed to parse "" into net.IPNet`, rd.Parse(``, r.ValueOf(&tar).Elem()))
+		errs(t, `failed to parse "10.0.0.1" into net.IPNet`, rd.Parse(`10.0.0.1`, r.ValueOf(&tar).Elem()))
+	})
+
+	t.Run(`netip`, func(t *testing.T) {
+		var addr netip.Addr
+		try(rd.Parse(`10.0.0.1`, r.ValueOf(&addr).Elem()))
+		eq(t, netip.MustParseAddr(`10.0.0.1`), addr)
+
+		var prefix netip.Prefix
+		try(rd.Parse(`10.0.0.1/8`, r.ValueOf(&prefix).Elem()))
+		eq(t, netip.MustParsePrefix(`10.0.0.1/8`), prefix)
+
+		errs(t, `ParseAddr("garbage")`, rd.Parse(`garbage`, r.ValueOf(&addr).Elem()))
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar struct {
+			One   net.IP       `json:"one"`
+			Two   net.IPNet    `json:"two"`
+			Three []netip.Addr `json:"three"`
+			Four  []net.IP     `json:"four"`
+		}
+
+		try(rd.Form{
+			`one`:   {`10.0.0.1`},
+			`two`:   {`192.168.0.0/16`},
+			`three`: {`10.0.0.1`, `::1`},
+			`four`:  {`10.0.0.2`},
+		}.Decode(&tar))
+
+		eq(t, net.ParseIP(`10.0.0.1`), tar.One)
+		eq(t, `192.168.0.0/16`, tar.Two.String())
+		eq(t, []netip.Addr{netip.MustParseAddr(`10.0.0.1`), netip.MustParseAddr(`::1`)}, tar.Three)
+		eq(t, []net.IP{net.ParseIP(`10.0.0.2`)}, tar.Four)
+	})
+
+	t.Run(`json coerce`, func(t *testing.T) {
+		var tar struct {
+			One net.IP       `json:"one"`
+			Two []netip.Addr `json:"two"`
+		}
+		try(rd.Json(`{"one": "10.0.0.1", "two": "::1"}`).DecodeWith(&tar, rd.Config{Coerce: true}))
+		eq(t, net.ParseIP(`10.0.0.1`), tar.One)
+		eq(t, []netip.Addr{netip.MustParseAddr(`::1`)}, tar.Two)
+	})
+}
+
+func TestParse_url(t *testing.T) {
+	test := func(exp string, src string) {
+		t.Helper()
+		var tar url.URL
+		try(rd.Parse(src, r.ValueOf(&tar).Elem()))
+		eq(t, exp, tar.String())
+	}
+
+	test(``, ``)
+	test(`https://example.com/one?two=three#four`, `https://example.com/one?two=three#four`)
+	test(`/one?two=three`, `/one?two=three`)
+
+	errs(t, `failed to parse "%zz" into url.URL: parse "%zz": invalid URL escape "%zz"`, rd.Parse(`%zz`, r.New(r.TypeOf(url.URL{})).Elem()))
+	errs(t, `failed to parse "http://[::1" into url.URL`, rd.Parse(`http://[::1`, r.New(r.TypeOf(url.URL{})).Elem()))
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar struct {
+			One   url.URL    `json:"one"`
+			Two   *url.URL   `json:"two"`
+			Three []*url.URL `json:"three"`
+		}
+
+		try(rd.Form{
+			`one`:   {`https://example.com/one`},
+			`two`:   {`/two`},
+			`three`: {`https://example.com/three`, `four`},
+		}.Decode(&tar))
+
+		eq(t, `https://example.com/one`, tar.One.String())
+		eq(t, `/two`, tar.Two.String())
+		eq(t, 2, len(tar.Three))
+		eq(t, `https://example.com/three`, tar.Three[0].String())
+		eq(t, `four`, tar.Three[1].String())
+
+		try(rd.Form{`two`: nil}.Decode(&tar))
+		eq(t, (*url.URL)(nil), tar.Two)
+	})
+}
+
+// `math/big` types implement `encoding.TextUnmarshaler`, no special case needed.
+func TestParse_big(t *testing.T) {
+	t.Run(`big.Int`, func(t *testing.T) {
+		test := func(exp string, src string) {
+			t.Helper()
+			var tar big.Int
+			try(rd.Parse(src, r.ValueOf(&tar).Elem()))
+			eq(t, exp, tar.String())
+		}
+
+		test(`0`, `0`)
+		test(`123456789012345678901234567890`, `123456789012345678901234567890`)
+		test(`-10`, `-10`)
+		test(`255`, `0xff`)
+
+		errs(t, `cannot unmarshal "1.5" into a *big.Int`, rd.Parse(`1.5`, r.New(r.TypeOf(big.Int{})).Elem()))
+	})
+
+	t.Run(`big.Float`, func(t *testing.T) {
+		var tar big.Float
+		try(rd.Parse(`1.25`, r.ValueOf(&tar).Elem()))
+		eq(t, `1.25`, tar.Text('f', -1))
+
+		errs(t, `garbage`, rd.Parse(`garbage`, r.ValueOf(&tar).Elem()))
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar struct {
+			One   *big.Int     `json:"one"`
+			Two   []big.Int    `json:"two"`
+			Three *big.Float   `json:"three"`
+			Four  []*big.Float `json:"four"`
+		}
+
+		try(rd.Form{
+			`one`:   {`123456789012345678901234567890`},
+			`two`:   {`10`, `-20`},
+			`three`: {`0.5`},
+			`four`:  {`1.5`, `2`},
+		}.Decode(&tar))
+
+		eq(t, `123456789012345678901234567890`, tar.One.String())
+		eq(t, []string{`10`, `-20`}, []string{tar.Two[0].String(), tar.Two[1].String()})
+		eq(t, `0.5`, tar.Three.Text('f', -1))
+		eq(t, []string{`1.5`, `2`}, []string{tar.Four[0].Text('f', -1), tar.Four[1].Text('f', -1)})
+	})
+}
+
+func TestParse_time_unix(t *testing.T) {
+	test := func(exp time.Time, src string) {
+		t.Helper()
+		var tar time.Time
+		try(rd.Parse(src, r.ValueOf(&tar).Elem()))
+		eq(t, exp, tar)
+	}
+
+	test(time.Unix(1700000000, 0).UTC(), `1700000000`)
+	test(time.Unix(0, 0).UTC(), `0`)
+	test(time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC), `2000-01-02T03:04:05Z`)
+
+	var tar time.Time
+	errs(t, `failed to parse "99999999999999999999" into time.Time`, rd.Parse(`99999999999999999999`, r.ValueOf(&tar).Elem()))
+	errs(t, `cannot parse "-1"`, rd.Parse(`-1`, r.ValueOf(&tar).Elem()))
+	errs(t, `cannot parse "+1"`, rd.Parse(`+1`, r.ValueOf(&tar).Elem()))
+}
+
+func TestForm_Decode_time_unix(t *testing.T) {
+	type Tar struct {
+		Sec   time.Time   `json:"sec"`
+		Milli time.Time   `json:"milli" rd:"unix=ms"`
+		Micro *time.Time  `json:"micro" rd:"unix=us"`
+		Nano  []time.Time `json:"nano"  rd:"unix=ns"`
+	}
+
+	var tar Tar
+	try(rd.Form{
+		`sec`:   {`1700000000`},
+		`milli`: {`1700000000123`},
+		`micro`: {`1700000000123456`},
+		`nano`:  {`1700000000123456789`, `2000-01-02T03:04:05Z`},
+	}.Decode(&tar))
+
+	eq(t, time.Unix(1700000000, 0).UTC(), tar.Sec)
+	eq(t, time.UnixMilli(1700000000123).UTC(), tar.Milli)
+	eq(t, time.UnixMicro(1700000000123456).UTC(), *tar.Micro)
+	eq(t, []time.Time{time.Unix(0, 1700000000123456789).UTC(), time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)}, tar.Nano)
+
+	t.Run(`invalid unit`, func(t *testing.T) {
+		type Tar struct {
+			Time time.Time `json:"time" rd:"unix=min"`
+		}
+		err := rd.Form{`time`: {`10`}}.Decode(new(Tar))
+		errStatus(t, http.StatusInternalServerError, err)
+		errs(t, `invalid unit of Unix timestamps in "rd" tag of field "time"`, err)
+	})
+}
+
+func TestForm_Decode_layout(t *testing.T) {
+	type Tar struct {
+		Born  time.Time            `json:"born"  layout:"2006-01-02"`
+		Ptr   *time.Time           `json:"ptr"   layout:"2006-01-02 15:04"`
+		Nums  []time.Time          `json:"nums"  layout:"20060102"`
+		Map   map[string]time.Time `json:"map" layout:"02.01.2006"`
+		Zone  time.Time            `json:"zone"  layout:"2006-01-02 -0700"`
+		Plain time.Time            `json:"plain"`
+		Str   string               `json:"str"   layout:"2006-01-02"`
+	}
+
+	var tar Tar
+	try(rd.Form{
+		`born`:     {`2000-01-02`},
+		`ptr`:      {`2000-01-02 03:04`},
+		`nums`:     {`20000102`, `20010203`},
+		`map[one]`: {`02.01.2000`},
+		`zone`:     {`2000-01-02 +0300`},
+		`plain`:    {`2000-01-02T03:04:05Z`},
+		`str`:      {`one`},
+	}.Decode(&tar))
+
+	eq(t, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), tar.Born)
+	eq(t, time.Date(2000, 1, 2, 3, 4, 0, 0, time.UTC), *tar.Ptr)
+	eq(t, []time.Time{time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)}, tar.Nums)
+	eq(t, map[string]time.Time{`one`: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)}, tar.Map)
+	eq(t, time.Date(2000, 1, 1, 21, 0, 0, 0, time.UTC), tar.Zone.UTC())
+	eq(t, time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC), tar.Plain)
+	eq(t, `one`, tar.Str)
+
+	t.Run(`invalid`, func(t *testing.T) {
+		err := rd.Form{`born`: {`2000-01-02T03:04:05Z`}}.Decode(new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to decode field "born": failed to parse "2000-01-02T03:04:05Z" into time.Time`, err)
+
+		errs(t, `failed to parse "1700000000" into time.Time`, rd.Form{`born`: {`1700000000`}}.Decode(new(Tar)))
+	})
+}
+
+func TestParse_binary_unmarshaler(t *testing.T) {
+	typ := r.TypeOf(BinaryKey{})
+	eq(t, BinaryKey{'a', 'b', 'c', 'd'}, parseNew(`abcd`, typ).Interface())
+	eq(t, BinaryKey{0, 0xff, '\n', 1}, parseNew("\x00\xff\n\x01", typ).Interface())
+	errs(t, `expected 4 bytes, got 3`, rd.Parse(`abc`, r.New(typ).Elem()))
+
+	// Text unmarshaling takes priority.
+	exp := time.Date(1234, 1, 2, 3, 4, 5, 0, time.UTC)
+	eq(t, exp, parseNew(`1234-01-02T03:04:05Z`, typeTime).Interface())
+
+	type Tar struct {
+		Key  BinaryKey   `json:"key"`
+		Keys []BinaryKey `json:"keys"`
+	}
+
+	var tar Tar
+	try(rd.Form{`key`: {`abcd`}, `keys`: {`efgh`, `ijkl`}}.DecodeWith(&tar, rd.Config{StrictArity: true}))
+	eq(t, Tar{Key: BinaryKey{'a', 'b', 'c', 'd'}, Keys: []BinaryKey{{'e', 'f', 'g', 'h'}, {'i', 'j', 'k', 'l'}}}, tar)
+}
+
 func TestParse_parser(t *testing.T) {
 	testOk := func(src string, exp TimeParser) {
 		t.Helper()
@@ -133,6 +471,40 @@ func testParseFail(t testing.TB, src string, typ r.Type, msg string) {
 
 // `rd.ParseSlice` delegates to `rd.Parse` which is tested separately.
 // Here we mainly need to verify slice handling.
+func TestParseInto(t *testing.T) {
+	eq(t, 10, tryVal(rd.ParseInto[int](`10`)))
+	eq(t, `one`, tryVal(rd.ParseInto[string](`one`)))
+	eq(t, 30*time.Second, tryVal(rd.ParseInto[time.Duration](`30s`)))
+	eq(t, TimeParser(time.Date(1234, 1, 2, 3, 4, 5, 0, time.UTC)), tryVal(rd.ParseInto[TimeParser](`1234-01-02T03:04:05Z`)))
+	eq(t, ptrInt(10), tryVal(rd.ParseInto[*int](`10`)))
+
+	val := tryVal(rd.ParseInto[**string](`one`))
+	eq(t, `one`, **val)
+
+	val0, err := rd.ParseInto[int](`one`)
+	errs(t, `failed to parse "one" into int`, err)
+	eq(t, 0, val0)
+
+	val1, err := rd.ParseInto[*int](`one`)
+	errs(t, `failed to parse "one" into int`, err)
+	eq(t, (*int)(nil), val1)
+
+	_, err = rd.ParseInto[interface{}](`one`)
+	errs(t, `dynamically-typed interface values are not supported`, err)
+}
+
+func TestParseSliceInto(t *testing.T) {
+	eq(t, []int(nil), tryVal(rd.ParseSliceInto[int](nil)))
+	eq(t, []int{}, tryVal(rd.ParseSliceInto[int]([]string{})))
+	eq(t, []int{10, 20}, tryVal(rd.ParseSliceInto[int]([]string{`10`, `20`})))
+	eq(t, []*int{ptrInt(10), ptrInt(20)}, tryVal(rd.ParseSliceInto[*int]([]string{`10`, `20`})))
+	eq(t, []time.Duration{time.Second}, tryVal(rd.ParseSliceInto[time.Duration]([]string{`1s`})))
+
+	val, err := rd.ParseSliceInto[int]([]string{`10`, `one`})
+	errs(t, `failed to parse "one" into int`, err)
+	eq(t, []int(nil), val)
+}
+
 func TestParseSlice(t *testing.T) {
 	test := func(exp []int, src []string, tar []int) {
 		t.Helper()
@@ -154,6 +526,113 @@ func TestParseSlice(t *testing.T) {
 	test([]int{30, 40}, []string{`30`, `40`}, []int{10, 20})
 }
 
+func TestParseSlice_ptr(t *testing.T) {
+	test := func(exp *[]int, src []string, tar *[]int) {
+		t.Helper()
+		try(rd.ParseSlice(src, r.ValueOf(&tar).Elem()))
+		eq(t, exp, tar)
+	}
+
+	test((*[]int)(nil), []string(nil), (*[]int)(nil))
+	test((*[]int)(nil), []string(nil), &[]int{10, 20})
+	test(&[]int{}, []string{}, (*[]int)(nil))
+	test(&[]int{}, []string{}, &[]int{10, 20})
+	test(&[]int{30}, []string{`30`}, (*[]int)(nil))
+	test(&[]int{30}, []string{`30`}, &[]int{10, 20})
+	test(&[]int{30, 40}, []string{`30`, `40`}, (*[]int)(nil))
+	test(&[]int{30, 40}, []string{`30`, `40`}, &[]int{10, 20})
+
+	t.Run(`existing pointer`, func(t *testing.T) {
+		val := []int{10, 20}
+		tar := &val
+		try(rd.ParseSlice([]string{`30`}, r.ValueOf(&tar).Elem()))
+		eq(t, []int{30}, val)
+	})
+
+	t.Run(`multi-level`, func(t *testing.T) {
+		var tar **[]int
+		try(rd.ParseSlice([]string{`30`, `40`}, r.ValueOf(&tar).Elem()))
+		eq(t, []int{30, 40}, **tar)
+
+		try(rd.ParseSlice(nil, r.ValueOf(&tar).Elem()))
+		eq(t, (**[]int)(nil), tar)
+	})
+
+	t.Run(`array`, func(t *testing.T) {
+		var tar *[2]int
+		try(rd.ParseSlice([]string{`30`, `40`}, r.ValueOf(&tar).Elem()))
+		eq(t, &[2]int{30, 40}, tar)
+	})
+
+	t.Run(`SliceParser`, func(t *testing.T) {
+		var tar *SliceParserStruct
+		try(rd.ParseSlice([]string{`10`, `20`}, r.ValueOf(&tar).Elem()))
+		eq(t, &SliceParserStruct{[]int{10, 20}}, tar)
+	})
+}
+
+func TestParseSlice_ptr_elems(t *testing.T) {
+	test := func(exp []int, src []string) {
+		t.Helper()
+
+		var one []*int
+		try(rd.ParseSlice(src, r.ValueOf(&one).Elem()))
+		eq(t, len(exp), len(one))
+
+		var two []**int
+		try(rd.ParseSlice(src, r.ValueOf(&two).Elem()))
+		eq(t, len(exp), len(two))
+
+		for i, val := range exp {
+			eq(t, val, *one[i])
+			eq(t, val, **two[i])
+		}
+	}
+
+	test(nil, []string(nil))
+	test([]int{}, []string{})
+	test([]int{30}, []string{`30`})
+	test([]int{30, 40}, []string{`30`, `40`})
+
+	t.Run(`array`, func(t *testing.T) {
+		var tar [2]**int
+		try(rd.ParseSlice([]string{`30`, `40`}, r.ValueOf(&tar).Elem()))
+		eq(t, 30, **tar[0])
+		eq(t, 40, **tar[1])
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		var tar []**int
+		errs(t, `failed to parse "one" into int`, rd.ParseSlice([]string{`10`, `one`}, r.ValueOf(&tar).Elem()))
+		eq(t, []**int(nil), tar)
+	})
+}
+
+func TestParseSlice_array(t *testing.T) {
+	test := func(exp [2]int, src []string, tar [2]int) {
+		t.Helper()
+		try(rd.ParseSlice(src, r.ValueOf(&tar).Elem()))
+		eq(t, exp, tar)
+	}
+
+	test([2]int{}, []string(nil), [2]int{})
+	test([2]int{}, []string(nil), [2]int{10, 20})
+	test([2]int{30, 40}, []string{`30`, `40`}, [2]int{})
+	test([2]int{30, 40}, []string{`30`, `40`}, [2]int{10, 20})
+
+	fail := func(exp string, src []string) {
+		t.Helper()
+		tar := [2]int{10, 20}
+		errs(t, exp, rd.ParseSlice(src, r.ValueOf(&tar).Elem()))
+		eq(t, [2]int{10, 20}, tar)
+	}
+
+	fail(`failed to parse 0 values into [2]int: expected 2 values`, []string{})
+	fail(`failed to parse 1 values into [2]int: expected 2 values`, []string{`30`})
+	fail(`failed to parse 3 values into [2]int: expected 2 values`, []string{`30`, `40`, `50`})
+	fail(`failed to parse "four" into int`, []string{`30`, `four`})
+}
+
 func TestParseSlice_SliceParser(t *testing.T) {
 	var tar SliceParserStruct
 	try(rd.ParseSlice([]string{`10`, `20`}, r.ValueOf(&tar).Elem()))
@@ -258,233 +737,5600 @@ func TestJson_Haser_parsing(t *testing.T) {
 	test(set(`one`, `two`), `{"one": ["three"], "two" : ["four"]}`)
 	test(set(`one`, `two`), `{"one": ["three", "four"], "two": ["five", "six"]}`)
 	test(set(`one`, `two`), `{"one": {"three\\four": "five\\six"}, "two" : { "seven" : [ "eight" , "nine" ] } }`)
-	test(set(`one\\two`, `two\\three`), `{"one\\two": null, "two\\three": null}`)
+	test(set(`one\two`, `two\three`), `{"one\\two": null, "two\\three": null}`)
+	test(set(`abc`), `{"a\u0062c": 1}`)
+	test(set(`abc`), `{"a\u0062c": 1, "abc": 2}`)
+	test(set("\"\\/\b\f\n\r\t"), `{"\"\\\/\b\f\n\r\t": null}`)
+	test(set(`é`, `日本`), `{"\u00e9": null, "\u65E5\u672c": null}`)
+	test(set(`😀`), `{"\ud83d\ude00": null}`)
+	test(set("\ufffd"), `{"\ud83d": null}`)
+	test(set("\ufffdx"), `{"\ude00x": null}`)
+	test(set("\ufffd\u0041"), `{"\ud83d\u0041": null}`)
+	test(set(`one`), `{"one": "\u0062\"}"}`)
 
-	// TODO test panics on invalid syntax.
-}
+	for _, src := range []string{
+		`{"a\u0062c": 1}`,
+		`{"\"\\\/\b\f\n\r\t": null}`,
+		`{"\ud83d\ude00": null, "\ud83d": null, "\ud83d\u0041": null}`,
+	} {
+		exp := rd.Set{}
+		for key := range parseSetWithStdlib([]byte(src)) {
+			exp.Add(key)
+		}
+		test(exp, src)
+	}
 
-func TestJson_Haser(t *testing.T) {
-	eq(t, testOuterJsonSet, rd.Json(testOuterJson).Haser())
-}
+	panics(t, `invalid escape sequence "\\x"`, func() { rd.Json(`{"one\x": null}`).Set() })
+	panics(t, `invalid escape sequence "\\u12G4"`, func() { rd.Json(`{"\u12G4": null}`).Set() })
 
-func TestJson_Set(t *testing.T) {
-	eq(t, testOuterJsonSet, rd.Json(testOuterJson).Set())
 }
 
-func TestForm_Haser(t *testing.T) {
-	val := rd.Form(testOuterQuery)
-	eq(t, val, val.Haser())
-}
+func TestJson_SetNested(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		eq(t, exp, rd.Json(src).SetNested())
+	}
 
-func TestForm_Set(t *testing.T) {
-	eq(t, testOuterQuerySet, rd.Form(testOuterQuery).Set())
+	test(nil, ``)
+	test(nil, `[]`)
+	test(nil, `{}`)
+	test(set(`one`), `{"one": {}}`)
+	test(set(`inner`, `inner.innerStr`), `{"inner": {"innerStr": 1}}`)
+	test(
+		set(`embedStr`, `embedNum`, `inner`, `inner.innerStr`, `inner.innerNum`, `outerStr`),
+		testOuterJson,
+	)
+	test(
+		set(`one`, `one.two`, `one.two.three`, `one.four`, `five`),
+		`{"one": {"two": {"three": [1, "}"]}, "four": null}, "five": "six"}`,
+	)
+	test(set(`list`, `list.one`, `list.two`), `{"list": [{"one": 1}, [{"two": 2}], 3]}`)
+	test(set(`a.b`, `a.b.c`), `{"a.b": {"c": 1}}`)
+	test(set(`abc`, `abc.d`), `{"a\u0062c": {"\u0064": 1}}`)
+
+	eq(t, set(`one`), rd.Json(`{"one": {"two": 3}}`).Set())
+
+	panics(t, `invalid JSON syntax in position 15`, func() { rd.Json(`{"one": {"two" 3}}`).SetNested() })
 }
 
-func TestForm_Has(t *testing.T) {
-	haser := rd.Form(testOuterQuery)
+func TestJson_SetLenient(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		eq(t, exp, rd.Json(src).SetLenient())
+	}
 
-	eq(t, true, haser.Has(`embedStr`))
-	eq(t, true, haser.Has(`embedNum`))
-	eq(t, true, haser.Has(`outerStr`))
-	eq(t, false, haser.Has(``))
-	eq(t, false, haser.Has(`inner`))
-	eq(t, false, haser.Has(`innerStr`))
-	eq(t, false, haser.Has(`innerNum`))
+	test(nil, ``)
+	test(nil, `[1, 2,]`)
+	test(set(), `{}`)
+	test(set(`one`), `{"one": 10,}`)
+	test(set(`one`, `two`), `{"one": [10, 20,], "two": {"three": 30,},}`)
+	test(set(`one`, `two`), "{\"one\": 10,\n\t\"two\": 20,\n}")
+	test(testOuterJsonSet, testOuterJson)
+
+	panics(t, `invalid JSON syntax in position 11`, func() { rd.Json(`{"one": 10,,}`).SetLenient() })
+	panics(t, `invalid JSON syntax in position 1`, func() { rd.Json(`{,}`).SetLenient() })
+	panics(t, `invalid JSON syntax in position 9`, func() { rd.Json(`{"one": [,]}`).SetLenient() })
+
+	// The default remains strict.
+	panics(t, `invalid JSON syntax in position 11`, func() { rd.Json(`{"one": 10,}`).Set() })
+	panics(t, `invalid JSON syntax in position 12`, func() { rd.Json(`{"one": [10,]}`).Set() })
 }
 
-// `rd.Json.Decode` delegates to `json.Unmarshal`.
-// We only need to verify that it does, in fact, unmarshal.
-func TestJson_Decode(t *testing.T) {
+func TestJson_Cached(t *testing.T) {
+	var _ rd.Dec = rd.Json(nil).Cached()
+
+	dec := rd.Json(testOuterJson).Cached()
+	eq(t, rd.Json(testOuterJson), dec.Json())
+
 	var tar Outer
-	try(rd.Json(testOuterJson).Decode(&tar))
+	try(dec.Decode(&tar))
 	eq(t, testOuter, tar)
-}
 
-func TestForm_Decode(t *testing.T) {
-	test := func(t testing.TB, exp, tar interface{}, src url.Values) {
-		testDec(t, exp, tar, rd.Form(src))
-	}
+	one := dec.Set()
+	eq(t, testOuterJsonSet, one)
+	eq(t, r.ValueOf(one).Pointer(), r.ValueOf(dec.Set()).Pointer())
+	eq(t, r.ValueOf(one).Pointer(), r.ValueOf(dec.Haser()).Pointer())
+	eq(t, true, dec.Haser().Has(`inner`))
 
-	t.Run(`normal`, func(t *testing.T) {
-		test(t, TarUnusable{}, TarUnusable{}, unusableVals)
-		test(t, TarVoid{}, TarVoid{}, url.Values{})
-		test(t, TarVoid{}, TarVoid{}, url.Values{`one`: {`two`}})
+	t.Run(`concurrent`, func(t *testing.T) {
+		dec := rd.Json(testOuterJson).Cached()
+		var group sync.WaitGroup
 
-		for _, src := range nopQueries {
-			test(t, TarInt{10}, TarInt{10}, src)
-			test(t, TarPtrInt{ptrInt(10)}, TarPtrInt{ptrInt(10)}, src)
-			test(t, TarSliceInt{[]int{10, 20}}, TarSliceInt{[]int{10, 20}}, src)
-			test(t, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
+		for range iter(16) {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				eq(t, true, dec.Haser().Has(`outerStr`))
+			}()
 		}
+		group.Wait()
+	})
 
-		for _, src := range zeroQueries {
-			test(t, TarInt{}, TarInt{10}, src)
-			test(t, TarPtrInt{}, TarPtrInt{ptrInt(10)}, src)
-			test(t, TarSliceInt{}, TarSliceInt{[]int{10, 20}}, src)
-			test(t, TarSlicePtrInt{}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
-		}
+	t.Run(`invalid`, func(t *testing.T) {
+		dec := rd.Json(`{"one" 10}`).Cached()
 
-		for i, src := range numQueries {
-			test(t, TarInt{20}, TarInt{10}, src)
-			test(t, TarPtrInt{ptrInt(20)}, TarPtrInt{ptrInt(10)}, src)
-			test(t, TarSliceInt{numOutputs[i]}, TarSliceInt{[]int{10, 20}}, src)
-			test(t, TarSlicePtrInt{numPtrOutputs[i]}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
-		}
+		_, err := dec.TrySet()
+		errStatus(t, 400, err)
+		errs(t, `invalid JSON syntax in position 7`, err)
+
+		panics(t, `invalid JSON syntax in position 7`, func() { dec.Set() })
+		panics(t, `invalid JSON syntax in position 7`, func() { dec.Haser() })
 	})
+}
 
-	t.Run(`missing fields are unaffected`, func(t *testing.T) {
-		test(
-			t,
-			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
-			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
-			url.Values{`three`: {`50`, `60`}},
-		)
+func TestJson_TrySet(t *testing.T) {
+	test := func(src string, exp rd.JsonSyntaxError, msg string) {
+		t.Helper()
 
-		test(
-			t,
-			TarPair{One: []int{10, 20}, Two: []int{50, 60}},
-			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
-			url.Values{`two`: {`50`, `60`}},
-		)
+		_, err := rd.Json(src).TrySet()
+		errStatus(t, 400, err)
+		errs(t, msg, err)
 
-		test(
-			t,
-			TarPair{One: []int{50, 60}, Two: []int{30, 40}},
-			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
-			url.Values{`one`: {`50`, `60`}},
-		)
-	})
+		var tar rd.JsonSyntaxError
+		if !errors.As(err, &tar) {
+			t.Fatalf(`expected rd.JsonSyntaxError, got %#v`, err)
+		}
+		tar.Cause = nil
+		eq(t, exp, tar)
 
-	t.Run(`structs embedded by value`, func(t *testing.T) {
-		test(
-			t,
-			testOuterSimple,
-			Outer{
-				Embed:    Embed{EmbedStr: `embed val old`, EmbedNum: 20},
-				OuterStr: `outer val old`,
-			},
-			testOuterQuery,
-		)
-	})
+		panics(t, msg, func() { rd.Json(src).Set() })
+	}
+
+	test(
+		`{"one" 10}`,
+		rd.JsonSyntaxError{Pos: 7, Line: 1, Col: 8, Snippet: `10}`},
+		`invalid JSON syntax in position 7 (line 1, column 8): unexpected "10}"`,
+	)
+
+	test(
+		"{\n\t\"one\": 10,\n\t\"two\": tru\n}",
+		rd.JsonSyntaxError{Pos: 23, Line: 3, Col: 10, Snippet: "ru\n}"},
+		`invalid JSON syntax in position 23 (line 3, column 10): unexpected "ru\n}"`,
+	)
+
+	test(
+		`{"one": "two", "three": "`+strings.Repeat(`a`, 64),
+		rd.JsonSyntaxError{Pos: 89, Line: 1, Col: 90},
+		`invalid JSON syntax in position 89 (line 1, column 90): unexpected EOF`,
+	)
+
+	// Unlike `rd.Parse`, JSON doesn't allow a leading "+".
+	test(
+		`{"one": +10}`,
+		rd.JsonSyntaxError{Pos: 8, Line: 1, Col: 9, Snippet: `+10}`},
+		`invalid JSON syntax in position 8 (line 1, column 9): unexpected "+10}"`,
+	)
+
+	test(
+		`{"one\x": 10}`,
+		rd.JsonSyntaxError{Pos: 2, Line: 1, Col: 3, Snippet: `one\x": 10}`},
+		`invalid JSON syntax in position 2 (line 1, column 3): invalid escape sequence "\\x"`,
+	)
+
+	t.Run(`snippet is truncated`, func(t *testing.T) {
+		_, err := rd.Json(`{"one": ` + strings.Repeat(`x`, 64) + `}`).TrySet()
+		var tar rd.JsonSyntaxError
+		errors.As(err, &tar)
+		eq(t, strings.Repeat(`x`, 32), tar.Snippet)
+	})
+
+	t.Run(`eof`, func(t *testing.T) {
+		_, err := rd.Json(`{"one": [10`).TrySet()
+		eq(t, true, errors.Is(err, io.EOF))
+
+		_, err = rd.Json(`{"one": 10 x`).TrySet()
+		eq(t, false, errors.Is(err, io.EOF))
+	})
+
+	t.Run(`valid`, func(t *testing.T) {
+		set, err := rd.Json(testOuterJson).TrySet()
+		try(err)
+		eq(t, testOuterJsonSet, set)
+	})
+}
+
+func TestJson_Valid(t *testing.T) {
+	t.Run(`valid`, func(t *testing.T) {
+		test := func(src string) {
+			t.Helper()
+			eq(t, true, json.Valid([]byte(src)))
+			try(rd.Json(src).Valid())
+		}
+
+		test(`null`)
+		test(` 10 `)
+		test(`-0.5e+10`)
+		test(`"str"`)
+		test(`[]`)
+		test(`[1, "two", {"three": [null, true, false]}]`)
+		test(`{"one\\two": "\u0062\ud83d\ude00", "\"\\\/\b\f\n\r\t": {}}`)
+		test(`{"日本": "語"}`)
+		test(testOuterJson)
+		test(jsonSrcHuge)
+	})
+
+	test := func(src string, msg string) {
+		t.Helper()
+		eq(t, false, json.Valid([]byte(src)))
+
+		err := rd.Json(src).Valid()
+		errStatus(t, 400, err)
+		errs(t, msg, err)
+
+		var tar rd.JsonSyntaxError
+		if !errors.As(err, &tar) {
+			t.Fatalf(`expected rd.JsonSyntaxError, got %#v`, err)
+		}
+	}
+
+	test(``, `invalid JSON syntax in position 0 (line 1, column 1): unexpected EOF`)
+	test(`   `, `invalid JSON syntax in position 3 (line 1, column 4): unexpected EOF`)
+	test(`{"one": 10} {}`, `invalid JSON syntax in position 12 (line 1, column 13): unexpected "{}"`)
+	test(`[1, 2`, `unexpected EOF`)
+	test(`{"one": 01}`, `invalid JSON syntax in position 9 (line 1, column 10): unexpected "1}"`)
+	test(`[-00]`, `invalid JSON syntax in position 3 (line 1, column 4): unexpected "0]"`)
+	test(`["one\x"]`, `invalid JSON syntax in position 5 (line 1, column 6): invalid escape sequence "\\x"`)
+	test(`["\u12g4"]`, `invalid JSON syntax in position 2 (line 1, column 3): invalid escape sequence "\\u12g4"`)
+	test("[\"one\ttwo\"]", `invalid JSON syntax in position 5 (line 1, column 6): invalid control character '\t' in string`)
+	test("[1,\v2]", `invalid JSON syntax in position 3 (line 1, column 4)`)
+	test(`arbitrary garbage`, `invalid JSON syntax in position 0 (line 1, column 1)`)
+
+	// Not detected by `rd.Json.Set`, which only cares about top-level keys.
+	eq(t, rd.Set{`one`: {}}, rd.Json(`{"one": [01, "\x"]}`).Set())
+}
+
+func TestJson_SetStrict(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		out, err := rd.Json(src).SetStrict()
+		try(err)
+		eq(t, exp, out)
+	}
+
+	test(nil, ``)
+	test(nil, `[1, 1]`)
+	test(set(`one`, `two`), `{"one": {"three": 1}, "two": {"three": 2}}`)
+	test(testOuterJsonSet, testOuterJson)
+
+	fail := func(msg string, src string) {
+		t.Helper()
+		_, err := rd.Json(src).SetStrict()
+		errStatus(t, 400, err)
+		errs(t, msg, err)
+	}
+
+	fail(`duplicate JSON key "one"`, `{"one": 10, "two": 20, "one": 30}`)
+	fail(`duplicate JSON key "one"`, `{"one": 10, "\u006fne": 20}`)
+	fail(`invalid JSON syntax in position 7`, `{"one" 10}`)
+}
+
+func TestParseSetReader(t *testing.T) {
+	readers := map[string]func(string) io.Reader{
+		`whole`:    func(src string) io.Reader { return strings.NewReader(src) },
+		`one byte`: func(src string) io.Reader { return iotest.OneByteReader(strings.NewReader(src)) },
+		`half`:     func(src string) io.Reader { return iotest.HalfReader(strings.NewReader(src)) },
+	}
+
+	for name, reader := range readers {
+		reader := reader
+
+		t.Run(name, func(t *testing.T) {
+			test := func(src string) {
+				t.Helper()
+				out, err := rd.ParseSetReader(reader(src))
+				try(err)
+				eq(t, rd.Json(src).Set(), out)
+			}
+
+			test(``)
+			test(`   `)
+			test(`null`)
+			test(`10`)
+			test(`"str"`)
+			test(`[{"one": "two"}]`)
+			test(`arbitrary garbage`)
+			test(`{}`)
+			test(` { } `)
+			test(`{"one": null, "two": true, "three": false}`)
+			test(`{"one": 0, "two": -12.34, "three": 1.2e+34, "four": -1.23E-45}`)
+			test(`{"one": ["three", "four"], "two": {"five": {"six": [7, {"eight": "}"}]}}}`)
+			test(`{"one\\two": null, "a\u0062c": 1, "\"\\\/\b\f\n\r\t": null}`)
+			test("\ufeff")
+			test("\ufeff{\"one\": 10}")
+			test("\xef\xbb{\"one\": 10}")
+			test(`{"\ud83d\ude00": null, "\ud83d": null, "one": "\u0062\"}"}`)
+			test(`{"日本": "語"}`)
+			test(testOuterJson)
+			test(jsonSrcHuge)
+		})
+	}
+
+	t.Run(`invalid`, func(t *testing.T) {
+		test := func(src string) {
+			t.Helper()
+
+			_, exp := rd.Json(src).TrySet()
+			var expTar rd.JsonSyntaxError
+			errors.As(exp, &expTar)
+
+			for _, reader := range readers {
+				_, err := rd.ParseSetReader(reader(src))
+				errStatus(t, http.StatusBadRequest, err)
+
+				var tar rd.JsonSyntaxError
+				if !errors.As(err, &tar) {
+					t.Fatalf(`expected rd.JsonSyntaxError, got %#v`, err)
+				}
+				eq(t, [3]int{expTar.Pos, expTar.Line, expTar.Col}, [3]int{tar.Pos, tar.Line, tar.Col})
+				eq(t, errors.Is(exp, io.EOF), errors.Is(err, io.EOF))
+			}
+		}
+
+		test(`{"one" 10}`)
+		test("{\n\t\"one\": 10,\n\t\"two\": tru\n}")
+		test(`{"one": "two", "three": "` + strings.Repeat(`a`, 64))
+		test(`{"one": [10`)
+		test(`{"one": 10 x`)
+		test(`{"one": 10,}`)
+		test(`{"one": -x}`)
+		test(`{"one": 1.}`)
+		test(`{"one": 1e}`)
+		test(`{"one": nul}`)
+		test(`{"one\x": 10}`)
+	})
+
+	t.Run(`snippet`, func(t *testing.T) {
+		for _, reader := range readers {
+			_, err := rd.ParseSetReader(reader(`{"one": ` + strings.Repeat(`x`, 64) + `}`))
+			errs(t, `unexpected "`+strings.Repeat(`x`, 32)+`"`, err)
+		}
+	})
+
+	t.Run(`reader error`, func(t *testing.T) {
+		_, err := rd.ParseSetReader(iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(`{"one": 10}`))))
+		errs(t, iotest.ErrTimeout.Error(), err)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, true, errors.Is(err, iotest.ErrTimeout))
+	})
+
+	t.Run(`no progress`, func(t *testing.T) {
+		_, err := rd.ParseSetReader(EmptyReader{})
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, true, errors.Is(err, io.ErrNoProgress))
+	})
+}
+
+func TestJson_Haser(t *testing.T) {
+	eq(t, testOuterJsonSet, rd.Json(testOuterJson).Haser())
+}
+
+func TestJson_Set(t *testing.T) {
+	eq(t, testOuterJsonSet, rd.Json(testOuterJson).Set())
+
+	t.Run(`huge`, func(t *testing.T) {
+		set := rd.Json(jsonSrcHuge).Set()
+		eq(t, jsonSrcHugeLen, len(set))
+
+		for key := range parseSetWithStdlib([]byte(jsonSrcHuge)) {
+			eq(t, true, set.Has(key))
+		}
+	})
+
+	t.Run(`bom`, func(t *testing.T) {
+		const bom = "\ufeff"
+
+		eq(t, testOuterJsonSet, rd.Json(bom+testOuterJson).Set())
+		eq(t, testOuterJsonSet, tryVal(rd.Json(bom+testOuterJson).TrySet()))
+		eq(t, true, rd.Json(bom+testOuterJson).Haser().Has(`outerStr`))
+		eq(t, set(`one`), rd.Json(bom+" \n{\"one\": 10}").Set())
+		eq(t, rd.Set(nil), rd.Json(bom).Set())
+
+		// Only a leading BOM is skipped.
+		eq(t, rd.Set(nil), rd.Json(` `+bom+`{"one": 10}`).Set())
+
+		// An incomplete BOM is not JSON.
+		eq(t, rd.Set(nil), rd.Json("\xef\xbb{\"one\": 10}").Set())
+
+		errs(
+			t,
+			`invalid JSON syntax in position 10 (line 1, column 11)`,
+			tryErr(rd.Json(bom+`{"one" 10}`).TrySet()),
+		)
+	})
+
+	// Pinned behavior: non-objects produce an empty set without validation.
+	t.Run(`non-objects`, func(t *testing.T) {
+		for _, src := range []string{``, `null`, `10`, `[{"one": 10}]`, `garbage`} {
+			eq(t, rd.Set(nil), rd.Json(src).Set())
+		}
+		errs(t, `unexpected "garbage"`, rd.Json(`garbage`).Valid())
+		try(rd.Json(`[{"one": 10}]`).Valid())
+	})
+}
+
+func TestSet_Keys(t *testing.T) {
+	eq(t, []string(nil), rd.Set(nil).Keys())
+	eq(t, []string(nil), rd.Set{}.Keys())
+	eq(t, []string{`one`}, set(`one`).Keys())
+	eq(t, []string{``, `One`, `one`, `three`, `two`}, set(`two`, `one`, `three`, ``, `One`).Keys())
+	eq(t, []string{`embedNum`, `embedStr`, `inner`, `outerStr`}, rd.Json(testOuterJson).Set().Keys())
+}
+
+func TestSet_Len(t *testing.T) {
+	eq(t, 0, rd.Set(nil).Len())
+	eq(t, 0, rd.Set{}.Len())
+	eq(t, 3, set(`one`, `two`, `three`).Len())
+	eq(t, 1, set(`one`, `one`).Len())
+}
+
+func TestSet_MarshalJSON(t *testing.T) {
+	test := func(exp string, src interface{}) {
+		t.Helper()
+		out, err := json.Marshal(src)
+		try(err)
+		eq(t, exp, string(out))
+	}
+
+	test(`null`, rd.Set(nil))
+	test(`[]`, rd.Set{})
+	test(`["one","three","two"]`, set(`two`, `one`, `three`))
+	test(`{"keys":["embedNum","embedStr","inner","outerStr"]}`, struct {
+		Keys rd.Set `json:"keys"`
+	}{rd.Json(testOuterJson).Set()})
+}
+
+func TestSet_UnmarshalJSON(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		tar := set(`prev`)
+		try(json.Unmarshal([]byte(src), &tar))
+		eq(t, exp, tar)
+	}
+
+	test(nil, `null`)
+	test(rd.Set{}, `[]`)
+	test(set(`one`, `two`), `["two", "one", "two"]`)
+
+	var tar rd.Set
+	errs(t, `cannot unmarshal object`, json.Unmarshal([]byte(`{"one": {}}`), &tar))
+
+	out, err := json.Marshal(rd.Form(testOuterQuery).Set())
+	try(err)
+	try(json.Unmarshal(out, &tar))
+	eq(t, rd.Form(testOuterQuery).Set(), tar)
+}
+
+func TestSet_ops(t *testing.T) {
+	one := set(`one`, `two`, `three`)
+	two := set(`two`, `three`, `four`)
+
+	eq(t, rd.Set{}, rd.Set(nil).Union(nil))
+	eq(t, set(`one`, `two`, `three`), one.Union(nil))
+	eq(t, set(`one`, `two`, `three`), rd.Set(nil).Union(one))
+	eq(t, set(`one`, `two`, `three`, `four`), one.Union(two))
+
+	eq(t, rd.Set{}, rd.Set(nil).Intersect(nil))
+	eq(t, rd.Set{}, one.Intersect(nil))
+	eq(t, rd.Set{}, rd.Set(nil).Intersect(one))
+	eq(t, set(`two`, `three`), one.Intersect(two))
+	eq(t, set(`two`, `three`), two.Intersect(one))
+	eq(t, set(`two`), one.Intersect(set(`two`, `five`)))
+
+	eq(t, rd.Set{}, rd.Set(nil).Diff(nil))
+	eq(t, set(`one`, `two`, `three`), one.Diff(nil))
+	eq(t, rd.Set{}, rd.Set(nil).Diff(one))
+	eq(t, set(`one`), one.Diff(two))
+	eq(t, set(`four`), two.Diff(one))
+
+	eq(t, true, rd.Set(nil).Equal(nil))
+	eq(t, true, rd.Set(nil).Equal(rd.Set{}))
+	eq(t, true, rd.Set{}.Equal(nil))
+	eq(t, true, one.Equal(set(`three`, `two`, `one`)))
+	eq(t, false, one.Equal(two))
+	eq(t, false, one.Equal(set(`one`, `two`)))
+	eq(t, false, one.Equal(nil))
+
+	t.Run(`inputs are not modified`, func(t *testing.T) {
+		one.Union(two).Add(`five`)
+		one.Intersect(two).Add(`five`)
+		one.Diff(two).Add(`five`)
+		eq(t, set(`one`, `two`, `three`), one)
+		eq(t, set(`two`, `three`, `four`), two)
+	})
+
+	t.Run(`reconciling fields`, func(t *testing.T) {
+		sent := rd.Json(testOuterJson).Set()
+		allowed := set(`embedStr`, `outerStr`, `other`)
+		eq(t, set(`embedNum`, `inner`), sent.Diff(allowed))
+		eq(t, set(`embedStr`, `outerStr`), sent.Intersect(allowed))
+	})
+}
+
+func TestForm_Haser(t *testing.T) {
+	val := rd.Form(testOuterQuery)
+	eq(t, val, val.Haser())
+}
+
+func TestForm_Set(t *testing.T) {
+	eq(t, testOuterQuerySet, rd.Form(testOuterQuery).Set())
+}
+
+func TestForm_Has(t *testing.T) {
+	haser := rd.Form(testOuterQuery)
+
+	eq(t, true, haser.Has(`embedStr`))
+	eq(t, true, haser.Has(`embedNum`))
+	eq(t, true, haser.Has(`outerStr`))
+	eq(t, false, haser.Has(``))
+	eq(t, false, haser.Has(`inner`))
+	eq(t, false, haser.Has(`innerStr`))
+	eq(t, false, haser.Has(`innerNum`))
+}
+
+func TestForm_HasAll(t *testing.T) {
+	src := rd.Form(testOuterQuery)
+
+	eq(t, true, src.HasAll())
+	eq(t, true, src.HasAll(`embedStr`))
+	eq(t, true, src.HasAll(`embedStr`, `embedNum`, `outerStr`))
+	eq(t, false, src.HasAll(`embedStr`, `inner`))
+	eq(t, false, src.HasAll(`inner`))
+	eq(t, false, rd.Form(nil).HasAll(`embedStr`))
+	eq(t, true, rd.Form(nil).HasAll())
+}
+
+func TestForm_HasAny(t *testing.T) {
+	src := rd.Form(testOuterQuery)
+
+	eq(t, false, src.HasAny())
+	eq(t, true, src.HasAny(`embedStr`))
+	eq(t, true, src.HasAny(`inner`, `outerStr`))
+	eq(t, false, src.HasAny(`inner`, `innerStr`))
+	eq(t, false, rd.Form(nil).HasAny(`embedStr`))
+}
+
+func TestForm_Value(t *testing.T) {
+	src := rd.Form{
+		`page`: {`3`, `4`},
+		`null`: {``},
+		`bool`: {`true`},
+		`dur`:  {`1m`},
+		`bad`:  {`one`},
+	}
+
+	t.Run(`scalar`, func(t *testing.T) {
+		page := 1
+		try(src.Value(`page`, &page))
+		eq(t, 3, page)
+
+		var dur time.Duration
+		try(src.Value(`dur`, &dur))
+		eq(t, time.Minute, dur)
+	})
+
+	t.Run(`missing`, func(t *testing.T) {
+		page := 1
+		try(src.Value(`missing`, &page))
+		eq(t, 1, page)
+	})
+
+	t.Run(`null`, func(t *testing.T) {
+		page := 1
+		try(src.Value(`null`, &page))
+		eq(t, 0, page)
+	})
+
+	t.Run(`pointer`, func(t *testing.T) {
+		var page *int
+		try(src.Value(`page`, &page))
+		eq(t, ptrInt(3), page)
+
+		try(src.Value(`null`, &page))
+		eq(t, (*int)(nil), page)
+	})
+
+	t.Run(`parser`, func(t *testing.T) {
+		var tar EmailParser
+		try(rd.Form{`email`: {`one@two`}}.Value(`email`, &tar))
+		eq(t, EmailParser(`one@two`), tar)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		page := 1
+		err := src.Value(`bad`, &page)
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to decode field "bad": failed to parse "one" into int`, err)
+
+		err = src.Value(`page`, page)
+		errStatus(t, http.StatusInternalServerError, err)
+		errs(t, `expected non-nil pointer, got int`, err)
+
+		errStatus(t, http.StatusInternalServerError, src.Value(`page`, (*int)(nil)))
+		errStatus(t, http.StatusInternalServerError, src.Value(`page`, nil))
+	})
+}
+
+func TestForm_Int(t *testing.T) {
+	src := rd.Form{`page`: {`3`}, `null`: {``}, `bad`: {`one`}}
+
+	test := func(exp int, key string) {
+		t.Helper()
+		val, err := src.Int(key)
+		try(err)
+		eq(t, exp, val)
+	}
+
+	test(3, `page`)
+	test(0, `null`)
+	test(0, `missing`)
+
+	_, err := src.Int(`bad`)
+	errs(t, `failed to decode field "bad": failed to parse "one" into int`, err)
+}
+
+func TestForm_Bool(t *testing.T) {
+	src := rd.Form{`one`: {`true`}, `two`: {`false`}, `null`: {``}, `bad`: {`on`}}
+
+	test := func(exp bool, key string) {
+		t.Helper()
+		val, err := src.Bool(key)
+		try(err)
+		eq(t, exp, val)
+	}
+
+	test(true, `one`)
+	test(false, `two`)
+	test(false, `null`)
+	test(false, `missing`)
+
+	_, err := src.Bool(`bad`)
+	errs(t, `failed to decode field "bad": failed to parse "on" into bool`, err)
+}
+
+// `rd.Json.Decode` delegates to `json.Unmarshal`.
+// We only need to verify that it does, in fact, unmarshal.
+func TestJson_Decode(t *testing.T) {
+	var tar Outer
+	try(rd.Json(testOuterJson).Decode(&tar))
+	eq(t, testOuter, tar)
+}
+
+func TestJson_Decode_errors(t *testing.T) {
+	t.Run(`syntax`, func(t *testing.T) {
+		test := func(src string, exp rd.JsonSyntaxError, msg string) {
+			t.Helper()
+
+			err := rd.Json(src).Decode(new(Outer))
+			errStatus(t, 400, err)
+			errs(t, msg, err)
+
+			var std *json.SyntaxError
+			if !errors.As(err, &std) {
+				t.Fatalf(`expected *json.SyntaxError, got %#v`, err)
+			}
+
+			var tar rd.JsonSyntaxError
+			if !errors.As(err, &tar) {
+				t.Fatalf(`expected rd.JsonSyntaxError, got %#v`, err)
+			}
+			tar.Cause = nil
+			eq(t, exp, tar)
+
+			errs(t, msg, rd.Json(src).DecodeWith(new(Outer), rd.Config{}))
+			errs(t, msg, rd.Json(src).DecodeWith(new(Outer), rd.Config{ZeroNull: true}))
+		}
+
+		test(
+			`{"outerStr": x}`,
+			rd.JsonSyntaxError{Pos: 13, Line: 1, Col: 14, Snippet: `x}`},
+			`invalid JSON syntax in position 13 (line 1, column 14): invalid character 'x' looking for beginning of value`,
+		)
+
+		test(
+			"{\n\t\"outerStr\": \"one\",\n}",
+			rd.JsonSyntaxError{Pos: 22, Line: 3, Col: 1, Snippet: `}`},
+			`invalid JSON syntax in position 22 (line 3, column 1): invalid character '}' looking for beginning of object key string`,
+		)
+
+		test(
+			`{"outerStr": `,
+			rd.JsonSyntaxError{Pos: 13, Line: 1, Col: 14},
+			`invalid JSON syntax in position 13 (line 1, column 14): unexpected end of JSON input`,
+		)
 
-	t.Run(`structs embedded by pointer`, func(t *testing.T) {
 		test(
+			``,
+			rd.JsonSyntaxError{Pos: 0, Line: 1, Col: 1},
+			`invalid JSON syntax in position 0 (line 1, column 1): unexpected end of JSON input`,
+		)
+	})
+
+	t.Run(`type`, func(t *testing.T) {
+		const msg = `invalid JSON value in position 47: json: cannot unmarshal string into Go struct field Outer.inner.innerNum of type int`
+		src := rd.Json(`{"outerStr": "one", "inner": {"innerNum": "two"}}`)
+
+		err := src.Decode(new(Outer))
+		errStatus(t, 400, err)
+		errs(t, msg, err)
+
+		var std *json.UnmarshalTypeError
+		if !errors.As(err, &std) {
+			t.Fatalf(`expected *json.UnmarshalTypeError, got %#v`, err)
+		}
+		eq(t, `inner.innerNum`, std.Field)
+
+		errs(t, msg, src.DecodeWith(new(Outer), rd.Config{}))
+	})
+}
+
+// Unlike `rd.Form`, `rd.Json` supports dynamically-typed fields.
+func TestJson_Decode_interface(t *testing.T) {
+	src := rd.Json(`{"any": {"one": [10, "two"]}, "raw": {"three": 30}, "str": "four"}`)
+
+	test := func(dec func(interface{}) error) {
+		t.Helper()
+
+		var tar TarDynamic
+		try(dec(&tar))
+		eq(
 			t,
-			testPtrOuterSimple,
-			PtrOuter{OuterStr: `outer val old`},
-			testOuterQuery,
+			TarDynamic{
+				Any: map[string]interface{}{`one`: []interface{}{10.0, `two`}},
+				Raw: json.RawMessage(`{"three": 30}`),
+				Str: `four`,
+			},
+			tar,
 		)
+	}
+
+	test(src.Decode)
+	test(func(out interface{}) error { return src.DecodeWith(out, rd.Config{Strict: true}) })
+
+	errs(
+		t,
+		`failed to decode field "any": failed to parse "10" into interface {}: dynamically-typed interface values are not supported`,
+		rd.Form{`any`: {`10`}}.Decode(new(TarDynamic)),
+	)
+
+	var tar TarDynamic
+	try(rd.Form{`str`: {`four`}}.Decode(&tar))
+	eq(t, TarDynamic{Str: `four`}, tar)
+}
+
+func TestJson_DecodeResidual(t *testing.T) {
+	test := func(exp, src string) {
+		t.Helper()
+		var tar Outer
+		res, err := rd.Json(src).DecodeResidual(&tar)
+		try(err)
+		eq(t, exp, string(res))
+	}
+
+	test(``, `{}`)
+	test(``, testOuterJson)
+	test(``, `{"OUTERSTR": "one", "inner": null}`)
+	test(``, `{"outer\u0053tr": "one"}`)
+	test(`{"one":"two"}`, `{"one": "two"}`)
+	test(
+		`{"one":{"two": [3, "}"]},"four":null,"five\"six":-1.5e3}`,
+		`{"one": {"two": [3, "}"]}, "embedStr": "embed val", "four": null, "five\"six": -1.5e3, "outerStr": "outer val"}`,
+	)
+
+	t.Run(`decodes known fields`, func(t *testing.T) {
+		var tar Outer
+		res, err := rd.Json(`{"outerStr": "one", "two": 3}`).DecodeResidual(&tar)
+		try(err)
+		eq(t, Outer{OuterStr: `one`}, tar)
+		eq(t, rd.Set{`two`: struct{}{}}, res.Set())
+	})
+
+	t.Run(`untagged fields`, func(t *testing.T) {
+		var tar struct {
+			One   string
+			Two   string `json:"-"`
+			Three string `json:",omitempty"`
+		}
+		res, err := rd.Json(`{"one": "1", "Two": "2", "three": "3"}`).DecodeResidual(&tar)
+		try(err)
+		eq(t, `{"Two":"2"}`, string(res))
+	})
+
+	t.Run(`non-struct output`, func(t *testing.T) {
+		var tar map[string]interface{}
+		res, err := rd.Json(`{"one": "two"}`).DecodeResidual(&tar)
+		try(err)
+		eq(t, rd.Json(nil), res)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		_, err := rd.Json(`{"one": }`).DecodeResidual(new(Outer))
+		errs(t, `invalid character`, err)
+	})
+}
+
+func TestJson_DecodeMulti(t *testing.T) {
+	decode := func(src string) ([]Outer, error) {
+		var out []Outer
+		err := rd.Json(src).DecodeMulti(func(src rd.Json) error {
+			var val Outer
+			err := src.Decode(&val)
+			out = append(out, val)
+			return err
+		})
+		return out, err
+	}
+
+	test := func(exp []Outer, src string) {
+		t.Helper()
+		out, err := decode(src)
+		try(err)
+		eq(t, exp, out)
+	}
+
+	test(nil, ``)
+	test(nil, " \n\t ")
+	test([]Outer{testOuter}, testOuterJson)
+	test([]Outer{{OuterStr: `one`}, {OuterStr: `two`}}, `{"outerStr": "one"}{"outerStr": "two"}`)
+	test(
+		[]Outer{{OuterStr: `one`}, {}, testOuter},
+		"{\"outerStr\": \"one\"}\n{}\n\t"+testOuterJson+"\n",
+	)
+
+	t.Run(`values`, func(t *testing.T) {
+		var out []string
+		try(rd.Json(`{"one":1} [2] "three"  null`).DecodeMulti(func(src rd.Json) error {
+			out = append(out, string(src))
+			return nil
+		}))
+		eq(t, []string{`{"one":1}`, `[2]`, `"three"`, `null`}, out)
+	})
+
+	t.Run(`callback error`, func(t *testing.T) {
+		var count int
+		err := rd.Json(`{} {} {}`).DecodeMulti(func(rd.Json) error {
+			count++
+			if count == 2 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		})
+		eq(t, io.ErrUnexpectedEOF, err)
+		eq(t, 2, count)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		out, err := decode(`{"outerStr": "one"} {"outerStr": }`)
+		errs(t, `invalid character`, err)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, []Outer{{OuterStr: `one`}}, out)
+
+		_, err = decode(`{"outerStr": "one"} {"outerStr"`)
+		errs(t, `unexpected EOF`, err)
+		errStatus(t, http.StatusBadRequest, err)
+	})
+}
+
+func TestForm_Decode(t *testing.T) {
+	test := func(t testing.TB, exp, tar interface{}, src url.Values) {
+		testDec(t, exp, tar, rd.Form(src))
+	}
+
+	// Field paths are cached with the field metadata, so no scratch buffers
+	// are needed.
+	t.Run(`no allocations`, func(t *testing.T) {
+		src := rd.Form(testOuterQuery)
+		var tar Outer
+		try(src.Decode(&tar))
+		eq(t, 0.0, testing.AllocsPerRun(16, func() { try(src.Decode(&tar)) }))
+	})
+
+	t.Run(`normal`, func(t *testing.T) {
+		test(t, TarUnusable{}, TarUnusable{}, unusableVals)
+		test(t, TarVoid{}, TarVoid{}, url.Values{})
+		test(t, TarVoid{}, TarVoid{}, url.Values{`one`: {`two`}})
+
+		for _, src := range nopQueries {
+			test(t, TarInt{10}, TarInt{10}, src)
+			test(t, TarPtrInt{ptrInt(10)}, TarPtrInt{ptrInt(10)}, src)
+			test(t, TarSliceInt{[]int{10, 20}}, TarSliceInt{[]int{10, 20}}, src)
+			test(t, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
+		}
+
+		for _, src := range zeroQueries {
+			test(t, TarInt{}, TarInt{10}, src)
+			test(t, TarPtrInt{}, TarPtrInt{ptrInt(10)}, src)
+			test(t, TarSliceInt{}, TarSliceInt{[]int{10, 20}}, src)
+			test(t, TarSlicePtrInt{}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
+		}
+
+		for i, src := range numQueries {
+			test(t, TarInt{20}, TarInt{10}, src)
+			test(t, TarPtrInt{ptrInt(20)}, TarPtrInt{ptrInt(10)}, src)
+			test(t, TarSliceInt{numOutputs[i]}, TarSliceInt{[]int{10, 20}}, src)
+			test(t, TarSlicePtrInt{numPtrOutputs[i]}, TarSlicePtrInt{[]*int{ptrInt(10), ptrInt(20)}}, src)
+		}
+	})
+
+	t.Run(`missing fields are unaffected`, func(t *testing.T) {
+		test(
+			t,
+			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
+			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
+			url.Values{`three`: {`50`, `60`}},
+		)
+
+		test(
+			t,
+			TarPair{One: []int{10, 20}, Two: []int{50, 60}},
+			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
+			url.Values{`two`: {`50`, `60`}},
+		)
+
+		test(
+			t,
+			TarPair{One: []int{50, 60}, Two: []int{30, 40}},
+			TarPair{One: []int{10, 20}, Two: []int{30, 40}},
+			url.Values{`one`: {`50`, `60`}},
+		)
+	})
+
+	t.Run(`structs embedded by value`, func(t *testing.T) {
+		test(
+			t,
+			testOuterSimple,
+			Outer{
+				Embed:    Embed{EmbedStr: `embed val old`, EmbedNum: 20},
+				OuterStr: `outer val old`,
+			},
+			testOuterQuery,
+		)
+	})
+
+	t.Run(`structs embedded by pointer`, func(t *testing.T) {
+		test(
+			t,
+			testPtrOuterSimple,
+			PtrOuter{OuterStr: `outer val old`},
+			testOuterQuery,
+		)
+	})
+
+	t.Run(`structs embedded by pre-populated pointer`, func(t *testing.T) {
+		embed := &Embed{EmbedStr: `embed val old`, EmbedNum: 10}
+		tar := PtrOuter{Embed: embed, OuterStr: `outer val`}
+
+		try(rd.Form{`embedStr`: {``}}.Decode(&tar))
+		eq(t, PtrOuter{Embed: &Embed{EmbedNum: 10}, OuterStr: `outer val`}, tar)
+		eq(t, true, embed == tar.Embed)
+
+		try(rd.Form{`embedNum`: nil, `embedStr`: {`embed val`}}.Decode(&tar))
+		eq(t, PtrOuter{Embed: &Embed{EmbedStr: `embed val`}, OuterStr: `outer val`}, tar)
+		eq(t, true, embed == tar.Embed)
+
+		try(rd.Json(`{"embedNum": null}`).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+		eq(t, PtrOuter{Embed: &Embed{EmbedStr: `embed val`}, OuterStr: `outer val`}, tar)
+		eq(t, true, embed == tar.Embed)
+	})
+
+	t.Run(`nulls don't allocate embedded pointers`, func(t *testing.T) {
+		var tar PtrOuter
+		try(rd.Form{`embedStr`: {``}, `embedNum`: nil}.Decode(&tar))
+		eq(t, PtrOuter{}, tar)
+
+		try(rd.Json(`{"embedStr": null}`).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+		eq(t, PtrOuter{}, tar)
+	})
+
+	t.Run(`invokes SliceParser`, func(t *testing.T) {
+		type T struct {
+			One   SliceParserStruct `json:"one"`
+			Two   SliceParserStruct `json:"two"`
+			Three SliceParserStruct `json:"three"`
+		}
+
+		test(
+			t,
+			T{
+				One:   SliceParserStruct{[]int{70, 80}},
+				Two:   SliceParserStruct{[]int{90, 100}},
+				Three: SliceParserStruct{[]int{50, 60}},
+			},
+			T{
+				One:   SliceParserStruct{[]int{10, 20}},
+				Two:   SliceParserStruct{[]int{30, 40}},
+				Three: SliceParserStruct{[]int{50, 60}},
+			},
+			url.Values{
+				`one`: {`70`, `80`},
+				`two`: {`90`, `100`},
+			},
+		)
+	})
+}
+
+func testDec(t testing.TB, exp, tar interface{}, dec rd.Dec) {
+	t.Helper()
+
+	ptr := r.New(r.TypeOf(tar))
+	ptr.Elem().Set(r.ValueOf(tar))
+
+	try(dec.Decode(ptr.Interface()))
+	eq(t, exp, ptr.Elem().Interface())
+}
+
+func TestForm_Parse(t *testing.T) {
+	var tar rd.Form
+	try(tar.Parse(testOuterQuery.Encode()))
+	eq(t, url.Values(tar), testOuterQuery)
+}
+
+func TestDecode_GET_query(t *testing.T) {
+	req := Req{}.Query(testOuterQuery).Ptr()
+
+	var tar Outer
+	rd.TryDecode(req, &tar)
+
+	eq(t, testOuterSimple, tar)
+}
+
+func TestDecode_GET_json(t *testing.T) {
+	req := Req{}.BodyJson(testOuterJson).Ptr()
+
+	var tar Outer
+	rd.TryDecode(req, &tar)
+
+	eq(t, testOuter, tar)
+}
+
+func TestDecode_POST_json(t *testing.T) {
+	req := Req{}.Post().BodyJson(testOuterJson).Ptr()
+
+	var tar Outer
+	rd.TryDecode(req, &tar)
+
+	eq(t, testOuter, tar)
+}
+
+func TestDecode_POST_form(t *testing.T) {
+	req := Req{}.Post().Query(testUrlQuery).BodyForm(testOuterQuery).Ptr()
+
+	var tar Outer
+	rd.TryDecode(req, &tar)
+
+	eq(t, testOuterSimple, tar)
+}
+
+func TestDecode_POST_multi(t *testing.T) {
+	req := Req{}.Post().Query(testUrlQuery).BodyMulti(testOuterQuery).Ptr()
+
+	var tar Outer
+	rd.TryDecode(req, &tar)
+
+	eq(t, testOuterSimple, tar)
+}
+
+func TestForm_DecodeRequest(t *testing.T) {
+	test := func(req *http.Request) {
+		t.Helper()
+		var form rd.Form
+		var tar Outer
+		try(form.DecodeRequest(req, &tar))
+		eq(t, testOuterSimple, tar)
+		eq(t, `outer val`, url.Values(form).Get(`outerStr`))
+	}
+
+	test(Req{}.Post().BodyForm(testOuterQuery).Ptr())
+	test(Req{}.Post().BodyMulti(testOuterQuery).Ptr())
+	test(Req{}.Post().Encoding(`gzip`).TypeForm().BodyString(gzipString(testOuterQuery.Encode())).Ptr())
+
+	t.Run(`nil request`, func(t *testing.T) {
+		form := rd.Form{`one`: {`two`}}
+		tar := testOuter
+		try(form.DecodeRequest(nil, &tar))
+		eq(t, rd.Form{}, form)
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`other content types`, func(t *testing.T) {
+		var form rd.Form
+
+		err := form.DecodeRequest(Req{}.Post().BodyJson(testOuterJson).Ptr(), new(Outer))
+		errStatus(t, http.StatusUnsupportedMediaType, err)
+		errs(t, `unsupported content type "application/json"`, err)
+
+		err = form.DecodeRequest(Req{}.Query(testOuterQuery).Ptr(), new(Outer))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `missing content type`, err)
+	})
+
+	t.Run(`decoding error`, func(t *testing.T) {
+		var form rd.Form
+		err := form.DecodeRequest(Req{}.Post().BodyForm(url.Values{`embedNum`: {`one`}}).Ptr(), new(Outer))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to decode field "embedNum"`, err)
+	})
+}
+
+func TestDecodeFields(t *testing.T) {
+	test := func(expTar Outer, expSet rd.Set, req *http.Request) {
+		t.Helper()
+		var tar Outer
+		tracked, err := rd.DecodeFields(req, &tar)
+		try(err)
+		eq(t, expTar, tar)
+		eq(t, expSet, tracked)
+	}
+
+	test(Outer{}, nil, nil)
+	test(Outer{}, nil, Req{}.Ptr())
+	test(Outer{}, nil, Req{}.Post().BodyJson(`{"unknown": 10}`).Ptr())
+
+	test(
+		testOuter,
+		set(`embedStr`, `embedNum`, `inner`, `outerStr`),
+		Req{}.Post().BodyJson(testOuterJson).Ptr(),
+	)
+
+	test(
+		testOuterSimple,
+		set(`embedStr`, `embedNum`, `outerStr`),
+		Req{}.Post().BodyForm(testOuterQuery).Ptr(),
+	)
+
+	test(
+		testOuterSimple,
+		set(`embedStr`, `embedNum`, `outerStr`),
+		Req{}.Query(testOuterQuery).Ptr(),
+	)
+
+	t.Run(`nested form fields`, func(t *testing.T) {
+		test(
+			Outer{OuterStr: `one`, Inner: Inner{InnerStr: `two`, InnerNum: 10}},
+			set(`inner`, `outerStr`),
+			Req{}.Post().BodyForm(url.Values{
+				`outerStr`:       {`one`},
+				`inner.innerStr`: {`two`},
+				`inner.innerNum`: {`10`},
+			}).Ptr(),
+		)
+	})
+
+	t.Run(`zero values are present`, func(t *testing.T) {
+		test(
+			Outer{},
+			set(`embedNum`, `outerStr`),
+			Req{}.Post().BodyJson(`{"embedNum": 0, "outerStr": null, "unknown": 10}`).Ptr(),
+		)
+	})
+
+	t.Run(`case-insensitive`, func(t *testing.T) {
+		test(
+			Outer{OuterStr: `one`, Embed: Embed{EmbedNum: 10}},
+			set(`embedNum`, `outerStr`),
+			Req{}.Post().BodyJson(`{"EMBEDNUM": 10, "outerstr": "one"}`).Ptr(),
+		)
+	})
+
+	t.Run(`non-struct output`, func(t *testing.T) {
+		var tar map[string]string
+		tracked, err := rd.DecodeFields(Req{}.Post().BodyJson(`{"one": "two"}`).Ptr(), &tar)
+		try(err)
+		eq(t, map[string]string{`one`: `two`}, tar)
+		eq(t, rd.Set(nil), tracked)
+	})
+
+	t.Run(`error`, func(t *testing.T) {
+		var tar Outer
+		tracked, err := rd.DecodeFields(Req{}.Post().BodyJson(`{"embedNum": "one"}`).Ptr(), &tar)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, rd.Set(nil), tracked)
+	})
+}
+
+func TestDecode_bom(t *testing.T) {
+	const bom = "\ufeff"
+
+	t.Run(`json`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Decode(Req{}.Post().BodyJson(bom+testOuterJson).Ptr(), &tar))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`json buffered`, func(t *testing.T) {
+		var tar Outer
+		try(rd.DecodeWith(Req{}.Post().BodyJson(bom+testOuterJson).Ptr(), &tar, rd.Config{Strict: true}))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`json download`, func(t *testing.T) {
+		var dec rd.Json
+		try(dec.Download(Req{}.Post().BodyJson(bom + `{"outerStr": "one"}`).Ptr()))
+		eq(t, `{"outerStr": "one"}`, string(dec))
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		req := Req{}.Post().TypeForm().BodyString(bom + testOuterQuery.Encode()).Ptr()
+
+		var tar Outer
+		try(rd.Decode(req, &tar))
+		eq(t, testOuterSimple, tar)
+	})
+
+	t.Run(`only leading`, func(t *testing.T) {
+		var dec rd.Json
+		try(dec.Download(Req{}.Post().BodyJson(bom + bom + `"one"`).Ptr()))
+		eq(t, bom+`"one"`, string(dec))
+
+		errs(t, `invalid character`, rd.Decode(Req{}.Post().BodyJson(` `+bom+`{}`).Ptr(), new(Outer)))
+
+		var form rd.Form
+		try(form.DownloadForm(Req{}.Post().TypeForm().BodyString(`outerStr=one` + bom).Ptr()))
+		eq(t, rd.Form{`outerStr`: {`one` + bom}}, form)
+	})
+
+	t.Run(`short body`, func(t *testing.T) {
+		var form rd.Form
+		try(form.DownloadForm(Req{}.Post().TypeForm().BodyString(`a=`).Ptr()))
+		eq(t, rd.Form{`a`: {``}}, form)
+
+		errs(t, `EOF`, rd.Decode(Req{}.Post().BodyJson(bom).Ptr(), new(Outer)))
+	})
+
+	t.Run(`validate`, func(t *testing.T) {
+		try(rd.Validate(Req{}.Post().BodyJson(bom + `{}`).Ptr()))
+	})
+}
+
+func TestDecodeTyped(t *testing.T) {
+	tar, err := rd.DecodeTyped[Outer](Req{}.Post().BodyJson(testOuterJson).Ptr())
+	try(err)
+	eq(t, testOuter, tar)
+
+	ptr, err := rd.DecodeTyped[*Outer](Req{}.Post().BodyJson(testOuterJson).Ptr())
+	try(err)
+	eq(t, &testOuter, ptr)
+
+	tar, err = rd.DecodeTyped[Outer](Req{}.Post().BodyJson(`{"outerStr": "one", "embedNum": "two"}`).Ptr())
+	errs(t, `cannot unmarshal string`, err)
+	eq(t, Outer{}, tar)
+}
+
+func TestDownload_GET_query(t *testing.T) {
+	req := Req{}.Query(testOuterQuery).Ptr()
+	eq(t, rd.Form(req.URL.Query()), rd.TryDownload(req))
+}
+
+func TestDownload_POST_query(t *testing.T) {
+	req := Req{}.Post().Query(testOuterQuery).Ptr()
+	eq(t, rd.Form(req.URL.Query()), rd.TryDownload(req))
+}
+
+func TestDownload_POST_json(t *testing.T) {
+	req := Req{}.Post().Query(testUrlQuery).BodyJson(testJsonStr).Ptr()
+	eq(t, rd.Json(testJsonStr), rd.TryDownload(req))
+}
+
+func TestDownload_POST_form(t *testing.T) {
+	req := Req{}.Post().Query(testUrlQuery).BodyForm(testBodyQuery).Ptr()
+	eq(t, rd.Form(testBodyQuery), rd.TryDownload(req))
+}
+
+func TestDownload_POST_multi(t *testing.T) {
+	req := Req{}.Post().Query(testUrlQuery).BodyMulti(testBodyQuery).Ptr()
+	eq(t, rd.Form(testBodyQuery), rd.TryDownload(req))
+}
+
+func TestDownloadMerged(t *testing.T) {
+	query := url.Values{`outerStr`: {`query`}, `embedStr`: {`query`}}
+	body := `{"outerStr": "body", "embedNum": 10}`
+
+	test := func(exp Outer, prec rd.Precedence) {
+		t.Helper()
+
+		dec, err := rd.DownloadMerged(Req{}.Post().Query(query).BodyJson(body).Ptr(), prec)
+		try(err)
+
+		var tar Outer
+		try(dec.Decode(&tar))
+		eq(t, exp, tar)
+
+		eq(t, set(`outerStr`, `embedStr`, `embedNum`), dec.Set())
+		eq(t, true, dec.Haser().Has(`embedStr`))
+		eq(t, true, dec.Haser().Has(`embedNum`))
+		eq(t, false, dec.Haser().Has(`inner`))
+	}
+
+	test(Outer{Embed: Embed{EmbedStr: `query`, EmbedNum: 10}, OuterStr: `body`}, rd.BodyWins)
+	test(Outer{Embed: Embed{EmbedStr: `query`, EmbedNum: 10}, OuterStr: `query`}, rd.QueryWins)
+
+	t.Run(`form body`, func(t *testing.T) {
+		req := Req{}.Post().Query(query).BodyForm(url.Values{`outerStr`: {`body`}}).Ptr()
+		dec, err := rd.DownloadMerged(req, rd.BodyWins)
+		try(err)
+
+		var tar Outer
+		try(dec.Decode(&tar))
+		eq(t, Outer{Embed: Embed{EmbedStr: `query`}, OuterStr: `body`}, tar)
+	})
+
+	t.Run(`without body`, func(t *testing.T) {
+		req := Req{}.Query(query).Ptr()
+		dec, err := rd.DownloadMerged(req, rd.BodyWins)
+		try(err)
+		eq(t, rd.Form(query), dec)
+	})
+
+	t.Run(`non-struct output with empty query`, func(t *testing.T) {
+		dec, err := rd.DownloadMerged(Req{}.Post().BodyJson(`[10, 20]`).Ptr(), rd.QueryWins)
+		try(err)
+
+		var tar []int
+		try(dec.Decode(&tar))
+		eq(t, []int{10, 20}, tar)
+	})
+
+	t.Run(`defaults`, func(t *testing.T) {
+		type Tar struct {
+			Page int    `json:"page" default:"1"`
+			Sort string `json:"sort" default:"id"`
+		}
+
+		test := func(exp Tar, req *http.Request, prec rd.Precedence) {
+			t.Helper()
+
+			dec, err := rd.DownloadMerged(req, prec)
+			try(err)
+
+			var tar Tar
+			try(dec.Decode(&tar))
+			eq(t, exp, tar)
+		}
+
+		for _, prec := range []rd.Precedence{rd.BodyWins, rd.QueryWins} {
+			test(
+				Tar{Page: 7, Sort: `id`},
+				Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{"page": 7}`).Ptr(),
+				prec,
+			)
+			test(
+				Tar{Page: 7, Sort: `name`},
+				Req{}.Post().Query(url.Values{`sort`: {`name`}}).BodyJson(`{"Page": 7}`).Ptr(),
+				prec,
+			)
+			test(
+				Tar{Page: 5, Sort: `id`},
+				Req{}.Post().Query(url.Values{`page`: {`5`}}).BodyForm(url.Values{`other`: {`one`}}).Ptr(),
+				prec,
+			)
+			test(
+				Tar{Page: 1, Sort: `id`},
+				Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{}`).Ptr(),
+				prec,
+			)
+		}
+
+		test(
+			Tar{Page: 5, Sort: `id`},
+			Req{}.Post().Query(url.Values{`page`: {`5`}}).BodyForm(url.Values{`page`: {`7`}}).Ptr(),
+			rd.QueryWins,
+		)
+		test(
+			Tar{Page: 7, Sort: `id`},
+			Req{}.Post().Query(url.Values{`page`: {`5`}}).BodyForm(url.Values{`page`: {`7`}}).Ptr(),
+			rd.BodyWins,
+		)
+	})
+
+	t.Run(`required`, func(t *testing.T) {
+		type Tar struct {
+			Name string `json:"name,required"`
+		}
+
+		test := func(exp Tar, req *http.Request) {
+			t.Helper()
+
+			dec, err := rd.DownloadMerged(req, rd.BodyWins)
+			try(err)
+
+			var tar Tar
+			try(dec.Decode(&tar))
+			eq(t, exp, tar)
+		}
+
+		test(Tar{Name: `one`}, Req{}.Post().Query(url.Values{`name`: {`one`}}).BodyForm(url.Values{`other`: {`two`}}).Ptr())
+		test(Tar{Name: `one`}, Req{}.Post().Query(url.Values{`name`: {`one`}}).BodyJson(`{}`).Ptr())
+		test(Tar{Name: `two`}, Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{"name": "two"}`).Ptr())
+
+		dec, err := rd.DownloadMerged(Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{}`).Ptr(), rd.BodyWins)
+		try(err)
+		errs(t, `missing required field`, dec.Decode(new(Tar)))
+
+		dec, err = rd.DownloadMerged(Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyForm(url.Values{`other`: {`two`}}).Ptr(), rd.QueryWins)
+		try(err)
+		errs(t, `missing required field`, dec.Decode(new(Tar)))
+
+		t.Run(`nested`, func(t *testing.T) {
+			type Tar struct {
+				Inner struct {
+					Str string `json:"str,required"`
+				} `json:"inner"`
+			}
+
+			dec, err := rd.DownloadMerged(Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{"inner": {"str": "two"}}`).Ptr(), rd.BodyWins)
+			try(err)
+
+			var tar Tar
+			try(dec.Decode(&tar))
+			eq(t, `two`, tar.Inner.Str)
+
+			dec, err = rd.DownloadMerged(Req{}.Post().Query(url.Values{`other`: {`one`}}).BodyJson(`{}`).Ptr(), rd.BodyWins)
+			try(err)
+			errs(t, `missing required field`, dec.Decode(new(Tar)))
+		})
+	})
+
+	t.Run(`errors`, func(t *testing.T) {
+		req := Req{}.Post().Query(url.Values{`embedNum`: {`one`}}).BodyJson(body).Ptr()
+		dec, err := rd.DownloadMerged(req, rd.QueryWins)
+		try(err)
+		errs(t, `failed to decode field "embedNum"`, dec.Decode(new(Outer)))
+
+		_, err = rd.DownloadMerged(Req{}.Post().Type(`text/plain`).BodyString(`one`).Ptr(), rd.BodyWins)
+		errStatus(t, http.StatusUnsupportedMediaType, err)
+	})
+}
+
+func TestDecode_content_encoding(t *testing.T) {
+	test := func(req *http.Request) {
+		t.Helper()
+		var tar Outer
+		try(rd.Decode(req, &tar))
+		eq(t, testOuter, tar)
+		eq(t, ``, req.Header.Get(`Content-Encoding`))
+	}
+
+	test(Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(testOuterJson)).Ptr())
+	test(Req{}.Post().Encoding(`X-Gzip`).BodyJson(gzipString(testOuterJson)).Ptr())
+	test(Req{}.Post().Encoding(`deflate`).BodyJson(zlibString(testOuterJson)).Ptr())
+	test(Req{}.Post().Encoding(`deflate`).BodyJson(flateString(testOuterJson)).Ptr())
+	test(Req{}.Post().Encoding(`identity`).BodyJson(testOuterJson).Ptr())
+	test(Req{}.Post().Encoding(`deflate, gzip`).BodyJson(gzipString(zlibString(testOuterJson))).Ptr())
+
+	t.Run(`form`, func(t *testing.T) {
+		req := Req{}.Post().Encoding(`gzip`).TypeForm().BodyString(gzipString(testOuterQuery.Encode())).Ptr()
+		var tar Outer
+		try(rd.Decode(req, &tar))
+		eq(t, testOuterSimple, tar)
+	})
+
+	t.Run(`download`, func(t *testing.T) {
+		req := Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(testOuterJson)).Ptr()
+		eq(t, rd.Json(testOuterJson), rd.TryDownload(req))
+	})
+
+	t.Run(`validate`, func(t *testing.T) {
+		req := Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(testOuterJson)).Ptr()
+		try(rd.Validate(req))
+
+		var tar Outer
+		try(rd.Decode(req, &tar))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`empty`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(``).Ptr(), &tar))
+		eq(t, Outer{}, tar)
+	})
+
+	t.Run(`limit applies to decompressed body`, func(t *testing.T) {
+		src := `{"outerStr": "` + strings.Repeat(`a`, 1024) + `"}`
+		req := Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(src)).Ptr()
+		err := rd.DecodeWith(req, new(Outer), rd.Config{JsonLimit: 128})
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+	})
+
+	t.Run(`decompression limit`, func(t *testing.T) {
+		bomb := gzipString(`{"outerStr": "` + strings.Repeat(`a`, int(rd.DecompressLimit)) + `"}`)
+		err := rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(bomb).Ptr(), new(Outer))
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+		errs(t, `decompressed request body exceeds the limit`, err)
+
+		defer func(prev int64) { rd.DecompressLimit = prev }(rd.DecompressLimit)
+		rd.DecompressLimit = 1024
+
+		test := func(req *http.Request) {
+			t.Helper()
+			err := rd.Decode(req, new(Outer))
+			errStatus(t, http.StatusRequestEntityTooLarge, err)
+			errs(t, `decompressed request body exceeds the limit of 1024 bytes`, err)
+		}
+
+		large := strings.Repeat(`a`, 2048)
+		vals := url.Values{`outerStr`: {large}}
+
+		test(Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(`{"outerStr": "` + large + `"}`)).Ptr())
+		test(Req{}.Post().Encoding(`gzip`).TypeForm().BodyString(gzipString(vals.Encode())).Ptr())
+
+		typ, body := queryToMultipart(vals)
+		multi, err := io.ReadAll(body)
+		try(err)
+		test(Req{}.Post().Encoding(`gzip`).Type(typ).BodyString(gzipString(string(multi))).Ptr())
+
+		_, err = rd.Download(Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(`{"outerStr": "` + large + `"}`)).Ptr())
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+
+		req := Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(`{"outerStr": "` + large + `"}`)).Ptr()
+		try(rd.Validate(req))
+		test(req)
+
+		var tar Outer
+		try(rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(testOuterJson)).Ptr(), &tar))
+		eq(t, testOuter, tar)
+
+		rd.DecompressLimit = 0
+		try(rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(gzipString(`{"outerStr": "`+large+`"}`)).Ptr(), new(Outer)))
+	})
+
+	t.Run(`unsupported`, func(t *testing.T) {
+		err := rd.Decode(Req{}.Post().Encoding(`br`).BodyJson(testOuterJson).Ptr(), new(Outer))
+		errStatus(t, http.StatusUnsupportedMediaType, err)
+		errs(t, `unsupported content encoding "br"`, err)
+	})
+
+	t.Run(`malformed`, func(t *testing.T) {
+		err := rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(testOuterJson).Ptr(), new(Outer))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to decompress request body with content encoding "gzip"`, err)
+
+		src := gzipString(testOuterJson)
+		err = rd.Decode(Req{}.Post().Encoding(`gzip`).BodyJson(src[:len(src)/2]).Ptr(), new(Outer))
+		errStatus(t, http.StatusBadRequest, err)
+	})
+}
+
+func TestDecodeFormReader(t *testing.T) {
+	type Tar struct {
+		Outer
+		List []int             `json:"list"`
+		Meta map[string]string `json:"meta"`
+	}
+
+	// Must match `url.ParseQuery` followed by `rd.Form.DecodeWith`.
+	test := func(src string, conf rd.Config) {
+		t.Helper()
+
+		var exp Tar
+		vals, expErr := url.ParseQuery(src)
+		if expErr == nil {
+			expErr = rd.Form(vals).DecodeWith(&exp, conf)
+		}
+
+		var tar Tar
+		err := rd.DecodeFormReader(strings.NewReader(src), &tar, conf)
+		eq(t, expErr == nil, err == nil)
+		eq(t, exp, tar)
+	}
+
+	for _, conf := range []rd.Config{{}, {Strict: true}, {LowercaseKeys: true}} {
+		test(``, conf)
+		test(`&&`, conf)
+		test(testOuterQuery.Encode(), conf)
+		test(`outerStr=one&unknown=two&embedNum=3`, conf)
+		test(`outerStr=one&outerStr=two`, conf)
+		test(`outerStr&embedStr=&embedNum=`, conf)
+		test(`outerStr=one+two%20three&inner.innerStr=%E6%97%A5`, conf)
+		test(`list=1&list=2&list[]=3&list[0]=4`, conf)
+		test(`meta[one]=two&meta[three]=four`, conf)
+		test(`OuterStr=one&EMBEDNUM=2`, conf)
+		test(`embedNum=one`, conf)
+		test(`outerStr=%zz`, conf)
+		test(`unknown=%zz`, conf)
+		test(`unknown%zz=one`, conf)
+		test(`outerStr=one;two`, conf)
+		test(`unknown=one;two`, conf)
+		test(`unknown=%2`, conf)
+	}
+
+	t.Run(`skipped values are not kept`, func(t *testing.T) {
+		large := strings.Repeat(`a`, 9<<20)
+
+		var tar Outer
+		try(rd.DecodeFormReader(strings.NewReader(`unknown=`+large+`&outerStr=one`), &tar, rd.Config{}))
+		eq(t, Outer{OuterStr: `one`}, tar)
+	})
+
+	t.Run(`limits`, func(t *testing.T) {
+		huge := strings.Repeat(`a`, 11<<20)
+
+		test := func(src string) {
+			t.Helper()
+			err := rd.DecodeFormReader(strings.NewReader(src), new(Outer), rd.Config{})
+			errStatus(t, http.StatusRequestEntityTooLarge, err)
+		}
+
+		test(`outerStr=` + huge)
+		test(`unknown=` + huge + `&outerStr=one`)
+		test(`outerStr=one&` + huge)
+		test(`outerStr=one&` + huge[:64<<10+1] + `=two`)
+
+		try(rd.DecodeFormReader(strings.NewReader(`outerStr=one&`+huge[:64<<10]+`=two`), new(Outer), rd.Config{}))
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar Outer
+		err := rd.DecodeFormReader(strings.NewReader(`outerStr=one&unknown=two`), &tar, rd.Config{Strict: true})
+		errs(t, `unknown fields ["unknown"]`, err)
+	})
+
+	t.Run(`querystring`, func(t *testing.T) {
+		var tar struct {
+			Outer
+			Rest url.Values `rd:"querystring"`
+		}
+		try(rd.DecodeFormReader(strings.NewReader(`outerStr=one&two=three`), &tar, rd.Config{}))
+		eq(t, `one`, tar.OuterStr)
+		eq(t, url.Values{`two`: {`three`}}, tar.Rest)
+	})
+
+	t.Run(`bom`, func(t *testing.T) {
+		var tar Outer
+		try(rd.DecodeFormReader(strings.NewReader("\uFEFFouterStr=one"), &tar, rd.Config{}))
+		eq(t, Outer{OuterStr: `one`}, tar)
+	})
+
+	t.Run(`invalid output`, func(t *testing.T) {
+		err := rd.DecodeFormReader(strings.NewReader(`outerStr=one`), Outer{}, rd.Config{})
+		errStatus(t, http.StatusInternalServerError, err)
+	})
+
+	t.Run(`DecodeWith`, func(t *testing.T) {
+		req := Req{}.Post().BodyForm(testOuterQuery).Ptr()
+		var tar Outer
+		try(rd.DecodeWith(req, &tar, rd.Config{StreamForm: true}))
+		eq(t, testOuterSimple, tar)
+		eq(t, url.Values(nil), req.PostForm)
+	})
+}
+
+func TestDownloadContext(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run(`complete`, func(t *testing.T) {
+		dec, err := rd.DownloadContext(ctx, Req{}.Post().BodyJson(testJsonStr).Ptr())
+		try(err)
+		eq(t, rd.Json(testJsonStr), dec)
+
+		dec, err = rd.DownloadContext(ctx, Req{}.Post().BodyForm(testBodyQuery).Ptr())
+		try(err)
+		eq(t, rd.Form(testBodyQuery), dec)
+
+		_, err = rd.DownloadContext(ctx, nil)
+		try(err)
+	})
+
+	t.Run(`cancel`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		body := newStallBody(`{"outerStr": `)
+		req := Req{}.Post().TypeJson().BodyReadCloser(body).Ptr()
+
+		time.AfterFunc(time.Millisecond*10, cancel)
+		_, err := rd.DownloadContext(ctx, req)
+
+		errStatus(t, http.StatusRequestTimeout, err)
+		eq(t, true, errors.Is(err, context.Canceled))
+		eq(t, true, body.Closed())
+		eq(t, io.ReadCloser(body), req.Body)
+	})
+
+	t.Run(`deadline`, func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+
+		_, err := rd.DownloadContext(ctx, Req{}.Post().TypeForm().BodyReadCloser(newStallBody(`outerStr=one&`)).Ptr())
+		errStatus(t, http.StatusRequestTimeout, err)
+		eq(t, true, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run(`done before start`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := rd.DownloadContext(ctx, Req{}.Post().BodyJson(testJsonStr).Ptr())
+		errStatus(t, http.StatusRequestTimeout, err)
+	})
+
+	t.Run(`json`, func(t *testing.T) {
+		var dec rd.Json
+		try(dec.DownloadContext(ctx, Req{}.Post().BodyJson(testJsonStr).Ptr()))
+		eq(t, rd.Json(testJsonStr), dec)
+
+		ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+
+		err := dec.DownloadContext(ctx, Req{}.Post().TypeJson().BodyReadCloser(newStallBody(`[`)).Ptr())
+		errStatus(t, http.StatusRequestTimeout, err)
+		eq(t, true, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func TestJson_DownloadLimited(t *testing.T) {
+	const src = `{"outerStr": "one"}`
+	req := func() *http.Request { return Req{}.Post().BodyJson(src).Ptr() }
+
+	t.Run(`within limit`, func(t *testing.T) {
+		var dec rd.Json
+		try(dec.DownloadLimited(req(), int64(len(src))))
+		eq(t, src, string(dec))
+
+		try(dec.DownloadLimited(req(), 0))
+		eq(t, src, string(dec))
+	})
+
+	t.Run(`over limit`, func(t *testing.T) {
+		err := new(rd.Json).DownloadLimited(req(), int64(len(src))-1)
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+		errs(t, `request body exceeds the limit of 18 bytes`, err)
+	})
+
+	t.Run(`package default`, func(t *testing.T) {
+		defer func(val int64) { rd.JsonLimit = val }(rd.JsonLimit)
+		rd.JsonLimit = 8
+
+		errStatus(t, http.StatusRequestEntityTooLarge, new(rd.Json).Download(req()))
+
+		_, err := rd.Download(req())
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+
+		errStatus(t, http.StatusRequestEntityTooLarge, rd.Decode(req(), new(Outer)))
+
+		var tar Outer
+		try(rd.DecodeWith(req(), &tar, rd.Config{JsonLimit: 64}))
+		eq(t, `one`, tar.OuterStr)
+	})
+
+	t.Run(`config`, func(t *testing.T) {
+		conf := rd.Config{JsonLimit: 8}
+		errStatus(t, http.StatusRequestEntityTooLarge, rd.DecodeWith(req(), new(Outer), conf))
+
+		_, err := rd.DownloadWith(req(), conf)
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+
+		dec, err := rd.DownloadWith(req(), rd.Config{JsonLimit: 64})
+		try(err)
+		eq(t, rd.Json(src), dec)
+	})
+}
+
+func TestDownloadWith_MaxMultipartMem(t *testing.T) {
+	content := strings.Repeat(`x`, 1024)
+	req := func() *http.Request {
+		typ, body := multipartWithFile(`outerStr`, `one`, `file`, content)
+		return Req{}.Post().Type(typ).BodyReader(body).Ptr()
+	}
+
+	// Files which don't fit into memory are stored on disk.
+	onDisk := func(req *http.Request) bool {
+		file, err := req.MultipartForm.File[`file`][0].Open()
+		try(err)
+		defer file.Close()
+
+		buf, err := io.ReadAll(file)
+		try(err)
+		eq(t, content, string(buf))
+
+		_, ok := file.(*os.File)
+		return ok
+	}
+
+	t.Run(`default`, func(t *testing.T) {
+		req := req()
+		dec, err := rd.DownloadWith(req, rd.Config{})
+		try(err)
+		eq(t, rd.Form{`outerStr`: {`one`}}, dec)
+		eq(t, false, onDisk(req))
+		try(req.MultipartForm.RemoveAll())
+	})
+
+	t.Run(`download`, func(t *testing.T) {
+		req := req()
+		dec, err := rd.DownloadWith(req, rd.Config{MaxMultipartMem: 16})
+		try(err)
+		eq(t, rd.Form{`outerStr`: {`one`}}, dec)
+		eq(t, true, onDisk(req))
+		try(req.MultipartForm.RemoveAll())
+	})
+
+	t.Run(`decode`, func(t *testing.T) {
+		req := req()
+		var tar Outer
+		try(rd.DecodeWith(req, &tar, rd.Config{MaxMultipartMem: 16}))
+		eq(t, `one`, tar.OuterStr)
+		eq(t, true, onDisk(req))
+		try(req.MultipartForm.RemoveAll())
+	})
+}
+
+func TestDecode_ndjson(t *testing.T) {
+	req := func(src string) *http.Request {
+		return Req{}.Post().Type(rd.TypeNdjson).BodyString(src).Ptr()
+	}
+
+	const src = "{\"outerStr\": \"one\"}\n{\"outerStr\": \"two\", \"embedNum\": 10}\n\n{\"inner\": {\"innerStr\": \"three\"}}\n"
+	exp := []Outer{
+		{OuterStr: `one`},
+		{OuterStr: `two`, Embed: Embed{EmbedNum: 10}},
+		{Inner: Inner{InnerStr: `three`}},
+	}
+
+	t.Run(`records`, func(t *testing.T) {
+		tar := []Outer{{OuterStr: `prev`}}
+		try(rd.Decode(req(src), &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`pointers`, func(t *testing.T) {
+		var tar []*Outer
+		try(rd.Decode(req(src), &tar))
+		eq(t, 3, len(tar))
+		eq(t, exp[1], *tar[1])
+	})
+
+	t.Run(`limit`, func(t *testing.T) {
+		var tar []Outer
+		try(rd.DecodeWith(req(src), &tar, rd.Config{JsonLimit: 1024}))
+		eq(t, exp, tar)
+
+		errStatus(t, http.StatusRequestEntityTooLarge, rd.DecodeWith(req(src), &tar, rd.Config{JsonLimit: 16}))
+	})
+
+	t.Run(`empty`, func(t *testing.T) {
+		tar := []Outer{{OuterStr: `prev`}}
+		try(rd.Decode(req("\n"), &tar))
+		eq(t, 0, len(tar))
+	})
+
+	t.Run(`invalid record`, func(t *testing.T) {
+		var tar []Outer
+		err := rd.Decode(req("{\"outerStr\": \"one\"}\n{\"outerStr\": 10}\n"), &tar)
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to decode record 1: json: cannot unmarshal number`, err)
+
+		errs(t, `failed to decode record 1: unexpected EOF`, rd.Decode(req("{}\n{\"outerStr\""), &tar))
+	})
+
+	t.Run(`failure leaves output unchanged`, func(t *testing.T) {
+		tar := []Outer{{OuterStr: `prev0`}, {OuterStr: `prev1`}}
+		errs(t, `failed to decode record 1`, rd.Decode(req("{\"outerStr\": \"one\"}\n{\"outerStr\": 10}\n"), &tar))
+		eq(t, []Outer{{OuterStr: `prev0`}, {OuterStr: `prev1`}}, tar)
+	})
+
+	t.Run(`invalid output`, func(t *testing.T) {
+		err := rd.Decode(req(src), new(Outer))
+		errStatus(t, http.StatusInternalServerError, err)
+		errs(t, `unable to decode "application/x-ndjson" into *rd_test.Outer, expected a non-nil slice pointer`, err)
+	})
+
+	t.Run(`download`, func(t *testing.T) {
+		_, err := rd.Download(req(src))
+		errs(t, `unsupported content type "application/x-ndjson"`, err)
+	})
+}
+
+func TestXml(t *testing.T) {
+	type Inner struct {
+		Str string `xml:"str"`
+	}
+
+	type Tar struct {
+		Str   string   `xml:"str"`
+		Num   int      `xml:"num"`
+		Attr  string   `xml:"attr,attr"`
+		List  []string `xml:"list>item"`
+		Inner Inner    `xml:"inner"`
+	}
+
+	const src = `<?xml version="1.0"?>
+<tar attr="one">
+	<str>two</str>
+	<num>10</num>
+	<list><item>three</item><item>four</item></list>
+	<inner><str>five</str></inner>
+</tar>`
+
+	exp := Tar{
+		Str:   `two`,
+		Num:   10,
+		Attr:  `one`,
+		List:  []string{`three`, `four`},
+		Inner: Inner{Str: `five`},
+	}
+
+	t.Run(`decode`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Decode(Req{}.Post().Type(rd.TypeXml).BodyString(src).Ptr(), &tar))
+		eq(t, exp, tar)
+
+		tar = Tar{}
+		try(rd.Decode(Req{}.Post().Type(rd.TypeXmlText).BodyString(src).Ptr(), &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`download`, func(t *testing.T) {
+		dec, err := rd.Download(Req{}.Post().Type(rd.TypeXml).BodyString(src).Ptr())
+		try(err)
+		eq(t, rd.Xml(src), dec)
+
+		var tar Tar
+		try(dec.Decode(&tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		err := rd.Decode(Req{}.Post().Type(rd.TypeXml).BodyString(`<tar><str>`).Ptr(), new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `XML syntax error`, err)
+	})
+
+	t.Run(`set`, func(t *testing.T) {
+		eq(t, set(`str`, `num`, `list`, `inner`), rd.Xml(src).Set())
+		eq(t, true, rd.Xml(src).Haser().Has(`inner`))
+		eq(t, false, rd.Xml(src).Haser().Has(`item`))
+		eq(t, false, rd.Xml(src).Haser().Has(`tar`))
+		eq(t, set(), rd.Xml(``).Set())
+		eq(t, set(), rd.Xml(` <tar/> `).Set())
+		eq(t, set(`one`), rd.Xml(`<tar><x:one xmlns:x="ns"/></tar>`).Set())
+
+		panics(t, `XML syntax error`, func() { rd.Xml(`<tar><one>`).Set() })
+		panics(t, `unexpected EOF`, func() { rd.Xml(`<tar><one/>`).Set() })
+	})
+
+	t.Run(`has`, func(t *testing.T) {
+		ok, err := rd.Has(Req{}.Post().Type(rd.TypeXml).BodyString(src).Ptr(), `num`)
+		try(err)
+		eq(t, true, ok)
+	})
+
+	t.Run(`limit`, func(t *testing.T) {
+		err := rd.DecodeWith(Req{}.Post().Type(rd.TypeXml).BodyString(src).Ptr(), new(Tar), rd.Config{JsonLimit: 16})
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+	})
+
+	t.Run(`validate`, func(t *testing.T) {
+		try(rd.Validate(Req{}.Post().Type(rd.TypeXml).BodyString(src).Ptr()))
+		errStatus(t, http.StatusBadRequest, rd.Validate(Req{}.Post().Type(rd.TypeXml).BodyString(`{}`).Ptr()))
+	})
+}
+
+func TestCsv(t *testing.T) {
+	type Inner struct {
+		Str string `json:"str"`
+	}
+
+	type Tar struct {
+		Str   string   `json:"str"`
+		Num   int      `json:"num"`
+		Hex   int      `json:"hex" rd:"base=16"`
+		Ptr   *float64 `json:"ptr"`
+		Inner *Inner   `json:"inner"`
+		Skip  string   `json:"-"`
+	}
+
+	const src = "\ufeffstr,num,hex,ptr,inner.str,unknown,str\n" +
+		"one,10,ff,1.5,two,three,four\n" +
+		"\"five, six\",,,,,,\n"
+
+	exp := []Tar{
+		{Str: `one`, Num: 10, Hex: 255, Ptr: ptrFloat64(1.5), Inner: &Inner{Str: `two`}},
+		{Str: `five, six`},
+	}
+
+	t.Run(`decode`, func(t *testing.T) {
+		tar := []Tar{{Str: `previous`}}
+		try(rd.Decode(Req{}.Post().Type(rd.TypeCsv).BodyString(src).Ptr(), &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`pointers`, func(t *testing.T) {
+		var tar []*Tar
+		try(rd.Csv(src).Decode(&tar))
+		eq(t, 2, len(tar))
+		eq(t, exp[0], *tar[0])
+		eq(t, exp[1], *tar[1])
+	})
+
+	t.Run(`download`, func(t *testing.T) {
+		dec, err := rd.Download(Req{}.Post().Type(rd.TypeCsv).BodyString(src).Ptr())
+		try(err)
+		eq(t, rd.Csv(strings.TrimPrefix(src, "\ufeff")), dec)
+
+		var tar []Tar
+		try(dec.Decode(&tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`empty`, func(t *testing.T) {
+		tar := []Tar{{Str: `previous`}}
+		try(rd.Csv(``).Decode(&tar))
+		eq(t, []Tar{}, tar)
+
+		tar = []Tar{{Str: `previous`}}
+		try(rd.Csv("str,num\n").Decode(&tar))
+		eq(t, []Tar{}, tar)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		test := func(exp string, src string) {
+			t.Helper()
+			err := rd.Csv(src).Decode(new([]Tar))
+			errStatus(t, http.StatusBadRequest, err)
+			errs(t, exp, err)
+		}
+
+		test(`failed to decode row 1: failed to decode field "num": failed to parse "two" into int`, "num\n1\ntwo\n")
+		test(`wrong number of fields`, "str,num\none\n")
+		test(`bare " in non-quoted-field`, "str\none\"two\n")
+	})
+
+	t.Run(`failure leaves output unchanged`, func(t *testing.T) {
+		tar := []Tar{{Num: 100}, {Num: 200}}
+		errs(t, `failed to decode row 1`, rd.Csv("num\n1\ntwo\n").Decode(&tar))
+		eq(t, []Tar{{Num: 100}, {Num: 200}}, tar)
+	})
+
+	t.Run(`unsupported output`, func(t *testing.T) {
+		test := func(out interface{}) {
+			t.Helper()
+			err := rd.Csv(src).Decode(out)
+			errStatus(t, http.StatusInternalServerError, err)
+			errs(t, `expected a non-nil pointer to a slice of structs`, err)
+		}
+
+		test(new(Tar))
+		test(new([]string))
+		test([]Tar(nil))
+		test((*[]Tar)(nil))
+	})
+
+	t.Run(`set`, func(t *testing.T) {
+		eq(t, set(`str`, `num`, `hex`, `ptr`, `inner.str`, `unknown`), rd.Csv(src).Set())
+		eq(t, true, rd.Csv(src).Haser().Has(`num`))
+		eq(t, false, rd.Csv(src).Haser().Has(`one`))
+		eq(t, set(), rd.Csv(``).Set())
+
+		panics(t, `bare "`, func() { rd.Csv("one\"two\n").Set() })
+	})
+
+	t.Run(`limit`, func(t *testing.T) {
+		err := rd.DecodeWith(Req{}.Post().Type(rd.TypeCsv).BodyString(src).Ptr(), new([]Tar), rd.Config{JsonLimit: 16})
+		errStatus(t, http.StatusRequestEntityTooLarge, err)
+	})
+
+	t.Run(`validate`, func(t *testing.T) {
+		try(rd.Validate(Req{}.Post().Type(rd.TypeCsv).BodyString(src).Ptr()))
+	})
+}
+
+func TestHas(t *testing.T) {
+	test := func(exp bool, req *http.Request, key string) {
+		t.Helper()
+		act, err := rd.Has(req, key)
+		try(err)
+		eq(t, exp, act)
+	}
+
+	test(false, nil, `embedStr`)
+
+	test(true, Req{}.Query(testOuterQuery).Ptr(), `embedStr`)
+	test(false, Req{}.Query(testOuterQuery).Ptr(), `inner`)
+	test(false, Req{}.Query(testOuterQuery).Ptr(), ``)
+
+	test(true, Req{}.Post().BodyForm(testBodyQuery).Ptr(), `eight`)
+	test(true, Req{}.Post().BodyForm(testBodyQuery).Ptr(), `seven`)
+	test(false, Req{}.Post().Query(testOuterQuery).BodyForm(url.Values{`one`: {`two`}}).Ptr(), `embedStr`)
+	test(true, Req{}.Post().BodyMulti(testBodyQuery).Ptr(), `ten`)
+
+	test(true, Req{}.Post().BodyJson(testOuterJson).Ptr(), `inner`)
+	test(false, Req{}.Post().BodyJson(testOuterJson).Ptr(), `innerStr`)
+	test(true, Req{}.Post().BodyJson(`{"one": null}`).Ptr(), `one`)
+	test(false, Req{}.Post().BodyJson(`[{"one": null}]`).Ptr(), `one`)
+
+	t.Run(`invalid`, func(t *testing.T) {
+		_, err := rd.Has(Req{}.Post().BodyJson(`{"one" 10}`).Ptr(), `one`)
+		errs(t, `invalid JSON syntax in position 7`, err)
+		errStatus(t, http.StatusBadRequest, err)
+
+		_, err = rd.Has(Req{}.Post().Type(`text/plain`).BodyString(`one`).Ptr(), `one`)
+		errs(t, `unsupported content type "text/plain"`, err)
+	})
+
+	// Sets used by `rd.Has` and strict decoding are pooled internally.
+	t.Run(`pooled sets are not shared`, func(t *testing.T) {
+		var group sync.WaitGroup
+
+		for i := range iter(64) {
+			group.Add(1)
+			go func(i int) {
+				defer group.Done()
+				key := strconv.Itoa(i)
+
+				test(true, Req{}.Post().BodyJson(`{"`+key+`": null}`).Ptr(), key)
+				test(false, Req{}.Post().BodyJson(`{"other": null}`).Ptr(), key)
+
+				var tar Outer
+				conf := rd.Config{Strict: true}
+				try(rd.Json(`{"outerStr": "`+key+`"}`).DecodeWith(&tar, conf))
+				errs(t, `duplicate JSON key "outerStr"`, rd.Json(`{"outerStr": "one", "outerStr": "two"}`).DecodeWith(&tar, conf))
+			}(i)
+		}
+		group.Wait()
+	})
+}
+
+func TestForm_Decode_embed_prefix(t *testing.T) {
+	type Deep struct {
+		Embed `rd:"prefix=deep_"`
+	}
+
+	type Tar struct {
+		Embed    `rd:"prefix=one_"`
+		*Deep    `rd:"prefix=two_"`
+		OuterStr string `json:"outerStr"`
+	}
+
+	var tar Tar
+	try(rd.Form{
+		`one_embedStr`:      {`one`},
+		`one_embedNum`:      {`10`},
+		`two_deep_embedNum`: {`20`},
+		`outerStr`:          {`three`},
+		`embedStr`:          {`ignored`},
+	}.Decode(&tar))
+
+	eq(
+		t,
+		Tar{
+			Embed:    Embed{EmbedStr: `one`, EmbedNum: 10},
+			Deep:     &Deep{Embed{EmbedNum: 20}},
+			OuterStr: `three`,
+		},
+		tar,
+	)
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `unknown fields ["embedStr"]`, rd.Form{`embedStr`: {`one`}}.DecodeWith(&tar, rd.Config{Strict: true}))
+	})
+
+	t.Run(`ignored by JSON`, func(t *testing.T) {
+		var tar Tar
+		src := rd.Json(`{"embedStr": "one", "one_embedNum": null}`)
+		try(src.DecodeWith(&tar, rd.Config{ZeroNull: true}))
+		eq(t, Tar{Embed: Embed{EmbedStr: `one`}}, tar)
+	})
+}
+
+// Uses the "form" tag, because "go vet" rejects duplicate "json" tags.
+func TestForm_Decode_ambiguous(t *testing.T) {
+	type One struct {
+		Str string `form:"str"`
+	}
+
+	type Two struct {
+		Str string `form:"str"`
+	}
+
+	type Tar struct {
+		One
+		Two
+		OuterStr string `form:"outerStr"`
+	}
+
+	src := rd.Form{`str`: {`one`}, `outerStr`: {`two`}}
+
+	t.Run(`every field is decoded`, func(t *testing.T) {
+		var tar Tar
+		try(src.DecodeWith(&tar, rd.Config{Tag: `form`}))
+		eq(t, Tar{One{`one`}, Two{`one`}, `two`}, tar)
+	})
+
+	t.Run(`reject`, func(t *testing.T) {
+		for range iter(2) {
+			var tar Tar
+			err := src.DecodeWith(&tar, rd.Config{Tag: `form`, RejectAmbiguous: true})
+			errStatus(t, http.StatusInternalServerError, err)
+			errs(t, `ambiguous fields ["str"] in type rd_test.Tar`, err)
+			eq(t, Tar{}, tar)
+		}
+	})
+
+	t.Run(`prefix avoids ambiguity`, func(t *testing.T) {
+		var tar struct {
+			One
+			Two `rd:"prefix=two_"`
+		}
+		try(rd.Form{`str`: {`one`}, `two_str`: {`two`}}.DecodeWith(&tar, rd.Config{Tag: `form`, RejectAmbiguous: true}))
+		eq(t, `one`, tar.One.Str)
+		eq(t, `two`, tar.Two.Str)
+	})
+
+	t.Run(`unambiguous`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form(testOuterQuery).DecodeWith(&tar, rd.Config{RejectAmbiguous: true}))
+		eq(t, testOuterSimple, tar)
+
+		var other Tar
+		try(src.DecodeWith(&other, rd.Config{RejectAmbiguous: true}))
+	})
+}
+
+func TestForm_Decode_nested(t *testing.T) {
+	t.Run(`value`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{
+			`outerStr`:       {`one`},
+			`inner.innerStr`: {`two`},
+			`inner.innerNum`: {`3`},
+		}.Decode(&tar))
+		eq(t, Outer{OuterStr: `one`, Inner: Inner{InnerStr: `two`, InnerNum: 3}}, tar)
+
+		try(rd.Form{`inner.innerStr`: {``}, `inner.innerNum`: {`4`}}.Decode(&tar))
+		eq(t, Outer{OuterStr: `one`, Inner: Inner{InnerNum: 4}}, tar)
+	})
+
+	t.Run(`null does not allocate`, func(t *testing.T) {
+		var tar struct {
+			Inner *Inner `json:"inner"`
+		}
+		try(rd.Form{`inner.innerStr`: {``}}.Decode(&tar))
+		eq(t, (*Inner)(nil), tar.Inner)
+
+		try(rd.Form{`inner.innerNum`: {`10`}}.Decode(&tar))
+		eq(t, &Inner{InnerNum: 10}, tar.Inner)
+	})
+
+	t.Run(`deep`, func(t *testing.T) {
+		type Deep struct {
+			One struct {
+				Two *struct {
+					Embed
+					Three []int `json:"three"`
+				} `json:"two"`
+			} `json:"one"`
+		}
+
+		var tar Deep
+		try(rd.Form{`one.two.three`: {`10`, `20`}, `one.two.embedStr`: {`four`}}.Decode(&tar))
+		eq(t, []int{10, 20}, tar.One.Two.Three)
+		eq(t, `four`, tar.One.Two.EmbedStr)
+	})
+
+	t.Run(`recursive`, func(t *testing.T) {
+		var tar TarNode
+		try(rd.Form{`val`: {`one`}, `next.val`: {`two`}, `next.next.val`: {`three`}}.Decode(&tar))
+		eq(t, TarNode{Val: `one`, Next: &TarNode{Val: `two`}}, tar)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		conf := rd.Config{Strict: true}
+		try(rd.Form{`inner.innerStr`: {`one`}}.DecodeWith(new(Outer), conf))
+		errs(t, `unknown fields ["inner.unknown"]`, rd.Form{`inner.unknown`: {`one`}}.DecodeWith(new(Outer), conf))
+	})
+
+	t.Run(`json ignores dotted fields`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Json(`{"inner.innerStr": "one"}`).DecodeWith(&tar, rd.Config{Coerce: true}))
+		eq(t, Outer{}, tar)
+	})
+}
+
+func TestForm_Decode_brackets(t *testing.T) {
+	test := func(exp TarPair, src rd.Form) {
+		t.Helper()
+		var tar TarPair
+		try(src.Decode(&tar))
+		eq(t, exp, tar)
+	}
+
+	test(TarPair{One: []int{10, 20}}, rd.Form{`one[]`: {`10`, `20`}})
+	test(TarPair{One: []int{10, 20, 30}}, rd.Form{`one[2]`: {`30`}, `one[0]`: {`10`}, `one[1]`: {`20`}})
+	test(TarPair{One: []int{10, 20, 30}}, rd.Form{`one[10]`: {`30`}, `one[9]`: {`20`}, `one[1]`: {`10`}})
+	test(TarPair{One: []int{10, 20}, Two: []int{30}}, rd.Form{`one[]`: {`10`, `20`}, `two[0]`: {`30`}})
+	test(
+		TarPair{One: []int{10, 20, 30, 40}},
+		rd.Form{`one[1]`: {`40`}, `one[0]`: {`30`}, `one[]`: {`20`}, `one`: {`10`}},
+	)
+	test(TarPair{}, rd.Form{`one[]`: {}})
+	test(TarPair{}, rd.Form{`one[x]`: {`10`}, `one[-1]`: {`20`}, `one[+1]`: {`30`}, `[]`: {`40`}})
+
+	t.Run(`source is not modified`, func(t *testing.T) {
+		src := rd.Form{`one`: {`10`}, `one[]`: {`20`}}
+		test(TarPair{One: []int{10, 20}}, src)
+		eq(t, rd.Form{`one`: {`10`}, `one[]`: {`20`}}, src)
+	})
+
+	t.Run(`non-list field`, func(t *testing.T) {
+		var tar TarInt
+		try(rd.Form{`val[1]`: {`20`}, `val[0]`: {`10`}}.Decode(&tar))
+		eq(t, TarInt{10}, tar)
+
+		errs(
+			t,
+			`expected at most one value for field "val", got 2`,
+			rd.Form{`val[]`: {`10`, `20`}}.DecodeWith(&tar, rd.Config{Strict: true}),
+		)
+	})
+
+	t.Run(`slice parser`, func(t *testing.T) {
+		var tar struct {
+			Val SliceParserStruct `json:"val"`
+		}
+		try(rd.Form{`val[1]`: {`20`}, `val[0]`: {`10`}}.Decode(&tar))
+		eq(t, []int{10, 20}, tar.Val.Inner)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar TarPair
+		try(rd.Form{`one[]`: {`10`}, `two[0]`: {`20`}}.DecodeWith(&tar, rd.Config{Strict: true}))
+		eq(t, TarPair{One: []int{10}, Two: []int{20}}, tar)
+	})
+
+	t.Run(`tracked`, func(t *testing.T) {
+		var tar TarPair
+		tracked, err := rd.Form{`one[0]`: {`10`}}.DecodeTracked(&tar, rd.Config{})
+		try(err)
+		eq(t, set(`one`), tracked)
+	})
+}
+
+func TestForm_Decode_map(t *testing.T) {
+	type Tar struct {
+		Meta map[string]string `json:"meta"`
+		Nums map[string]int    `json:"nums"`
+		Ptr  *map[int]bool     `json:"ptr"`
+		Str  string            `json:"str"`
+	}
+
+	test := func(exp, tar Tar, src rd.Form) {
+		t.Helper()
+		try(src.Decode(&tar))
+		eq(t, exp, tar)
+	}
+
+	test(Tar{}, Tar{}, rd.Form{})
+	test(Tar{}, Tar{}, rd.Form{`meta`: {``}, `nums[]`: {``}, `metas[one]`: {`two`}})
+	test(
+		Tar{Meta: map[string]string{`color`: `red`, `size`: `large`, `0`: `zero`}},
+		Tar{},
+		rd.Form{`meta[color]`: {`red`, `blue`}, `meta[size]`: {`large`}, `meta[0]`: {`zero`}},
+	)
+	test(
+		Tar{Nums: map[string]int{`one`: 10, `two`: 0}, Str: `three`},
+		Tar{},
+		rd.Form{`nums[one]`: {`10`}, `nums[two]`: {``}, `str`: {`three`}},
+	)
+
+	ptr := map[int]bool{10: true, 20: false}
+	test(Tar{Ptr: &ptr}, Tar{}, rd.Form{`ptr[10]`: {`true`}, `ptr[20]`: {`false`}})
+
+	t.Run(`existing map`, func(t *testing.T) {
+		test(
+			Tar{Meta: map[string]string{`one`: `two`, `three`: `five`, `six`: `seven`}},
+			Tar{Meta: map[string]string{`one`: `two`, `three`: `four`}},
+			rd.Form{`meta[three]`: {`five`}, `meta[six]`: {`seven`}},
+		)
+
+		test(
+			Tar{Meta: map[string]string{`six`: `seven`}},
+			Tar{Meta: map[string]string{`one`: `two`}},
+			rd.Form{`meta`: {``}, `meta[six]`: {`seven`}},
+		)
+
+		test(Tar{}, Tar{Meta: map[string]string{`one`: `two`}}, rd.Form{`meta`: {}})
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `failed to parse "x"`, rd.Form{`nums[one]`: {`x`}}.Decode(&tar))
+		errs(t, `failed to parse "x"`, rd.Form{`ptr[x]`: {`true`}}.Decode(&tar))
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`meta[one]`: {`two`}}.DecodeWith(&tar, rd.Config{Strict: true}))
+		eq(t, map[string]string{`one`: `two`}, tar.Meta)
+
+		errs(
+			t,
+			`expected at most one value for field "meta[one]", got 2`,
+			rd.Form{`meta[one]`: {`two`, `three`}}.DecodeWith(&tar, rd.Config{Strict: true}),
+		)
+		errs(
+			t,
+			`unknown fields ["str[one]"]`,
+			rd.Form{`str[one]`: {`two`}}.DecodeWith(&tar, rd.Config{Strict: true}),
+		)
+	})
+
+	t.Run(`nested`, func(t *testing.T) {
+		var tar struct {
+			Inner struct {
+				Meta map[string]string `json:"meta"`
+			} `json:"inner"`
+		}
+		try(rd.Form{`inner.meta[one]`: {`two`}}.Decode(&tar))
+		eq(t, map[string]string{`one`: `two`}, tar.Inner.Meta)
+	})
+
+	t.Run(`querystring`, func(t *testing.T) {
+		var tar struct {
+			Meta  map[string]string `json:"meta"`
+			Query string            `rd:"querystring"`
+		}
+		try(rd.Form{`meta[one]`: {`two`}, `three[four]`: {`five`}}.Decode(&tar))
+		eq(t, map[string]string{`one`: `two`}, tar.Meta)
+		eq(t, `three%5Bfour%5D=five`, tar.Query)
+	})
+
+	t.Run(`raw and tracked`, func(t *testing.T) {
+		src := rd.Form{`meta[one]`: {`two`}, `str`: {`three`}, `unknown`: {`four`}}
+
+		var tar Tar
+		raw, err := src.DecodeRaw(&tar, rd.Config{})
+		try(err)
+		eq(t, rd.Form{`meta[one]`: {`two`}, `str`: {`three`}}, raw)
+
+		tracked, err := src.DecodeTracked(&tar, rd.Config{})
+		try(err)
+		eq(t, set(`meta`, `str`), tracked)
+	})
+}
+
+func TestForm_Decode_repeated(t *testing.T) {
+	var tar struct {
+		Str   string  `json:"str"`
+		Num   int     `json:"num"`
+		Ptr   *int    `json:"ptr"`
+		Inner Inner   `json:"inner"`
+		List  []int   `json:"list"`
+		Bool  *bool   `json:"bool"`
+		Float float64 `json:"float"`
+	}
+
+	try(rd.Form{
+		`str`:            {`one`, `two`},
+		`num`:            {`10`, `20`},
+		`ptr`:            {`30`, `40`},
+		`inner.innerStr`: {`three`, `four`},
+		`list`:           {`50`, `60`},
+		`bool`:           {`true`, `false`},
+		`float`:          {`1.5`, `2.5`},
+	}.Decode(&tar))
+
+	eq(t, `one`, tar.Str)
+	eq(t, 10, tar.Num)
+	eq(t, 30, *tar.Ptr)
+	eq(t, `three`, tar.Inner.InnerStr)
+	eq(t, []int{50, 60}, tar.List)
+	eq(t, true, *tar.Bool)
+	eq(t, 1.5, tar.Float)
+
+	t.Run(`matches url.Values.Get`, func(t *testing.T) {
+		src := url.Values{`outerStr`: {`one`, `two`, `three`}}
+
+		var tar Outer
+		try(rd.Form(src).Decode(&tar))
+		eq(t, src.Get(`outerStr`), tar.OuterStr)
+
+		try(rd.DecodeWith(Req{}.Query(src).Ptr(), &tar, rd.Config{}))
+		eq(t, src.Get(`outerStr`), tar.OuterStr)
+	})
+
+	t.Run(`json key`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`_json`: {`{"outerStr": "one"}`, `{"outerStr": "two"}`}}.DecodeWith(&tar, rd.Config{JsonKey: `_json`}))
+		eq(t, `one`, tar.OuterStr)
+	})
+
+	t.Run(`strict types`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`outerStr`: {`one`, `two`}}.DecodeStrictTypes(&tar))
+		eq(t, `one`, tar.OuterStr)
+	})
+}
+
+type BindTar struct {
+	Id      int      `json:"id"           rd:"in=path"`
+	Page    int      `json:"page"         rd:"in=query"`
+	Tags    []string `json:"tags"         rd:"in=query"`
+	Trace   string   `json:"X-Request-Id" rd:"in=header"`
+	Session string   `json:"session"      rd:"in=cookie"`
+	Name    string   `json:"name"`
+	Num     int      `json:"num"          rd:"in=body"`
+}
+
+func bindPath(req *http.Request, name string) string {
+	if name == `id` {
+		return strings.TrimPrefix(req.URL.Path, `/items/`)
+	}
+	return ``
+}
+
+func bindReq(req Req) *http.Request {
+	req = req.Query(url.Values{`page`: {`2`}, `tags`: {`one`, `two`}})
+	req.URL.Path = `/items/10`
+	req.Header.Set(`x-request-id`, `trace`)
+	req.Header.Add(`Cookie`, `session=secret; other=val`)
+	return req.Ptr()
+}
+
+func TestBinder(t *testing.T) {
+	binder := rd.Binder{Path: bindPath}
+	exp := BindTar{
+		Id:      10,
+		Page:    2,
+		Tags:    []string{`one`, `two`},
+		Trace:   `trace`,
+		Session: `secret`,
+		Name:    `three`,
+		Num:     20,
+	}
+
+	t.Run(`json`, func(t *testing.T) {
+		var tar BindTar
+		try(binder.Bind(bindReq(Req{}.Post().BodyJson(`{"name": "three", "num": 20}`)), &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar BindTar
+		try(binder.Bind(bindReq(Req{}.Post().BodyForm(url.Values{`name`: {`three`}, `num`: {`20`}})), &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`query without body`, func(t *testing.T) {
+		req := bindReq(Req{})
+		req.URL.RawQuery += `&name=three&num=20`
+
+		var tar BindTar
+		try(binder.Bind(req, &tar))
+		eq(t, exp, tar)
+	})
+
+	t.Run(`body can't override other sources`, func(t *testing.T) {
+		tar := BindTar{Trace: `prev`}
+		req := Req{}.Post().BodyJson(`{"id": 30, "page": 40, "X-Request-Id": "fake", "session": "fake", "name": "three"}`)
+		try(binder.Bind(req.Ptr(), &tar))
+		eq(t, BindTar{Trace: `prev`, Name: `three`}, tar)
+
+		tar = BindTar{}
+		req = Req{}.Post().BodyForm(url.Values{`id`: {`30`}, `session`: {`fake`}, `name`: {`three`}})
+		try(binder.Bind(req.Ptr(), &tar))
+		eq(t, BindTar{Name: `three`}, tar)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		binder := rd.Binder{Path: bindPath, Config: rd.Config{Strict: true}}
+		req := Req{}.Post().BodyForm(url.Values{`session`: {`fake`}, `name`: {`three`}})
+		errs(t, `disallowed fields ["session"]`, binder.Bind(req.Ptr(), new(BindTar)))
+
+		t.Run(`json`, func(t *testing.T) {
+			req := Req{}.Post().BodyJson(`{"session": "fake", "Page": 40, "name": "three"}`).Ptr()
+			tar := BindTar{Session: `prev`}
+			err := binder.Bind(req, &tar)
+			errStatus(t, http.StatusBadRequest, err)
+			errs(t, `disallowed fields ["Page" "session"]`, err)
+			eq(t, `prev`, tar.Session)
+
+			var out BindTar
+			try(binder.Bind(bindReq(Req{}.Post().BodyJson(`{"name": "three", "num": 20}`)), &out))
+			eq(t, exp, out)
+
+			try(binder.Bind(Req{}.Post().TypeJson().Ptr(), new(BindTar)))
+		})
+	})
+
+	t.Run(`required`, func(t *testing.T) {
+		type Tar struct {
+			Trace string `json:"X-Request-Id,required" rd:"in=header"`
+			Name  string `json:"name,required"`
+		}
+
+		err := rd.Binder{}.Bind(Req{}.Post().BodyForm(url.Values{`name`: {`one`}}).Ptr(), new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `invalid field "X-Request-Id": missing required field`, err)
+
+		req := Req{}.Post().BodyForm(url.Values{`other`: {`one`}})
+		req.Header.Set(`X-Request-Id`, `trace`)
+		errs(t, `invalid field "name": missing required field`, rd.Binder{}.Bind(req.Ptr(), new(Tar)))
+	})
+
+	t.Run(`nested`, func(t *testing.T) {
+		type Tar struct {
+			Inner Inner  `json:"inner" rd:"in=query"`
+			Name  string `json:"name"`
+		}
+
+		req := Req{}.Query(url.Values{`inner.innerStr`: {`one`}}).Post().BodyJson(`{"name": "two", "inner": {"innerNum": 10}}`)
+
+		var tar Tar
+		try(rd.Binder{}.Bind(req.Ptr(), &tar))
+		eq(t, Tar{Inner: Inner{InnerStr: `one`}, Name: `two`}, tar)
+	})
+
+	t.Run(`post decode runs once`, func(t *testing.T) {
+		var calls []BindTar
+		typ := r.TypeOf(BindTar{})
+		rd.RegisterPostDecode(typ, func(val r.Value) error {
+			calls = append(calls, val.Interface().(BindTar))
+			return nil
+		})
+		defer rd.RegisterPostDecode(typ, nil)
+
+		try(binder.Bind(bindReq(Req{}.Post().BodyJson(`{"name": "three", "num": 20}`)), new(BindTar)))
+		eq(t, []BindTar{exp}, calls)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		err := rd.Binder{}.Bind(bindReq(Req{}), new(BindTar))
+		errStatus(t, http.StatusInternalServerError, err)
+		errs(t, `unable to bind path field "id" without rd.Binder.Path`, err)
+
+		type Tar struct {
+			Val string `json:"val" rd:"in=nowhere"`
+		}
+		errs(t, `unknown source "nowhere" in "rd" tag of field "val"`, binder.Bind(bindReq(Req{}), new(Tar)))
+
+		errs(t, `expected settable struct pointer`, binder.Bind(bindReq(Req{}), BindTar{}))
+	})
+}
+
+func TestRegisterPostDecode(t *testing.T) {
+	type Tar struct {
+		Str string `json:"str"`
+		Len int    `json:"-"`
+	}
+
+	typ := r.TypeOf(Tar{})
+	panics(t, `for nil type`, func() { rd.RegisterPostDecode(nil, nil) })
+
+	var calls int
+	rd.RegisterPostDecode(typ, func(val r.Value) error {
+		calls++
+		tar := val.Addr().Interface().(*Tar)
+		if tar.Str == `invalid` {
+			return fmt.Errorf(`invalid str`)
+		}
+		if tar.Str == `internal` {
+			return rd.Err{Status: http.StatusInternalServerError, Cause: fmt.Errorf(`internal`)}
+		}
+		tar.Str = strings.ToUpper(strings.TrimSpace(tar.Str))
+		tar.Len = len(tar.Str)
+		return nil
+	})
+	defer rd.RegisterPostDecode(typ, nil)
+
+	t.Run(`json`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Decode(Req{}.Post().BodyJson(`{"str": " one "}`).Ptr(), &tar))
+		eq(t, Tar{Str: `ONE`, Len: 3}, tar)
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Decode(Req{}.Post().BodyForm(url.Values{`str`: {`two`}}).Ptr(), &tar))
+		eq(t, Tar{Str: `TWO`, Len: 3}, tar)
+	})
+
+	t.Run(`pointer output`, func(t *testing.T) {
+		tar, err := rd.DecodeTyped[*Tar](Req{}.Post().BodyJson(`{"str": "three"}`).Ptr())
+		try(err)
+		eq(t, &Tar{Str: `THREE`, Len: 5}, tar)
+	})
+
+	t.Run(`errors are bad requests`, func(t *testing.T) {
+		err := rd.Decode(Req{}.Post().BodyJson(`{"str": "invalid"}`).Ptr(), new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `invalid str`, err)
+
+		err = rd.Decode(Req{}.Post().BodyJson(`{"str": "internal"}`).Ptr(), new(Tar))
+		errStatus(t, http.StatusInternalServerError, err)
+	})
+
+	t.Run(`skipped on decoding failure`, func(t *testing.T) {
+		calls = 0
+		errs(t, `invalid character`, rd.Decode(Req{}.Post().BodyJson(`{"str": }`).Ptr(), new(Tar)))
+		eq(t, 0, calls)
+	})
+
+	t.Run(`not used by decoder types`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`str`: {`four`}}.Decode(&tar))
+		eq(t, Tar{Str: `four`}, tar)
+	})
+
+	t.Run(`other types`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Decode(Req{}.Query(url.Values{`outerStr`: {`five`}}).Ptr(), &tar))
+		eq(t, `five`, tar.OuterStr)
+	})
+
+	t.Run(`unregistered`, func(t *testing.T) {
+		rd.RegisterPostDecode(typ, nil)
+		defer rd.RegisterPostDecode(typ, func(r.Value) error { return nil })
+
+		var tar Tar
+		try(rd.Decode(Req{}.Post().BodyJson(`{"str": " six "}`).Ptr(), &tar))
+		eq(t, Tar{Str: ` six `}, tar)
+	})
+}
+
+func TestRegisterCombiner(t *testing.T) {
+	typ := r.TypeOf(time.Time{})
+
+	panics(t, `without keys`, func() { rd.RegisterCombiner(typ, nil, combineDate) })
+
+	rd.RegisterCombiner(typ, []string{`year`, `month`, `day`}, combineDate)
+	defer rd.RegisterCombiner(typ, nil, nil)
+
+	type Tar struct {
+		One   time.Time  `json:"one"`
+		Two   *time.Time `json:"two"`
+		Three time.Time  `json:"three"`
+	}
+
+	var tar Tar
+	try(rd.Form{
+		`one.year`:  {`2026`},
+		`one.month`: {`10`},
+		`one.day`:   {`14`},
+		`two.year`:  {`1999`, `2000`},
+		`two.month`: {`1`},
+		`two.day`:   {`2`},
+		`three`:     {`2001-02-03T04:05:06Z`},
+	}.Decode(&tar))
+
+	eq(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), tar.One)
+	eq(t, time.Date(1999, 1, 2, 0, 0, 0, 0, time.UTC), *tar.Two)
+	eq(t, time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), tar.Three)
+
+	t.Run(`missing keys`, func(t *testing.T) {
+		errs(t, `cannot parse "-10-14"`, rd.Form{`one.month`: {`10`}, `one.day`: {`14`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`combiner errors are bad requests`, func(t *testing.T) {
+		err := rd.Form{`one.year`: {`2026`}, `one.month`: {`13`}, `one.day`: {`1`}}.Decode(new(Tar))
+		errStatus(t, 400, err)
+		errs(t, `month out of range`, err)
+	})
+
+	t.Run(`nil zeroes`, func(t *testing.T) {
+		tar := Tar{One: time.Now()}
+		try(rd.Form{`one.year`: {``}, `one.month`: {``}, `one.day`: {``}}.Decode(&tar))
+		eq(t, time.Time{}, tar.One)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		conf := rd.Config{Strict: true}
+		try(rd.Form{`one.year`: {`2026`}, `one.month`: {`1`}, `one.day`: {`1`}}.DecodeWith(new(Tar), conf))
+		errs(t, `unknown fields ["one.hour"]`, rd.Form{`one.hour`: {`1`}}.DecodeWith(new(Tar), conf))
+	})
+
+	t.Run(`incompatible output`, func(t *testing.T) {
+		rd.RegisterCombiner(typ, []string{`year`}, func([]string) (interface{}, error) { return `str`, nil })
+		defer rd.RegisterCombiner(typ, []string{`year`, `month`, `day`}, combineDate)
+
+		err := rd.Form{`one.year`: {`2026`}}.Decode(new(Tar))
+		errStatus(t, 500, err)
+		errs(t, `combiner for time.Time returned incompatible value of type string`, err)
+	})
+
+	t.Run(`unregistered`, func(t *testing.T) {
+		rd.RegisterCombiner(typ, nil, nil)
+		defer rd.RegisterCombiner(typ, []string{`year`, `month`, `day`}, combineDate)
+
+		var tar Tar
+		try(rd.Form{`one.year`: {`2026`}}.Decode(&tar))
+		eq(t, Tar{}, tar)
+	})
+}
+
+func TestForm_DecodeWith_JsonKey(t *testing.T) {
+	conf := rd.Config{JsonKey: `_json`}
+
+	t.Run(`disabled by default`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`_json`: {testOuterJson}}.Decode(&tar))
+		eq(t, Outer{}, tar)
+	})
+
+	t.Run(`only json`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`_json`: {testOuterJson}}.DecodeWith(&tar, conf))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`form fields take precedence`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{
+			`_json`:    {testOuterJson},
+			`outerStr`: {`outer val from form`},
+			`embedNum`: {``},
+		}.DecodeWith(&tar, conf))
+
+		eq(
+			t,
+			Outer{
+				Embed:    Embed{EmbedStr: `embed val`},
+				Inner:    Inner{InnerStr: `inner val`, InnerNum: 20},
+				OuterStr: `outer val from form`,
+			},
+			tar,
+		)
+	})
+
+	t.Run(`empty json is ignored`, func(t *testing.T) {
+		tar := testOuter
+		try(rd.Form{`_json`: {``}}.DecodeWith(&tar, conf))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`invalid json`, func(t *testing.T) {
+		var tar Outer
+		errs(t, `unexpected end of JSON input`, rd.Form{`_json`: {`{`}}.DecodeWith(&tar, conf))
+	})
+
+	t.Run(`via request`, func(t *testing.T) {
+		req := Req{}.Post().BodyForm(url.Values{
+			`_json`:    {`{"inner": {"innerNum": 30}}`},
+			`outerStr`: {`outer val`},
+		}).Ptr()
+
+		var tar Outer
+		try(rd.DecodeWith(req, &tar, conf))
+		eq(t, Outer{Inner: Inner{InnerNum: 30}, OuterStr: `outer val`}, tar)
+	})
+}
+
+func TestAsSet(t *testing.T) {
+	eq(t, rd.Set(nil), rd.AsSet(nil))
+	eq(t, rd.Set(nil), rd.AsSet(HaserFunc(func(string) bool { return true })))
+	eq(t, testOuterQuerySet, rd.AsSet(rd.Form(testOuterQuery)))
+	eq(t, testOuterJsonSet, rd.AsSet(rd.Json(testOuterJson).Haser()))
+	eq(t, set(`one`, `two`), rd.AsSet(set(`one`, `two`)))
+}
+
+func TestToml_Decode(t *testing.T) {
+	errs(t, `missing unmarshaler for content type "application/toml"`, rd.Toml(testOuterToml).Decode(new(Outer)))
+
+	defer withRegistered(rd.TypeToml, tomlUnmarshal)()
+
+	var tar Outer
+	try(rd.Toml(testOuterToml).Decode(&tar))
+	eq(t, testOuter, tar)
+
+	errs(t, `invalid TOML line`, rd.Toml(`garbage`).Decode(&tar))
+}
+
+func TestToml_Set(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		eq(t, exp, rd.Toml(src).Set())
+	}
+
+	test(set(), ``)
+	test(set(), "  \n\t\n  ")
+	test(set(), `# comment`)
+	test(set(`embedStr`, `embedNum`, `outerStr`, `inner`), testOuterToml)
+
+	test(set(`one`), `one = "two"`)
+	test(set(`one`), `one = 'two'`)
+	test(set(`one`), `one = "two \" three # four"`)
+	test(set(`one`), `one = 'two \'`)
+	test(set(`one`), `one = 10 # comment`)
+	test(set(`one`), `one = -1.5e+3`)
+	test(set(`one`), `one = true`)
+	test(set(`one`), `one = 1979-05-27T07:32:00Z`)
+	test(set(`one`), `one = 1979-05-27 07:32:00Z`)
+	test(set(`one`), `one = 1979-05-27`)
+	test(set(`one`), `one = [1, "two", [3], {four = 5}]`)
+	test(set(`one`), "one = [\n\t1, # comment\n\t2,\n]")
+	test(set(`one`), `one = {two = 3, four.five = "six"}`)
+	test(set(`one`, `two`), "one = \"\"\"\ntwo = 3\n\"\"\"\ntwo = 4")
+	test(set(`one`, `two`), "one = '''\ntwo = 3\n'''\ntwo = 4")
+	test(set(`one`, `two`), "one = \"\"\"two \"\"\"\"\"\ntwo = 4")
+	test(set(`one`), `one.two.three = 4`)
+	test(set(`one`), `one . two = 3`)
+	test(set(`one two`), `"one two" = 3`)
+	test(set(`one"two`), `"one\"two" = 3`)
+	test(set(`one\two`), `'one\two' = 3`)
+	test(set(`one`, `two`), "one = 1\n[two]\nthree = 4")
+	test(set(`one`, `two`), "one = 1\n[two.three]\nfour = 5")
+	test(set(`one`, `two`), "one = 1\n[[two]]\nthree = 4\n[[two]]\nthree = 5")
+	test(set(`one`, `two`), "[one]\nthree = 4\n[ two ]\nfive = 6")
+
+	panics(t, `unexpected TOML EOF`, func() { rd.Toml(`garbage`).Set() })
+	panics(t, `invalid TOML syntax`, func() { rd.Toml(`garbage garbage`).Set() })
+	panics(t, `invalid TOML syntax`, func() { rd.Toml(`one = 1 two`).Set() })
+	panics(t, `invalid TOML syntax`, func() { rd.Toml(`= 1`).Set() })
+	panics(t, `unexpected TOML EOF`, func() { rd.Toml(`one = "two`).Set() })
+	panics(t, `unexpected TOML EOF`, func() { rd.Toml(`one = [1, 2`).Set() })
+	panics(t, `unexpected TOML EOF`, func() { rd.Toml(`[one`).Set() })
+}
+
+func TestRegister(t *testing.T) {
+	panics(t, `unsupported content type "text/plain"`, func() {
+		rd.Register(`text/plain`, tomlUnmarshal)
+	})
+
+	req := func() *http.Request {
+		return Req{}.Post().Type(rd.TypeToml).BodyString(testOuterToml).Ptr()
+	}
+
+	errs(t, `unsupported content type "application/toml"`, rd.Decode(req(), new(Outer)))
+
+	_, err := rd.Download(req())
+	errs(t, `unsupported content type "application/toml"`, err)
+
+	defer withRegistered(rd.TypeToml, tomlUnmarshal)()
+
+	var tar Outer
+	rd.TryDecode(req(), &tar)
+	eq(t, testOuter, tar)
+
+	eq(t, rd.Toml(testOuterToml), rd.TryDownload(req()))
+}
+
+func TestJson_DecodeWith_JsonNumberString(t *testing.T) {
+	type Str string
+
+	type Tar struct {
+		Str  string    `json:"str"`
+		Ptr  *string   `json:"ptr"`
+		Strs []string  `json:"strs"`
+		Arr  [2]Str    `json:"arr"`
+		Num  int       `json:"num"`
+		Time time.Time `json:"time"`
+	}
+
+	conf := rd.Config{JsonNumberString: true}
+
+	test := func(exp Tar, src string) {
+		t.Helper()
+		var tar Tar
+		try(rd.Json(src).DecodeWith(&tar, conf))
+		eq(t, exp, tar)
+	}
+
+	ten := `10`
+
+	test(Tar{}, `{}`)
+	test(Tar{Str: `one`}, `{"str": "one"}`)
+	test(Tar{Str: `10`}, `{"str": 10}`)
+	test(Tar{Str: `-1.50e3`}, `{"str": -1.50e3}`)
+	test(Tar{Ptr: &ten}, `{"ptr": 10}`)
+	test(Tar{Strs: []string{`10`, `one`, `2.5`}}, `{"strs": [10, "one", 2.5]}`)
+	test(Tar{Arr: [2]Str{`10`, `20`}}, `{"arr": [10, 20]}`)
+	test(Tar{Num: 10}, `{"num": 10}`)
+
+	t.Run(`matches forms`, func(t *testing.T) {
+		var fromJson, fromForm Tar
+		try(rd.Json(`{"str": 10, "strs": [20, 30]}`).DecodeWith(&fromJson, conf))
+		try(rd.Form{`str`: {`10`}, `strs`: {`20`, `30`}}.Decode(&fromForm))
+		eq(t, fromForm, fromJson)
+	})
+
+	t.Run(`strict by default`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `cannot unmarshal number`, rd.Json(`{"str": 10}`).Decode(&tar))
+	})
+
+	t.Run(`other values are rejected`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `cannot unmarshal bool`, rd.Json(`{"str": true}`).DecodeWith(&tar, conf))
+		errs(t, `cannot unmarshal object`, rd.Json(`{"strs": [{}]}`).DecodeWith(&tar, conf))
+		errs(t, `cannot unmarshal number`, rd.Json(`{"time": 10}`).DecodeWith(&tar, conf))
+	})
+
+	t.Run(`case-insensitive keys`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Json(`{"STR": 10, "Strs": [20]}`).DecodeWith(&tar, conf))
+		eq(t, Tar{Str: `10`, Strs: []string{`20`}}, tar)
+	})
+
+	t.Run(`with coerce`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Json(`{"strs": 10, "str": [20]}`).DecodeWith(&tar, rd.Config{JsonNumberString: true, Coerce: true}))
+		eq(t, Tar{Str: `20`, Strs: []string{`10`}}, tar)
+	})
+
+	t.Run(`via request`, func(t *testing.T) {
+		req := Req{}.Post().BodyJson(`{"str": 10}`).Ptr()
+		var tar Tar
+		try(rd.DecodeWith(req, &tar, conf))
+		eq(t, Tar{Str: `10`}, tar)
+	})
+}
+
+func TestJson_DecodeWith_Coerce(t *testing.T) {
+	type Tar struct {
+		Str   string    `json:"str"`
+		Strs  []string  `json:"strs"`
+		Arr   [1]int    `json:"arr"`
+		Bytes []byte    `json:"bytes"`
+		Ptr   *string   `json:"ptr"`
+		Inner *Inner    `json:"inner"`
+		Ints  *[]int    `json:"ints"`
+		Time  time.Time `json:"time"`
+	}
+
+	conf := rd.Config{Coerce: true}
+
+	test := func(exp Tar, src string) {
+		t.Helper()
+		var tar Tar
+		try(rd.Json(src).DecodeWith(&tar, conf))
+		eq(t, exp, tar)
+	}
+
+	str := `one`
+
+	test(Tar{}, `{}`)
+	test(Tar{}, `null`)
+	test(Tar{Str: `one`}, `{"str": "one"}`)
+	test(Tar{Str: `one`}, `{"str": ["one"]}`)
+	test(Tar{Strs: []string{`one`}}, `{"strs": "one"}`)
+	test(Tar{Strs: []string{`one`}}, `{"strs": ["one"]}`)
+	test(Tar{Strs: []string{`one`, `two`}}, `{"strs": ["one", "two"]}`)
+	test(Tar{Arr: [1]int{10}}, `{"arr": 10}`)
+	test(Tar{Bytes: []byte(`one`)}, `{"bytes": "b25l"}`)
+	test(Tar{Ptr: &str}, `{"ptr": ["one"]}`)
+	test(Tar{Inner: &Inner{InnerNum: 10}}, `{"inner": [{"innerNum": 10}]}`)
+	test(Tar{Ints: &[]int{10}}, `{"ints": 10}`)
+	test(Tar{Time: time.Date(1234, 1, 2, 3, 4, 5, 0, time.UTC)}, `{"time": ["1234-01-02T03:04:05Z"]}`)
+	test(Tar{}, `{"strs": null, "str": null}`)
+
+	t.Run(`strict by default`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `cannot unmarshal array`, rd.Json(`{"str": ["one"]}`).Decode(&tar))
+		errs(t, `cannot unmarshal string`, rd.Json(`{"strs": "one"}`).Decode(&tar))
+	})
+
+	t.Run(`case-insensitive keys`, func(t *testing.T) {
+		test(Tar{Str: `one`, Strs: []string{`two`}}, `{"STR": ["one"], "Strs": "two"}`)
+		test(Tar{Str: `one`}, `{"Str": ["two"], "str": ["one"]}`)
+		test(Tar{Str: `two`}, `{"STR": ["one"], "Str": ["two"]}`)
+		test(Tar{Str: `one`}, `{"Str": ["two"], "STR": ["one"]}`)
+	})
+
+	t.Run(`duplicate keys win like in regular decoding`, func(t *testing.T) {
+		for _, src := range []string{
+			`{"Str": "one", "str": "two"}`,
+			`{"str": "one", "Str": "two"}`,
+			`{"str": "one", "STR": "two", "Str": "three"}`,
+			`{"Str": "one", "str": "two", "Str": "three"}`,
+		} {
+			var exp, tar Tar
+			try(rd.Json(src).DecodeWith(&exp, rd.Config{}))
+			try(rd.Json(src).DecodeWith(&tar, conf))
+			eq(t, exp, tar)
+		}
+	})
+
+	t.Run(`multiple elements are not unwrapped`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `cannot unmarshal array`, rd.Json(`{"str": ["one", "two"]}`).DecodeWith(&tar, conf))
+	})
+
+	t.Run(`non-struct output`, func(t *testing.T) {
+		var tar []string
+		try(rd.Json(`["one"]`).DecodeWith(&tar, conf))
+		eq(t, []string{`one`}, tar)
+	})
+
+	t.Run(`invalid JSON`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `unexpected end of JSON input`, rd.Json(`{"str": `).DecodeWith(&tar, conf))
+	})
+
+	t.Run(`via request`, func(t *testing.T) {
+		req := Req{}.Post().BodyJson(`{"str": ["one"], "strs": "two"}`).Ptr()
+
+		var tar Tar
+		try(rd.DecodeWith(req, &tar, conf))
+		eq(t, Tar{Str: `one`, Strs: []string{`two`}}, tar)
+	})
+}
+
+func TestForm_DecodeRaw(t *testing.T) {
+	test := func(expTar Outer, expRaw rd.Form, src rd.Form) {
+		t.Helper()
+		var tar Outer
+		raw, err := src.DecodeRaw(&tar, rd.Config{})
+		try(err)
+		eq(t, expTar, tar)
+		eq(t, expRaw, raw)
+	}
+
+	test(Outer{}, nil, nil)
+	test(Outer{}, nil, rd.Form{`unknown`: {`one`}})
+	test(testOuterSimple, rd.Form(testOuterQuery), rd.Form{
+		`embedStr`: {`embed val`},
+		`embedNum`: {`10`},
+		`outerStr`: {`outer val`},
+		`unknown`:  {`one`},
+	})
+
+	test(
+		Outer{OuterStr: `one`},
+		rd.Form{`outerStr`: {`one`, `two`}, `embedNum`: {``}},
+		rd.Form{`outerStr`: {`one`, `two`}, `embedNum`: {``}},
+	)
+
+	t.Run(`error`, func(t *testing.T) {
+		var tar Outer
+		raw, err := rd.Form{`embedNum`: {`garbage`}}.DecodeRaw(&tar, rd.Config{})
+		errs(t, `failed to parse "garbage"`, err)
+		eq(t, rd.Form(nil), raw)
+	})
+}
+
+func TestForm_DecodeTracked(t *testing.T) {
+	test := func(expTar Outer, expSet rd.Set, src rd.Form) {
+		t.Helper()
+		var tar Outer
+		tracked, err := src.DecodeTracked(&tar, rd.Config{})
+		try(err)
+		eq(t, expTar, tar)
+		eq(t, expSet, tracked)
+	}
+
+	test(Outer{}, nil, nil)
+	test(Outer{}, nil, rd.Form{`unknown`: {`one`}})
+	test(testOuterSimple, set(`embedStr`, `embedNum`, `outerStr`), rd.Form(testOuterQuery))
+
+	t.Run(`zero values are present`, func(t *testing.T) {
+		test(
+			Outer{},
+			set(`embedStr`, `embedNum`, `outerStr`, `inner.innerNum`),
+			rd.Form{
+				`embedStr`:       {``},
+				`embedNum`:       {`0`},
+				`outerStr`:       {},
+				`inner.innerNum`: {`0`},
+				`unknown`:        {`0`},
+			},
+		)
+
+		test(
+			Outer{Embed: Embed{EmbedStr: `one`}},
+			set(`embedStr`, `embedNum`),
+			rd.Form{`embedStr`: {`one`}, `embedNum`: {``}},
+		)
+	})
+
+	t.Run(`case-insensitive`, func(t *testing.T) {
+		var tar Outer
+		tracked, err := rd.Form{`EMBEDNUM`: {`0`}}.DecodeTracked(&tar, rd.Config{CaseInsensitive: true})
+		try(err)
+		eq(t, set(`embedNum`), tracked)
+	})
+
+	t.Run(`combined`, func(t *testing.T) {
+		typ := r.TypeOf(time.Time{})
+		rd.RegisterCombiner(typ, []string{`year`, `month`, `day`}, combineDate)
+		defer rd.RegisterCombiner(typ, nil, nil)
+
+		var tar struct {
+			One time.Time `json:"one"`
+			Two time.Time `json:"two"`
+		}
+		tracked, err := rd.Form{`one.year`: {``}}.DecodeTracked(&tar, rd.Config{})
+		try(err)
+		eq(t, set(`one`), tracked)
+	})
+
+	t.Run(`error`, func(t *testing.T) {
+		var tar Outer
+		tracked, err := rd.Form{`embedNum`: {`garbage`}}.DecodeTracked(&tar, rd.Config{})
+		errs(t, `failed to parse "garbage"`, err)
+		eq(t, rd.Set(nil), tracked)
+	})
+}
+
+func TestForm_DecodeAllowed(t *testing.T) {
+	src := rd.Form{
+		`embedStr`:       {`embed val`},
+		`embedNum`:       {`10`},
+		`outerStr`:       {`outer val`},
+		`inner.innerStr`: {`inner val`},
+		`inner.innerNum`: {`20`},
+		`unknown`:        {`one`},
+	}
+
+	test := func(exp Outer, allowed rd.Set) {
+		t.Helper()
+		var tar Outer
+		try(src.DecodeAllowed(allowed, &tar))
+		eq(t, exp, tar)
+	}
+
+	test(Outer{}, nil)
+	test(Outer{}, set(`two`))
+	test(Outer{OuterStr: `outer val`}, set(`outerStr`))
+	test(Outer{Embed: Embed{EmbedNum: 10}, OuterStr: `outer val`}, set(`embedNum`, `outerStr`))
+	test(Outer{Inner: Inner{InnerNum: 20}}, set(`inner.innerNum`))
+	test(testOuter, set(`embedStr`, `embedNum`, `outerStr`, `inner`))
+
+	t.Run(`nil config allows all`, func(t *testing.T) {
+		var tar Outer
+		try(src.DecodeWith(&tar, rd.Config{}))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		conf := rd.Config{Strict: true, Allowed: set(`outerStr`, `inner`)}
+
+		var tar Outer
+		try(rd.Form{`outerStr`: {`one`}, `inner.innerNum`: {`20`}}.DecodeWith(&tar, conf))
+		eq(t, Outer{Inner: Inner{InnerNum: 20}, OuterStr: `one`}, tar)
+
+		err := rd.Form{`outerStr`: {`one`}, `embedNum`: {`10`}, `embedStr`: {``}}.DecodeWith(&tar, conf)
+		errs(t, `disallowed fields ["embedNum" "embedStr"]`, err)
+		errStatus(t, http.StatusBadRequest, err)
+
+		errs(
+			t,
+			`unknown fields ["two"]`,
+			rd.Form{`outerStr`: {`one`}, `two`: {`three`}}.DecodeWith(&tar, conf),
+		)
+	})
+
+	t.Run(`json key`, func(t *testing.T) {
+		src := rd.Form{`_json`: {`{"embedNum": 10}`}, `outerStr`: {`one`}}
+
+		var tar Outer
+		try(src.DecodeWith(&tar, rd.Config{JsonKey: `_json`, Allowed: set(`outerStr`)}))
+		eq(t, Outer{OuterStr: `one`}, tar)
+
+		tar = Outer{}
+		try(src.DecodeWith(&tar, rd.Config{JsonKey: `_json`, Allowed: set(`outerStr`, `_json`)}))
+		eq(t, Outer{Embed: Embed{EmbedNum: 10}, OuterStr: `one`}, tar)
+
+		errs(
+			t,
+			`disallowed fields ["_json"]`,
+			src.DecodeWith(&tar, rd.Config{JsonKey: `_json`, Allowed: set(`outerStr`), Strict: true}),
+		)
+	})
+
+	t.Run(`querystring`, func(t *testing.T) {
+		var tar struct {
+			One   string `json:"one"`
+			Two   string `json:"two"`
+			Query string `rd:"querystring"`
+		}
+		try(rd.Form{`one`: {`1`}, `two`: {`2`}, `three`: {`3`}}.DecodeAllowed(set(`one`), &tar))
+		eq(t, `1`, tar.One)
+		eq(t, ``, tar.Two)
+		eq(t, `three=3`, tar.Query)
+	})
+
+	t.Run(`tracked`, func(t *testing.T) {
+		var tar Outer
+		tracked, err := src.DecodeTracked(&tar, rd.Config{Allowed: set(`outerStr`)})
+		try(err)
+		eq(t, set(`outerStr`), tracked)
+	})
+}
+
+func TestForm_DecodeWith_StrictArity(t *testing.T) {
+	conf := rd.Config{StrictArity: true}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form(testOuterQuery).DecodeWith(&tar, conf))
+		eq(t, testOuterSimple, tar)
+
+		var pair TarPair
+		try(rd.Form{`one`: {`10`, `20`}, `two[]`: {`30`, `40`}}.DecodeWith(&pair, conf))
+		eq(t, TarPair{One: []int{10, 20}, Two: []int{30, 40}}, pair)
+	})
+
+	t.Run(`unknown keys are allowed`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`outerStr`: {`one`}, `two`: {`three`, `four`}}.DecodeWith(&tar, conf))
+		eq(t, Outer{OuterStr: `one`}, tar)
+	})
+
+	test := func(msg string, tar interface{}, src rd.Form, conf rd.Config) {
+		t.Helper()
+		err := src.DecodeWith(tar, conf)
+		errs(t, msg, err)
+		errStatus(t, http.StatusBadRequest, err)
+		try(src.Decode(tar))
+	}
+
+	t.Run(`scalar`, func(t *testing.T) {
+		test(`expected at most one value for field "outerStr", got 2`, new(Outer), rd.Form{`outerStr`: {`one`, `two`}}, conf)
+		test(`expected at most one value for field "val", got 3`, new(TarInt), rd.Form{`val`: {`1`, `2`, `3`}}, conf)
+		test(`expected at most one value for field "val", got 2`, new(TarInt), rd.Form{`val[]`: {`1`, `2`}}, conf)
+		test(`expected at most one value for field "inner.innerNum", got 2`, new(Outer), rd.Form{`inner.innerNum`: {`1`, `2`}}, conf)
+		test(`expected at most one value for field "val", got 2`, new(TarPtrInt), rd.Form{`val`: {`1`, `2`}}, conf)
+	})
+
+	t.Run(`map entry`, func(t *testing.T) {
+		var tar struct {
+			Meta map[string]string `json:"meta"`
+		}
+		test(`expected at most one value for field "meta[one]", got 2`, &tar, rd.Form{`meta[one]`: {`two`, `three`}}, conf)
+	})
+
+	t.Run(`json key`, func(t *testing.T) {
+		test(
+			`expected at most one value for field "_json", got 2`,
+			new(Outer),
+			rd.Form{`_json`: {`{}`, `{}`}},
+			rd.Config{StrictArity: true, JsonKey: `_json`},
+		)
+	})
+
+	t.Run(`combined`, func(t *testing.T) {
+		typ := r.TypeOf(time.Time{})
+		rd.RegisterCombiner(typ, []string{`year`, `month`, `day`}, combineDate)
+		defer rd.RegisterCombiner(typ, nil, nil)
+
+		var tar struct {
+			One time.Time `json:"one"`
+		}
+		test(
+			`expected at most one value for field "one.year", got 2`,
+			&tar,
+			rd.Form{`one.year`: {`2000`, `2001`}, `one.month`: {`1`}, `one.day`: {`2`}},
+			conf,
+		)
+	})
+
+	t.Run(`array`, func(t *testing.T) {
+		var tar struct {
+			Val [2]int `json:"val"`
+		}
+		try(rd.Form{`val`: {`10`, `20`}}.DecodeWith(&tar, conf))
+		eq(t, [2]int{10, 20}, tar.Val)
+	})
+
+	t.Run(`array parser`, func(t *testing.T) {
+		var tar struct {
+			Val TarArrayParser `json:"val"`
+		}
+		try(rd.Form{`val`: {`10`}}.DecodeWith(&tar, conf))
+		eq(t, TarArrayParser{'1', '0'}, tar.Val)
+	})
+
+	t.Run(`implied by strict`, func(t *testing.T) {
+		var tar struct {
+			Meta map[string]string `json:"meta"`
+		}
+		errs(
+			t,
+			`expected at most one value for field "meta[one]", got 2`,
+			rd.Form{`meta[one]`: {`two`, `three`}}.DecodeWith(&tar, rd.Config{Strict: true}),
+		)
+	})
+}
+
+func TestForm_DecodeWithPresenceStruct(t *testing.T) {
+	type EmbedPresence struct {
+		EmbedStr bool `json:"embedStr"`
+		EmbedNum bool `json:"embedNum"`
+	}
+
+	type InnerPresence struct {
+		InnerStr bool  `json:"innerStr"`
+		InnerNum *bool `json:"innerNum"`
+	}
+
+	type Presence struct {
+		EmbedPresence
+		Inner    InnerPresence `json:"inner"`
+		OuterStr bool          `json:"outerStr"`
+	}
+
+	test := func(expTar Outer, expPresence Presence, src rd.Form) {
+		t.Helper()
+		var tar Outer
+		presence := Presence{OuterStr: true, Inner: InnerPresence{InnerStr: true}}
+		try(src.DecodeWithPresenceStruct(&tar, &presence))
+		eq(t, expTar, tar)
+		eq(t, expPresence, presence)
+	}
+
+	test(Outer{}, Presence{}, nil)
+	test(Outer{}, Presence{}, rd.Form{`unknown`: {`one`}})
+
+	test(
+		testOuterSimple,
+		Presence{EmbedPresence: EmbedPresence{true, true}, OuterStr: true},
+		rd.Form(testOuterQuery),
+	)
+
+	test(
+		Outer{},
+		Presence{EmbedPresence: EmbedPresence{EmbedNum: true}, OuterStr: true},
+		rd.Form{`embedNum`: {`0`}, `outerStr`: {``}},
+	)
+
+	yes := true
+	test(
+		Outer{Inner: Inner{InnerNum: 20}},
+		Presence{Inner: InnerPresence{InnerNum: &yes}},
+		rd.Form{`inner.innerNum`: {`20`}},
+	)
+
+	t.Run(`nested presence`, func(t *testing.T) {
+		var tar Outer
+		var presence struct {
+			Inner    bool `json:"inner"`
+			OuterStr bool `json:"outerStr"`
+		}
+		try(rd.Form{`inner.innerStr`: {`one`}}.DecodeWithPresenceStruct(&tar, &presence))
+		eq(t, true, presence.Inner)
+		eq(t, false, presence.OuterStr)
+	})
+
+	t.Run(`mismatch`, func(t *testing.T) {
+		var presence struct {
+			OuterStr bool `json:"outerStr"`
+			Other    bool `json:"other"`
+		}
+		err := rd.Form{`outerStr`: {`one`}}.DecodeWithPresenceStruct(new(Outer), &presence)
+		errs(t, `presence field "other" doesn't correspond to any field of rd_test.Outer`, err)
+		errStatus(t, http.StatusInternalServerError, err)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		var presence Presence
+		err := rd.Form{`embedNum`: {`garbage`}}.DecodeWithPresenceStruct(new(Outer), &presence)
+		errs(t, `failed to parse "garbage"`, err)
+		errStatus(t, http.StatusInternalServerError, rd.Form{}.DecodeWithPresenceStruct(new(Outer), presence))
+	})
+}
+
+func TestForm_Decode_required(t *testing.T) {
+	type Tar struct {
+		Email string `json:"email,required"`
+		Name  string `json:"name,omitempty,required"`
+		Age   int    `json:"age"`
+		Inner struct {
+			InnerStr string `json:"innerStr,required"`
+		} `json:"inner"`
+	}
+
+	missing := func(exp []string, src rd.Form) {
+		t.Helper()
+
+		tar := Tar{Age: 10}
+		err := src.Decode(&tar)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, true, errors.Is(err, rd.ErrMissing))
+		eq(t, Tar{Age: 10}, tar)
+
+		var names []string
+		var errs rd.Errs
+		if errors.As(err, &errs) {
+			for _, err := range errs {
+				names = append(names, err.(rd.FieldErr).Name)
+			}
+		} else {
+			var field rd.FieldErr
+			errors.As(err, &field)
+			names = append(names, field.Name)
+		}
+		eq(t, exp, names)
+	}
+
+	missing([]string{`email`, `name`, `inner.innerStr`}, nil)
+	missing([]string{`email`, `name`, `inner.innerStr`}, rd.Form{})
+	missing([]string{`email`, `name`, `inner.innerStr`}, rd.Form{`age`: {`20`}})
+	missing([]string{`email`, `inner.innerStr`}, rd.Form{`email`: {``}, `name`: {`one`}})
+	missing([]string{`email`}, rd.Form{`email`: {}, `name`: {`one`}, `inner.innerStr`: {`two`}})
+	missing([]string{`name`}, rd.Form{`email`: {`one`}, `name`: {``}, `inner.innerStr`: {`two`}})
+
+	t.Run(`message`, func(t *testing.T) {
+		err := rd.Form{`email`: {`one`}, `inner.innerStr`: {`two`}}.Decode(new(Tar))
+		errs(t, `invalid field "name": missing required field`, err)
+	})
+
+	t.Run(`present`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`email`: {`one`}, `name`: {`two`}, `inner.innerStr`: {`three`}}.Decode(&tar))
+		eq(t, `one`, tar.Email)
+		eq(t, `two`, tar.Name)
+		eq(t, `three`, tar.Inner.InnerStr)
+	})
+
+	t.Run(`not required without option`, func(t *testing.T) {
+		var tar struct {
+			One string `json:"one,omitempty"`
+			Two string `json:"required"`
+		}
+		try(rd.Form{}.Decode(&tar))
+		try(rd.Form{`three`: {`four`}}.Decode(&tar))
+	})
+
+	t.Run(`other tag`, func(t *testing.T) {
+		var tar struct {
+			One string `json:"one,required" form:"one"`
+			Two string `json:"two" form:"two,required"`
+		}
+		err := rd.Form{`one`: {`1`}}.DecodeWith(&tar, rd.Config{Tag: `form`})
+		errs(t, `invalid field "two": missing required field`, err)
+	})
+
+	t.Run(`allowed`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`email`: {`one`}}.DecodeAllowed(set(`email`, `age`), &tar))
+		eq(t, `one`, tar.Email)
+	})
+
+	t.Run(`request`, func(t *testing.T) {
+		var tar Tar
+		err := rd.Decode(Req{}.Ptr(), &tar)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, true, errors.Is(err, rd.ErrMissing))
+	})
+}
+
+func TestForm_Decode_csv(t *testing.T) {
+	type Tar struct {
+		Ids    []int             `json:"ids,csv"`
+		Strs   []string          `json:"strs,omitempty,csv"`
+		Parser SliceParserStruct `json:"parser,csv"`
+		Plain  []string          `json:"plain"`
+		Str    string            `json:"str,csv"`
+	}
+
+	t.Run(`single value`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{
+			`ids`:    {`1,2,3`},
+			`strs`:   {`one,,two`},
+			`parser`: {`30,40`},
+			`plain`:  {`five,six`},
+			`str`:    {`seven,eight`},
+		}.Decode(&tar))
+
+		eq(t, Tar{
+			Ids:    []int{1, 2, 3},
+			Strs:   []string{`one`, ``, `two`},
+			Parser: SliceParserStruct{Inner: []int{30, 40}},
+			Plain:  []string{`five,six`},
+			Str:    `seven,eight`,
+		}, tar)
+	})
+
+	t.Run(`repeated keys`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`ids`: {`1`, `2,3`, `4`}, `ids[]`: {`5,6`}}.Decode(&tar))
+		eq(t, []int{1, 2, 3, 4, 5, 6}, tar.Ids)
+	})
+
+	t.Run(`invalid elements`, func(t *testing.T) {
+		errs(t, `failed to parse "" into int`, rd.Form{`ids`: {`1,,2`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`null`, func(t *testing.T) {
+		tar := Tar{Ids: []int{1}}
+		try(rd.Form{`ids`: {``}}.Decode(&tar))
+		eq(t, []int(nil), tar.Ids)
+	})
+}
+
+func TestForm_DecodeWith_EmptySentinel(t *testing.T) {
+	type Tar struct {
+		Strs   []string           `json:"strs"`
+		Ptr    *[]int             `json:"ptr"`
+		Parser *SliceParserStruct `json:"parser"`
+		Str    string             `json:"str"`
+	}
+
+	conf := rd.Config{EmptySentinel: `__empty__`}
+
+	t.Run(`sentinel`, func(t *testing.T) {
+		tar := Tar{Strs: []string{`one`}}
+		try(rd.Form{
+			`strs`:   {`__empty__`},
+			`ptr`:    {`__empty__`},
+			`parser`: {`__empty__`},
+			`str`:    {`__empty__`},
+		}.DecodeWith(&tar, conf))
+
+		eq(t, Tar{
+			Strs:   []string{},
+			Ptr:    &[]int{},
+			Parser: &SliceParserStruct{},
+			Str:    `__empty__`,
+		}, tar)
+
+		if tar.Strs == nil || *tar.Ptr == nil {
+			t.Fatalf(`expected non-nil empty slices`)
+		}
+	})
+
+	t.Run(`normal values`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`strs`: {`one`, `__empty__`}}.DecodeWith(&tar, conf))
+		eq(t, []string{`one`, `__empty__`}, tar.Strs)
+
+		errs(t, `failed to parse "__empty__" into int`, rd.Form{`ptr`: {`__empty__`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`null`, func(t *testing.T) {
+		tar := Tar{Strs: []string{`one`}}
+		try(rd.Form{`strs`: {``}}.DecodeWith(&tar, conf))
+		eq(t, []string(nil), tar.Strs)
+	})
+
+	t.Run(`absence`, func(t *testing.T) {
+		tar := Tar{Strs: []string{`one`}}
+		try(rd.Form{`str`: {`two`}}.DecodeWith(&tar, conf))
+		eq(t, Tar{Strs: []string{`one`}, Str: `two`}, tar)
+	})
+
+	t.Run(`disabled`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`strs`: {`__empty__`}}.Decode(&tar))
+		eq(t, []string{`__empty__`}, tar.Strs)
+	})
+}
+
+func TestForm_Decode_base(t *testing.T) {
+	type Tar struct {
+		Dec  int            `json:"dec"`
+		Auto int            `json:"auto"  rd:"base=0"`
+		Hex  uint8          `json:"hex"   rd:"base=16"`
+		Bin  []int          `json:"bin"   rd:"base=2"`
+		Map  map[int]uint   `json:"map"   rd:"base=0"`
+		Ptr  *int           `json:"ptr"   rd:",base=0"`
+		Str  string         `json:"str"   rd:"base=0"`
+		Dur  time.Duration  `json:"dur"   rd:"base=0"`
+		Opts map[string]int `json:"opts"`
+	}
+
+	t.Run(`decimal by default`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`dec`: {`010`}, `opts[one]`: {`020`}}.Decode(&tar))
+		eq(t, Tar{Dec: 10, Opts: map[string]int{`one`: 20}}, tar)
+
+		errs(t, `failed to parse "0xff" into int`, rd.Form{`dec`: {`0xff`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`prefixes`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{
+			`auto`:   {`0xff`},
+			`hex`:    {`ff`},
+			`bin`:    {`1010`, `11`},
+			`map[9]`: {`0b101`},
+			`ptr`:    {`-0o17`},
+			`str`:    {`0x10`},
+			`dur`:    {`10`},
+		}.Decode(&tar))
+
+		eq(t, Tar{
+			Auto: 255,
+			Hex:  255,
+			Bin:  []int{10, 3},
+			Map:  map[int]uint{9: 5},
+			Ptr:  ptrInt(-15),
+			Str:  `0x10`,
+			Dur:  10,
+		}, tar)
+	})
+
+	t.Run(`leading zeros are octal`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`auto`: {`010`}}.Decode(&tar))
+		eq(t, 8, tar.Auto)
+
+		err := rd.Form{`auto`: {`09`}}.Decode(new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `failed to parse "09" into int`, err)
+	})
+
+	t.Run(`map keys are decimal`, func(t *testing.T) {
+		errs(t, `failed to parse "0x1" into int`, rd.Form{`map[0x1]`: {`1`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`out of range`, func(t *testing.T) {
+		errs(t, `failed to parse "100" into uint8`, rd.Form{`hex`: {`100`}}.Decode(new(Tar)))
+	})
+
+	t.Run(`invalid base`, func(t *testing.T) {
+		type Bad struct {
+			Num int `json:"num" rd:"base=1"`
+		}
+
+		err := rd.Form{`num`: {`1`}}.Decode(new(Bad))
+		errStatus(t, http.StatusInternalServerError, err)
+		errs(t, `invalid integer base in "rd" tag of field "num"`, err)
+	})
+}
+
+func TestForm_Decode_default(t *testing.T) {
+	type Inner struct {
+		Size int `json:"size" default:"20"`
+	}
+
+	type Tar struct {
+		Page  int      `json:"page"  default:"1"`
+		Sort  string   `json:"sort"  default:"name"`
+		Ids   []int    `json:"ids,csv" default:"1,2"`
+		Hex   *uint8   `json:"hex"   default:"ff" rd:"base=16"`
+		Zero  string   `json:"zero"  default:""`
+		Plain string   `json:"plain"`
+		Inner *Inner   `json:"inner"`
+		Tags  []string `json:"tags"`
+	}
+
+	def := func() Tar {
+		return Tar{
+			Page:  1,
+			Sort:  `name`,
+			Ids:   []int{1, 2},
+			Hex:   ptrUint8(255),
+			Plain: `plain`,
+			Inner: &Inner{Size: 20},
+		}
+	}
+
+	t.Run(`missing`, func(t *testing.T) {
+		tar := Tar{Zero: `zero`, Plain: `plain`}
+		try(rd.Form{`tags`: {`one`}}.Decode(&tar))
+
+		exp := def()
+		exp.Tags = []string{`one`}
+		eq(t, exp, tar)
+	})
+
+	t.Run(`empty form`, func(t *testing.T) {
+		tar := Tar{Zero: `zero`, Plain: `plain`}
+		try(rd.Form{}.Decode(&tar))
+		eq(t, def(), tar)
+
+		try(rd.Form(nil).Decode(new(Tar)))
+	})
+
+	t.Run(`present`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`page`: {`3`}, `ids`: {`4`}, `inner.size`: {`5`}}.Decode(&tar))
+		eq(t, 3, tar.Page)
+		eq(t, []int{4}, tar.Ids)
+		eq(t, &Inner{Size: 5}, tar.Inner)
+		eq(t, `name`, tar.Sort)
+	})
+
+	t.Run(`null`, func(t *testing.T) {
+		tar := def()
+		try(rd.Form{`page`: {``}, `sort`: nil, `plain`: {``}}.Decode(&tar))
+		eq(t, 0, tar.Page)
+		eq(t, ``, tar.Sort)
+		eq(t, ``, tar.Plain)
+	})
+
+	t.Run(`DefaultOnNull`, func(t *testing.T) {
+		tar := def()
+		tar.Page = 10
+		try(rd.Form{`page`: {``}, `sort`: nil, `plain`: {``}}.DecodeWith(&tar, rd.Config{DefaultOnNull: true}))
+		eq(t, 1, tar.Page)
+		eq(t, `name`, tar.Sort)
+		eq(t, ``, tar.Plain)
+	})
+
+	t.Run(`JsonKey`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`json`: {`{"page": 7}`}}.DecodeWith(&tar, rd.Config{JsonKey: `json`}))
+		eq(t, 7, tar.Page)
+		eq(t, `name`, tar.Sort)
+	})
+
+	t.Run(`Allowed`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`tags`: {`one`}}.DecodeAllowed(rd.Set{`page`: {}, `tags`: {}}, &tar))
+		eq(t, Tar{Page: 1, Tags: []string{`one`}}, tar)
+	})
+
+	t.Run(`required`, func(t *testing.T) {
+		type Tar struct {
+			Page int `json:"page,required" default:"1"`
+		}
+		errs(t, `invalid field "page": missing required field`, rd.Form{}.Decode(new(Tar)))
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		type Tar struct {
+			Page int `json:"page" default:"one"`
+		}
+
+		test := func(err error) {
+			t.Helper()
+			errStatus(t, http.StatusInternalServerError, err)
+			errs(t, `invalid default value of field "page": failed to parse "one" into int`, err)
+		}
+
+		test(rd.Form{}.Decode(new(Tar)))
+		test(rd.Form{`other`: {`two`}}.Decode(new(Tar)))
+		test(rd.Form{`page`: {``}}.DecodeWith(new(Tar), rd.Config{DefaultOnNull: true}))
+	})
+}
+
+func TestForm_DecodeWith_LenientBool(t *testing.T) {
+	type Tar struct {
+		Bool  bool            `json:"bool"`
+		Ptr   *bool           `json:"ptr"`
+		Bools []bool          `json:"bools"`
+		Str   string          `json:"str"`
+		Map   map[string]bool `json:"map"`
+	}
+
+	conf := rd.Config{LenientBool: true}
+
+	for _, src := range []string{`true`, `t`, `1`, `on`, `yes`, `ON`, `Yes`, `TRUE`} {
+		var tar Tar
+		try(rd.Form{`bool`: {src}, `ptr`: {src}, `str`: {src}}.DecodeWith(&tar, conf))
+		eq(t, Tar{Bool: true, Ptr: ptrBool(true), Str: src}, tar)
+	}
+
+	for _, src := range []string{`false`, `f`, `0`, `off`, `no`, `OFF`, `No`} {
+		tar := Tar{Bool: true}
+		try(rd.Form{`bool`: {src}, `ptr`: {src}}.DecodeWith(&tar, conf))
+		eq(t, Tar{Ptr: ptrBool(false)}, tar)
+	}
+
+	t.Run(`slice`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`bools`: {`on`, `0`, `true`}}.DecodeWith(&tar, conf))
+		eq(t, []bool{true, false, true}, tar.Bools)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		errs(t, `failed to parse "2" into bool`, rd.Form{`bool`: {`2`}}.DecodeWith(new(Tar), conf))
+		errs(t, `failed to parse "y" into bool`, rd.Form{`bools`: {`on`, `y`}}.DecodeWith(new(Tar), conf))
+	})
+
+	t.Run(`map values`, func(t *testing.T) {
+		errs(t, `failed to parse "on" into bool`, rd.Form{`map[one]`: {`on`}}.DecodeWith(new(Tar), conf))
+	})
+
+	t.Run(`disabled`, func(t *testing.T) {
+		errs(t, `failed to parse "on" into bool`, rd.Form{`bool`: {`on`}}.Decode(new(Tar)))
+		errs(t, `failed to parse "1" into bool`, rd.Form{`bools`: {`1`}}.Decode(new(Tar)))
+	})
+}
+
+func TestForm_Decode_array(t *testing.T) {
+	type Inner struct {
+		N [1]int `json:"n"`
+	}
+
+	type Tar struct {
+		Point [2]float64     `json:"point"`
+		Ptr   *[2]int        `json:"ptr"`
+		Ptrs  [2]*int        `json:"ptrs"`
+		Csv   [3]int         `json:"csv,csv"`
+		Key   BinaryKey      `json:"key"`
+		Arr   TarArrayParser `json:"arr"`
+		Bools [2]bool        `json:"bools"`
+		Empty [0]int         `json:"empty"`
+		Inner Inner          `json:"inner"`
+	}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{
+			`point`:   {`1.5`, `2.5`},
+			`ptr`:     {`10`, `20`},
+			`ptrs`:    {`30`, `40`},
+			`csv`:     {`1,2`, `3`},
+			`key`:     {`abcd`},
+			`arr`:     {`ef`},
+			`bools`:   {`on`, `off`},
+			`inner.n`: {`50`},
+		}.DecodeWith(&tar, rd.Config{LenientBool: true}))
+
+		eq(t, [2]float64{1.5, 2.5}, tar.Point)
+		eq(t, &[2]int{10, 20}, tar.Ptr)
+		eq(t, [2]*int{ptrInt(30), ptrInt(40)}, tar.Ptrs)
+		eq(t, [3]int{1, 2, 3}, tar.Csv)
+		eq(t, BinaryKey{'a', 'b', 'c', 'd'}, tar.Key)
+		eq(t, TarArrayParser{'e', 'f'}, tar.Arr)
+		eq(t, [2]bool{true, false}, tar.Bools)
+		eq(t, [1]int{50}, tar.Inner.N)
+	})
+
+	t.Run(`null`, func(t *testing.T) {
+		tar := Tar{Point: [2]float64{1, 2}}
+		try(rd.Form{`point`: {``}}.Decode(&tar))
+		eq(t, [2]float64{}, tar.Point)
+	})
+
+	t.Run(`length mismatch`, func(t *testing.T) {
+		test := func(exp string, src rd.Form) {
+			t.Helper()
+			tar := Tar{Point: [2]float64{1, 2}}
+			err := src.Decode(&tar)
+			errStatus(t, http.StatusBadRequest, err)
+			errs(t, exp, err)
+			eq(t, [2]float64{1, 2}, tar.Point)
+		}
+
+		test(`failed to decode field "point": failed to parse 1 values into [2]float64: expected 2 values`, rd.Form{`point`: {`1.5`}})
+		test(`failed to decode field "point": failed to parse 3 values into [2]float64: expected 2 values`, rd.Form{`point`: {`1`, `2`, `3`}})
+		test(`failed to parse 4 values into [3]int`, rd.Form{`csv`: {`1,2`, `3,4`}})
+	})
+
+	t.Run(`partial failure`, func(t *testing.T) {
+		tar := Tar{Point: [2]float64{1, 2}}
+		errs(t, `failed to parse "two" into float64`, rd.Form{`point`: {`3`, `two`}}.Decode(&tar))
+		eq(t, [2]float64{1, 2}, tar.Point)
+	})
+
+	t.Run(`StrictTypes`, func(t *testing.T) {
+		var tar Tar
+		err := rd.Form{`point`: {`one`, `two`}, `ptrs`: {`1`}}.DecodeStrictTypes(&tar)
+		errs(t, `failed to parse "one" into float64`, err)
+		errs(t, `failed to parse "two" into float64`, err)
+		errs(t, `failed to parse 1 values into [2]*int`, err)
+	})
+}
+
+func TestForm_DecodeWith_Strict(t *testing.T) {
+	conf := rd.Config{Strict: true}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form(testOuterQuery).DecodeWith(&tar, conf))
+		eq(t, testOuterSimple, tar)
+
+		var pair TarPair
+		try(rd.Form{`one`: {`10`, `20`}}.DecodeWith(&pair, conf))
+		eq(t, TarPair{One: []int{10, 20}}, pair)
+	})
+
+	t.Run(`unknown keys`, func(t *testing.T) {
+		src := rd.Form{`outerStr`: {`one`}, `two`: {`three`}, `four`: {`five`}}
+
+		var tar Outer
+		try(src.Decode(&tar))
+		errs(t, `unknown fields ["four" "two"]`, src.DecodeWith(&tar, conf))
+	})
+
+	t.Run(`json key is not unknown`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`_json`: {`{}`}}.DecodeWith(&tar, rd.Config{Strict: true, JsonKey: `_json`}))
+	})
+
+	t.Run(`duplicates`, func(t *testing.T) {
+		src := rd.Form{`outerStr`: {`one`, `two`}}
+
+		var tar Outer
+		try(src.Decode(&tar))
+		eq(t, `one`, tar.OuterStr)
+
+		errs(t, `expected at most one value for field "outerStr", got 2`, src.DecodeWith(&tar, conf))
+	})
+}
+
+func TestForm_DecodeStrictTypes(t *testing.T) {
+	type Tar struct {
+		Nums []int  `json:"nums"`
+		Num  int    `json:"num"`
+		Bool bool   `json:"bool"`
+		Str  string `json:"str"`
+	}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`nums`: {`10`, `20`}, `num`: {`30`, `40`}, `str`: {`one`}}.DecodeStrictTypes(&tar))
+		eq(t, Tar{Nums: []int{10, 20}, Num: 30, Str: `one`}, tar)
+	})
+
+	t.Run(`all failures`, func(t *testing.T) {
+		src := rd.Form{
+			`nums`: {`10`, `one`, `20`, ``},
+			`num`:  {`30`, `two`},
+			`bool`: {`three`},
+			`str`:  {`four`},
+		}
+
+		var tar Tar
+		errs(t, `failed to parse "one" into int`, src.Decode(&tar))
+
+		tar = Tar{}
+		err := src.DecodeStrictTypes(&tar)
+		errStatus(t, 400, err)
+		eq(t, Tar{Num: 30, Str: `four`}, tar)
+
+		var list rd.Errs
+		if !errors.As(err, &list) {
+			t.Fatalf(`expected rd.Errs, got %#v`, err)
+		}
+		eq(t, 3, len(list))
+
+		eq(t, `nums`, list[0].(rd.FieldErr).Name)
+		eq(t, `num`, list[1].(rd.FieldErr).Name)
+		eq(t, `bool`, list[2].(rd.FieldErr).Name)
+
+		eq(t, 2, len(list[0].(rd.FieldErr).Cause.(rd.Errs)))
+		errs(t, `invalid field "nums": failed to parse "one" into int`, list[0])
+		errs(t, `; failed to parse "" into int`, list[0])
+		errs(t, `invalid field "num": failed to parse "two" into int`, list[1])
+		errs(t, `invalid field "bool": failed to parse "three" into bool`, list[2])
+	})
+
+	t.Run(`extra values`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`num`: {`10`, `garbage`}}.Decode(&tar))
+		eq(t, 10, tar.Num)
+
+		errs(t, `invalid field "num": failed to parse "garbage" into int`, rd.Form{`num`: {`10`, `garbage`}}.DecodeStrictTypes(&tar))
+	})
+}
+
+func TestForm_DecodeAll(t *testing.T) {
+	type Tar struct {
+		Nums []int  `json:"nums"`
+		Num  int    `json:"num"`
+		Bool bool   `json:"bool"`
+		Str  string `json:"str"`
+	}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`nums`: {`10`, `20`}, `num`: {`30`, `garbage`}, `str`: {`one`}}.DecodeAll(&tar))
+		eq(t, Tar{Nums: []int{10, 20}, Num: 30, Str: `one`}, tar)
+	})
+
+	t.Run(`single failure`, func(t *testing.T) {
+		var tar Tar
+		err := rd.Form{`num`: {`one`}, `str`: {`two`}}.DecodeAll(&tar)
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, `invalid field "num": failed to parse "one" into int`, err)
+		eq(t, Tar{Str: `two`}, tar)
+
+		var field rd.FieldErr
+		eq(t, true, errors.As(err, &field))
+		eq(t, `num`, field.Name)
+	})
+
+	t.Run(`all failures`, func(t *testing.T) {
+		src := rd.Form{
+			`nums`: {`10`, `one`, `two`},
+			`num`:  {`three`},
+			`bool`: {`four`},
+			`str`:  {`five`},
+		}
+
+		var tar Tar
+		err := src.DecodeAll(&tar)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, Tar{Str: `five`}, tar)
+		errs(
+			t,
+			`invalid field "nums": failed to parse "one" into int: strconv.ParseInt: parsing "one": invalid syntax; `+
+				`invalid field "num": failed to parse "three" into int: strconv.ParseInt: parsing "three": invalid syntax; `+
+				`invalid field "bool": failed to parse "four" into bool`,
+			err,
+		)
+
+		var list rd.Errs
+		if !errors.As(err, &list) {
+			t.Fatalf(`expected rd.Errs, got %#v`, err)
+		}
+		eq(t, 3, len(list))
+		eq(t, `nums`, list[0].(rd.FieldErr).Name)
+		eq(t, `num`, list[1].(rd.FieldErr).Name)
+		eq(t, `bool`, list[2].(rd.FieldErr).Name)
+
+		var field rd.FieldErr
+		eq(t, true, errors.As(err, &field))
+		eq(t, `nums`, field.Name)
+	})
+
+	// Used by "errors" before Go 1.20, which doesn't support `Unwrap() []error`.
+	t.Run(`Is and As`, func(t *testing.T) {
+		list := rd.Errs{rd.FieldErr{`one`, io.EOF}, rd.FieldErr{`two`, rd.ErrMissing}}
+
+		eq(t, true, list.Is(rd.ErrMissing))
+		eq(t, true, list.Is(io.EOF))
+		eq(t, false, list.Is(io.ErrUnexpectedEOF))
+
+		var field rd.FieldErr
+		eq(t, true, list.As(&field))
+		eq(t, `one`, field.Name)
+
+		var syntax rd.JsonSyntaxError
+		eq(t, false, list.As(&syntax))
+	})
+}
+
+func TestForm_Decode_fieldErr(t *testing.T) {
+	type Inner struct {
+		Email EmailParser `json:"email"`
+	}
+
+	type Tar struct {
+		Email  EmailParser      `json:"email"`
+		Emails EmailSliceParser `json:"emails"`
+		Nums   []int            `json:"nums"`
+		Num    int              `json:"num"`
+		Inner  Inner            `json:"inner"`
+	}
+
+	test := func(exp string, src rd.Form) {
+		t.Helper()
+		err := src.Decode(new(Tar))
+		errStatus(t, http.StatusBadRequest, err)
+		errs(t, exp, err)
+	}
+
+	test(`failed to decode field "email": invalid email`, rd.Form{`email`: {`one`}})
+	test(`failed to decode field "emails": invalid email`, rd.Form{`emails`: {`one@two`, `three`}})
+	test(`failed to decode field "nums": failed to parse "one" into int`, rd.Form{`nums`: {`10`, `one`}})
+	test(`failed to decode field "num": failed to parse "one" into int`, rd.Form{`num`: {`one`}})
+	test(`failed to decode field "inner.email": invalid email`, rd.Form{`inner.email`: {`one`}})
+
+	t.Run(`cause`, func(t *testing.T) {
+		err := rd.Form{`email`: {`one`}}.Decode(new(Tar))
+		eq(t, true, errors.Is(err, errEmail))
+	})
+
+	t.Run(`aggregated`, func(t *testing.T) {
+		err := rd.Form{`email`: {`one`}}.DecodeAll(new(Tar))
+		errs(t, `invalid field "email": invalid email`, err)
+		if strings.Contains(err.Error(), `failed to decode field`) {
+			t.Fatalf(`unexpected double wrapping: %v`, err)
+		}
+	})
+}
+
+func TestForm_DecodeMerge(t *testing.T) {
+	type Tar struct {
+		Str  string   `json:"str"`
+		Num  int      `json:"num"`
+		Ptr  *int     `json:"ptr"`
+		List []string `json:"list"`
+		Page int      `json:"page" default:"1"`
+	}
+
+	prev := func() Tar {
+		return Tar{Str: `one`, Num: 10, Ptr: ptrInt(20), List: []string{`two`}, Page: 3}
+	}
+
+	src := rd.Form{`str`: {``}, `num`: {`30`}, `ptr`: nil, `list`: {}, `page`: {``}}
+
+	t.Run(`MergeZero`, func(t *testing.T) {
+		tar := prev()
+		try(src.DecodeMerge(&tar, rd.MergeZero))
+		eq(t, Tar{Num: 30}, tar)
+	})
+
+	t.Run(`MergeSkip`, func(t *testing.T) {
+		tar := prev()
+		try(src.DecodeMerge(&tar, rd.MergeSkip))
+
+		exp := prev()
+		exp.Num = 30
+		eq(t, exp, tar)
+	})
+
+	t.Run(`missing`, func(t *testing.T) {
+		for _, mode := range []rd.Merge{rd.MergeZero, rd.MergeSkip} {
+			tar := prev()
+			try(rd.Form{`other`: {``}}.DecodeMerge(&tar, mode))
+			eq(t, prev(), tar)
+
+			tar = prev()
+			try(rd.Form{}.DecodeMerge(&tar, mode))
+			eq(t, prev(), tar)
+		}
+	})
+
+	t.Run(`SkipDefaults`, func(t *testing.T) {
+		tar := prev()
+		try(src.DecodeWith(&tar, rd.Config{DefaultOnNull: true, SkipDefaults: true}))
+		eq(t, Tar{Num: 30}, tar)
+	})
+
+	t.Run(`DefaultOnNull`, func(t *testing.T) {
+		tar := prev()
+		try(src.DecodeWith(&tar, rd.Config{SkipNull: true, DefaultOnNull: true}))
+
+		exp := prev()
+		exp.Num = 30
+		exp.Page = 1
+		eq(t, exp, tar)
+	})
+}
+
+func TestForm_DecodeWith_Tag(t *testing.T) {
+	type Embed struct {
+		EmbedStr string `json:"embedStr" form:"embed_str"`
+	}
+
+	type Tar struct {
+		Embed
+		One   string `json:"one" form:"first"`
+		Two   string `json:"two"`
+		Three string `form:"three,omitempty"`
+		Four  string `json:"four" form:"-"`
+	}
+
+	src := rd.Form{
+		`embedStr`:  {`embed json`},
+		`embed_str`: {`embed form`},
+		`one`:       {`one json`},
+		`first`:     {`one form`},
+		`two`:       {`two json`},
+		`three`:     {`three form`},
+		`four`:      {`four json`},
+	}
+
+	form := rd.Config{Tag: `form`}
+
+	// Alternating verifies that cached fields are not shared between tags.
+	for range iter(2) {
+		var tar Tar
+		try(src.Decode(&tar))
+		eq(t, Tar{Embed: Embed{`embed json`}, One: `one json`, Two: `two json`, Four: `four json`}, tar)
+
+		tar = Tar{}
+		try(src.DecodeWith(&tar, form))
+		eq(t, Tar{Embed: Embed{`embed form`}, One: `one form`, Three: `three form`}, tar)
+	}
+
+	errs(t, `unknown fields ["embedStr" "four" "one" "two"]`, src.DecodeWith(new(Tar), rd.Config{Tag: `form`, Strict: true}))
+}
+
+func TestForm_DecodeWith_Tags(t *testing.T) {
+	src := rd.Form{
+		`embed_str`:       {`embed db`},
+		`embedNum`:        {`10`},
+		`inner.inner_num`: {`20`},
+		`outer_str`:       {`outer db`},
+		`outerStr`:        {`outer json`},
+	}
+
+	// Alternating verifies that cached fields are not shared between tag lists.
+	for range iter(2) {
+		var tar Outer
+		try(src.DecodeWith(&tar, rd.Config{Tags: []string{`db`}}))
+		eq(t, Outer{Embed: Embed{EmbedStr: `embed db`}, Inner: Inner{InnerNum: 20}, OuterStr: `outer db`}, tar)
+
+		tar = Outer{}
+		try(src.DecodeWith(&tar, rd.Config{Tags: []string{`db`, `json`}}))
+		eq(t, Outer{Embed: Embed{EmbedStr: `embed db`}, Inner: Inner{InnerNum: 20}, OuterStr: `outer db`}, tar)
+
+		tar = Outer{}
+		try(src.DecodeWith(&tar, rd.Config{Tags: []string{`json`, `db`}}))
+		eq(t, Outer{Embed: Embed{EmbedNum: 10}, OuterStr: `outer json`}, tar)
+	}
+
+	t.Run(`fallback`, func(t *testing.T) {
+		type Tar struct {
+			One   string `json:"one" form:"first"`
+			Two   string `json:"two"`
+			Three string `json:"three" form:"-"`
+			Four  string `form:"four,required"`
+		}
+
+		conf := rd.Config{Tags: []string{`form`, `json`}}
+
+		var tar Tar
+		try(rd.Form{`one`: {`1`}, `first`: {`2`}, `two`: {`3`}, `three`: {`4`}, `four`: {`5`}}.DecodeWith(&tar, conf))
+		eq(t, Tar{One: `2`, Two: `3`, Four: `5`}, tar)
+
+		errs(t, `invalid field "four": missing required field`, rd.Form{}.DecodeWith(new(Tar), conf))
+	})
+
+	t.Run(`overrides tag`, func(t *testing.T) {
+		var tar Outer
+		try(src.DecodeWith(&tar, rd.Config{Tag: `json`, Tags: []string{`db`}}))
+		eq(t, `outer db`, tar.OuterStr)
+	})
+}
+
+func TestEncodeForm(t *testing.T) {
+	// Decoding the result must produce an equal value.
+	roundTrip := func(src interface{}, conf rd.Config) url.Values {
+		t.Helper()
+		form, err := rd.EncodeFormWith(src, conf)
+		try(err)
+
+		tar := r.New(r.TypeOf(src))
+		try(rd.Form(form).DecodeWith(tar.Interface(), conf))
+		eq(t, src, tar.Elem().Interface())
+		return form
+	}
+
+	eq(t, url.Values(nil), tryVal(rd.EncodeForm(nil)))
+	eq(t, url.Values(nil), tryVal(rd.EncodeForm((*Outer)(nil))))
+	eq(t, url.Values{}, tryVal(rd.EncodeForm(struct{}{})))
+
+	eq(
+		t,
+		url.Values{
+			`embedStr`:       {`embed val`},
+			`embedNum`:       {`10`},
+			`inner.innerStr`: {`inner val`},
+			`inner.innerNum`: {`20`},
+			`outerStr`:       {`outer val`},
+		},
+		roundTrip(testOuter, rd.Config{}),
+	)
+
+	eq(t, roundTrip(testOuter, rd.Config{}), tryVal(rd.EncodeForm(&testOuter)))
+
+	eq(
+		t,
+		url.Values{
+			`embed_str`:       {`embed val`},
+			`embed_num`:       {`10`},
+			`inner.inner_str`: {`inner val`},
+			`inner.inner_num`: {`20`},
+			`outer_str`:       {`outer val`},
+		},
+		roundTrip(testOuter, rd.Config{Tag: `db`}),
+	)
+
+	t.Run(`nil pointers`, func(t *testing.T) {
+		eq(
+			t,
+			url.Values{`inner.innerStr`: {``}, `inner.innerNum`: {`0`}, `outerStr`: {`outer val`}},
+			roundTrip(PtrOuter{OuterStr: `outer val`}, rd.Config{}),
+		)
+
+		eq(
+			t,
+			url.Values{
+				`embedStr`:       {`embed val`},
+				`embedNum`:       {`10`},
+				`inner.innerStr`: {``},
+				`inner.innerNum`: {`0`},
+				`outerStr`:       {`outer val`},
+			},
+			roundTrip(testPtrOuterSimple, rd.Config{}),
+		)
+	})
+
+	t.Run(`types`, func(t *testing.T) {
+		type Tar struct {
+			Str      string         `json:"str"`
+			Int      int            `json:"int"`
+			Hex      uint16         `json:"hex" rd:"base=16"`
+			Float    float64        `json:"float"`
+			Bool     bool           `json:"bool"`
+			Ptr      *int           `json:"ptr"`
+			Ints     []int          `json:"ints"`
+			PtrElems []*string      `json:"ptrElems"`
+			Arr      [2]bool        `json:"arr"`
+			Dict     map[string]int `json:"dict"`
+			Dur      time.Duration  `json:"dur"`
+			Time     time.Time      `json:"time"`
+			Date     time.Time      `json:"date" layout:"2006-01-02"`
+			Ip       net.IP         `json:"ip"`
+			IpNet    net.IPNet      `json:"ipNet"`
+			Addr     netip.Addr     `json:"addr"`
+			Url      url.URL        `json:"url"`
+			Big      *big.Int       `json:"big"`
+			Query    url.Values     `json:"-" rd:"querystring"`
+		}
+
+		str := `one`
+		_, ipNet, _ := net.ParseCIDR(`10.0.0.0/8`)
+
+		form := roundTrip(
+			Tar{
+				Str:      `two`,
+				Int:      -10,
+				Hex:      255,
+				Float:    1.5,
+				Bool:     true,
+				Ptr:      ptrInt(20),
+				Ints:     []int{30, 40},
+				PtrElems: []*string{&str},
+				Arr:      [2]bool{true, false},
+				Dict:     map[string]int{`three`: 50},
+				Dur:      90 * time.Second,
+				Time:     time.Date(1234, 1, 2, 3, 4, 5, 0, time.UTC),
+				Date:     time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+				Ip:       net.ParseIP(`10.0.0.1`),
+				IpNet:    *ipNet,
+				Addr:     netip.MustParseAddr(`::1`),
+				Url:      *tryVal(url.Parse(`https://example.com/four?five=six`)),
+				Big:      big.NewInt(70),
+				Query:    url.Values{`seven`: {`eight`}},
+			},
+			rd.Config{},
+		)
+
+		eq(
+			t,
+			url.Values{
+				`str`:         {`two`},
+				`int`:         {`-10`},
+				`hex`:         {`ff`},
+				`float`:       {`1.5`},
+				`bool`:        {`true`},
+				`ptr`:         {`20`},
+				`ints`:        {`30`, `40`},
+				`ptrElems`:    {`one`},
+				`arr`:         {`true`, `false`},
+				`dict[three]`: {`50`},
+				`dur`:         {`1m30s`},
+				`time`:        {`1234-01-02T03:04:05Z`},
+				`date`:        {`2024-05-06`},
+				`ip`:          {`10.0.0.1`},
+				`ipNet`:       {`10.0.0.0/8`},
+				`addr`:        {`::1`},
+				`url`:         {`https://example.com/four?five=six`},
+				`big`:         {`70`},
+				`seven`:       {`eight`},
+			},
+			form,
+		)
+	})
+
+	t.Run(`omitempty`, func(t *testing.T) {
+		type Tar struct {
+			One   string  `json:"one,omitempty"`
+			Two   string  `json:"two"`
+			Three *string `json:"three,omitempty"`
+			Four  []int   `json:"four,omitempty"`
+			Five  int     `json:"five,omitempty"`
+		}
+
+		empty := ``
+		eq(t, url.Values{`two`: {``}}, roundTrip(Tar{}, rd.Config{}))
+		eq(t, url.Values{`two`: {``}, `three`: {``}}, tryVal(rd.EncodeForm(Tar{Three: &empty})))
+		eq(t, url.Values{`two`: {``}, `five`: {`10`}}, roundTrip(Tar{Five: 10}, rd.Config{}))
+	})
+
+	t.Run(`errors`, func(t *testing.T) {
+		_, err := rd.EncodeForm(`str`)
+		errs(t, `unable to encode string as form: expected struct`, err)
+
+		_, err = rd.EncodeForm(struct {
+			One interface{} `json:"one"`
+		}{10})
+		errs(t, `failed to encode field "one": unable to encode interface {}: unsupported kind interface`, err)
+
+		_, err = rd.EncodeForm(struct {
+			One SliceParserStruct `json:"one"`
+		}{})
+		errs(t, `failed to encode field "one": unable to encode rd_test.SliceParserStruct: implements rd.SliceParser`, err)
+	})
+}
+
+func TestForm_ParseWith(t *testing.T) {
+	test := func(exp rd.Form, src string, sep byte) {
+		t.Helper()
+		var tar rd.Form
+		try(tar.ParseWith(src, sep))
+		eq(t, exp, tar)
+	}
+
+	test(rd.Form{}, ``, ';')
+	test(rd.Form{}, `;;`, ';')
+	test(rd.Form{`one`: {`two`}}, `one=two`, ';')
+	test(rd.Form{`one`: {`two`, `four`}, `three`: {``}}, `one=two;three;one=four;`, ';')
+	test(rd.Form{`one`: {`two&three=four`}}, `one=two&three=four`, ';')
+	test(rd.Form{`one two`: {`three;four`}}, `one+two=three%3Bfour`, ';')
+	test(rd.Form{`one`: {`two=three`}}, `one=two=three`, ';')
+	test(rd.Form{`one`: {`two`}, `three`: {`four`}}, `one=two|three=four`, '|')
+	test(rd.Form{`one`: {`two`}, `three`: {`four`}}, `one=two&three=four`, '&')
+
+	t.Run(`Parse uses "&"`, func(t *testing.T) {
+		var tar rd.Form
+		errs(t, `invalid semicolon separator in query`, tar.Parse(`one=two;three=four`))
+	})
+
+	t.Run(`errors`, func(t *testing.T) {
+		tar := rd.Form{`one`: {`two`}}
+		errs(t, `invalid URL escape "%zz"`, tar.ParseWith(`three=four;five=%zz`, ';'))
+		errs(t, `invalid URL escape "%zz"`, tar.ParseWith(`%zz=four`, ';'))
+		eq(t, rd.Form{`one`: {`two`}}, tar)
+	})
+
+	t.Run(`decode`, func(t *testing.T) {
+		var form rd.Form
+		try(form.ParseWith(`embedStr=embed+val;embedNum=10;outerStr=outer+val`, ';'))
+
+		var tar Outer
+		try(form.Decode(&tar))
+		eq(t, testOuterSimple, tar)
+	})
+}
+
+func TestForm_DecodeWith_Transform(t *testing.T) {
+	type Tar struct {
+		Email string            `json:"email"`
+		Num   *int              `json:"num"`
+		Strs  []string          `json:"strs"`
+		Csv   []string          `json:"csv,csv"`
+		Dict  map[string]string `json:"dict"`
+		Def   string            `json:"def" default:" def "`
+	}
+
+	var names []string
+	conf := rd.Config{Transform: func(name, input string) string {
+		names = append(names, name)
+		return strings.ToLower(strings.TrimSpace(input))
+	}}
+
+	src := rd.Form{
+		`email`:     {` One@Example.COM `},
+		`num`:       {` `},
+		`strs`:      {` One`, `TWO `},
+		`csv`:       {` A,B `},
+		`dict[Key]`: {` Val `},
+		`unknown`:   {` Three `},
+	}
+
+	num := 10
+	tar := Tar{Num: &num}
+	try(src.DecodeWith(&tar, conf))
+
+	eq(
+		t,
+		Tar{
+			Email: `one@example.com`,
+			Strs:  []string{`one`, `two`},
+			Csv:   []string{`a`, `b`},
+			Dict:  map[string]string{`Key`: `val`},
+			Def:   ` def `,
+		},
+		tar,
+	)
+
+	sort.Strings(names)
+	eq(t, []string{`csv`, `dict`, `email`, `num`, `strs`, `strs`}, names)
+
+	// The form is unchanged.
+	eq(t, []string{` One@Example.COM `}, src[`email`])
+
+	t.Run(`disabled by default`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `failed to parse " " into int`, src.Decode(&tar))
+		eq(t, ` One@Example.COM `, tar.Email)
+	})
+}
+
+func TestForm_DecodeWith_CaseInsensitive(t *testing.T) {
+	conf := rd.Config{CaseInsensitive: true}
+	src := rd.Form{`EmbedStr`: {`one`}, `OUTERSTR`: {`two`}, `embednum`: {`10`}}
+
+	var tar Outer
+	try(src.Decode(&tar))
+	eq(t, Outer{}, tar)
+
+	try(src.DecodeWith(&tar, conf))
+	eq(t, Outer{Embed: Embed{EmbedStr: `one`, EmbedNum: 10}, OuterStr: `two`}, tar)
+
+	t.Run(`exact match is preferred`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form{`OuterStr`: {`one`}, `outerStr`: {`two`}, `OUTERSTR`: {`three`}}.DecodeWith(&tar, conf))
+		eq(t, `two`, tar.OuterStr)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		conf := rd.Config{CaseInsensitive: true, Strict: true}
+		try(src.DecodeWith(new(Outer), conf))
+		errs(t, `unknown fields ["Unknown"]`, rd.Form{`OuterStr`: {`one`}, `Unknown`: {`two`}}.DecodeWith(new(Outer), conf))
+	})
+
+	t.Run(`raw`, func(t *testing.T) {
+		raw, err := src.DecodeRaw(new(Outer), conf)
+		try(err)
+		eq(t, rd.Form{`embedStr`: {`one`}, `outerStr`: {`two`}, `embedNum`: {`10`}}, raw)
+	})
+
+	t.Run(`source is not modified`, func(t *testing.T) {
+		eq(t, rd.Form{`EmbedStr`: {`one`}, `OUTERSTR`: {`two`}, `embednum`: {`10`}}, src)
+	})
+}
+
+func TestForm_DecodeWith_LowercaseKeys(t *testing.T) {
+	conf := rd.Config{LowercaseKeys: true}
+	src := rd.Form{
+		`EMBEDSTR`:       {`one`},
+		`outerstr`:       {`two`},
+		`EmbedNum`:       {`10`},
+		`INNER.INNERSTR`: {`three`},
+	}
+
+	var tar Outer
+	try(src.Decode(&tar))
+	eq(t, Outer{}, tar)
+
+	try(src.DecodeWith(&tar, conf))
+	eq(t, Outer{
+		Embed:    Embed{EmbedStr: `one`, EmbedNum: 10},
+		OuterStr: `two`,
+		Inner:    Inner{InnerStr: `three`},
+	}, tar)
+
+	t.Run(`collisions are combined in key order`, func(t *testing.T) {
+		src := rd.Form{`outerStr`: {`three`}, `OUTERSTR`: {`one`}, `OuterStr`: {`two`}}
+
+		var tar Outer
+		try(src.DecodeWith(&tar, conf))
+		eq(t, `one`, tar.OuterStr)
+
+		raw, err := src.DecodeRaw(new(Outer), conf)
+		try(err)
+		eq(t, rd.Form{`outerStr`: {`one`, `two`, `three`}}, raw)
+
+		conf := rd.Config{LowercaseKeys: true, Strict: true}
+		errs(t, `expected at most one value for field "outerStr", got 3`, src.DecodeWith(new(Outer), conf))
+	})
+
+	t.Run(`lists`, func(t *testing.T) {
+		type Tar struct {
+			Ids []int `json:"itemIds"`
+		}
+
+		var tar Tar
+		try(rd.Form{`ITEMIDS`: {`10`}, `itemids[]`: {`20`}}.DecodeWith(&tar, conf))
+		eq(t, []int{10, 20}, tar.Ids)
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		conf := rd.Config{LowercaseKeys: true, Strict: true}
+		try(src.DecodeWith(new(Outer), conf))
+		errs(t, `unknown fields ["UNKNOWN"]`, rd.Form{`OUTERSTR`: {`one`}, `UNKNOWN`: {`two`}}.DecodeWith(new(Outer), conf))
+	})
+
+	t.Run(`precedence over case insensitive`, func(t *testing.T) {
+		var tar Outer
+		conf := rd.Config{LowercaseKeys: true, CaseInsensitive: true}
+		try(rd.Form{`OuterStr`: {`one`}, `outerStr`: {`two`}}.DecodeWith(&tar, conf))
+		eq(t, `one`, tar.OuterStr)
+	})
+
+	t.Run(`source is not modified`, func(t *testing.T) {
+		eq(t, rd.Form{
+			`EMBEDSTR`:       {`one`},
+			`outerstr`:       {`two`},
+			`EmbedNum`:       {`10`},
+			`INNER.INNERSTR`: {`three`},
+		}, src)
+	})
+}
+
+func TestDecodeWith_Log(t *testing.T) {
+	var logs []string
+	conf := rd.Config{Log: func(msg string) { logs = append(logs, msg) }}
+
+	req := Req{}.Post().BodyForm(url.Values{
+		`outerStr`: {`secret one`},
+		`embedNum`: {`10`},
+		`unknown`:  {`secret two`},
+	}).Ptr()
+
+	var tar Outer
+	try(rd.DecodeWith(req, &tar, conf))
+	eq(t, Outer{Embed: Embed{EmbedNum: 10}, OuterStr: `secret one`}, tar)
+
+	eq(
+		t,
+		[]string{
+			`content type "application/x-www-form-urlencoded"`,
+			`form field "embedStr": skipped, no matching key`,
+			`form field "embedNum": matched`,
+			`form field "inner": skipped, no matching key`,
+			`form field "inner.innerStr": skipped, no matching key`,
+			`form field "inner.innerNum": skipped, no matching key`,
+			`form field "outerStr": matched`,
+			`form key "unknown": no matching field`,
+		},
+		logs,
+	)
+
+	for _, msg := range logs {
+		if strings.Contains(msg, `secret`) {
+			t.Fatalf(`log message must not contain values: %q`, msg)
+		}
+	}
+
+	logs = nil
+	_, err := rd.DownloadWith(Req{}.Post().BodyJson(testOuterJson).Ptr(), conf)
+	try(err)
+	eq(t, []string{`content type "application/json"`}, logs)
+
+	logs = nil
+	try(rd.DecodeWith(Req{}.Query(url.Values{`outerStr`: {`one`}}).Ptr(), new(Outer), conf))
+	eq(t, `missing content type, using URL query`, logs[0])
+}
+
+func TestJson_DecodeWith_JsonSliceParser(t *testing.T) {
+	type Tar struct {
+		One   SliceParserStruct  `json:"one"`
+		Two   *SliceParserStruct `json:"two"`
+		Three []int              `json:"three"`
+		Four  string             `json:"four"`
+	}
+
+	conf := rd.Config{JsonSliceParser: true}
+
+	test := func(exp Tar, src string) {
+		t.Helper()
+		var tar Tar
+		try(rd.Json(src).DecodeWith(&tar, conf))
+		eq(t, exp, tar)
+	}
+
+	test(Tar{}, `{}`)
+	test(Tar{One: SliceParserStruct{[]int{10, 20}}}, `{"one": ["10", "20"]}`)
+	test(Tar{One: SliceParserStruct{[]int{10, 20}}}, `{"one": [10, 20]}`)
+	test(Tar{One: SliceParserStruct{[]int{10, 20}}}, `{"one": ["10", 20]}`)
+	test(Tar{One: SliceParserStruct{[]int{10}}}, `{"one": 10}`)
+	test(Tar{}, `{"one": []}`)
+	test(
+		Tar{Two: &SliceParserStruct{[]int{30}}, Three: []int{40, 50}, Four: `six`},
+		`{"two": ["30"], "three": [40, 50], "four": "six"}`,
+	)
+
+	t.Run(`null`, func(t *testing.T) {
+		tar := Tar{One: SliceParserStruct{[]int{10}}, Two: &SliceParserStruct{[]int{20}}}
+		try(rd.Json(`{"one": null, "two": null}`).DecodeWith(&tar, conf))
+		eq(t, Tar{}, tar)
+	})
+
+	t.Run(`invalid`, func(t *testing.T) {
+		var tar Tar
+
+		err := rd.Json(`{"one": [{"two": 3}]}`).DecodeWith(&tar, conf)
+		errs(t, `unable to decode field "one": expected array of scalars, got element {"two": 3}`, err)
+		errStatus(t, http.StatusBadRequest, err)
+
+		errs(t, `invalid syntax`, rd.Json(`{"one": ["x"]}`).DecodeWith(&tar, conf))
+	})
+
+	t.Run(`disabled`, func(t *testing.T) {
+		var tar Tar
+		errs(t, `cannot unmarshal array`, rd.Json(`{"one": ["10"]}`).Decode(&tar))
+	})
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Json(`{"one": ["10"]}`).DecodeWith(&tar, rd.Config{JsonSliceParser: true, Strict: true}))
+		eq(t, Tar{One: SliceParserStruct{[]int{10}}}, tar)
+	})
+
+	t.Run(`with coercion`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Json(`{"one": 10, "three": 20}`).DecodeWith(&tar, rd.Config{JsonSliceParser: true, Coerce: true}))
+		eq(t, Tar{One: SliceParserStruct{[]int{10}}, Three: []int{20}}, tar)
+	})
+}
+
+func TestJson_DecodeWith_ZeroNull(t *testing.T) {
+	type Inner struct {
+		Str string `json:"str"`
+	}
+
+	type Tar struct {
+		Str   string    `json:"str"`
+		Num   int       `json:"num"`
+		Ptr   *int      `json:"ptr"`
+		Inner Inner     `json:"inner"`
+		Time  time.Time `json:"time"`
+		Other string    `json:"other"`
+	}
+
+	full := func() Tar {
+		return Tar{
+			Str:   `one`,
+			Num:   10,
+			Ptr:   ptrInt(20),
+			Inner: Inner{Str: `two`},
+			Time:  time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+			Other: `three`,
+		}
+	}
+
+	const src = `{"str": null, "num": null, "ptr": null, "inner": {"str": null}, "time": null}`
+
+	t.Run(`disabled`, func(t *testing.T) {
+		tar := full()
+		try(rd.Json(src).Decode(&tar))
+
+		exp := full()
+		exp.Ptr = nil
+		eq(t, exp, tar)
+	})
+
+	t.Run(`enabled`, func(t *testing.T) {
+		tar := full()
+		try(rd.Json(src).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+		eq(t, Tar{Inner: Inner{Str: `two`}, Other: `three`}, tar)
+
+		tar = full()
+		try(rd.Json(`{"inner": null, "str": "four"}`).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+
+		exp := full()
+		exp.Inner = Inner{}
+		exp.Str = `four`
+		eq(t, exp, tar)
+	})
+
+	t.Run(`case-insensitive keys`, func(t *testing.T) {
+		tar := full()
+		try(rd.Json(`{"STR": null, "Num": null}`).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+
+		exp := full()
+		exp.Str = ``
+		exp.Num = 0
+		eq(t, exp, tar)
+	})
+
+	t.Run(`Decode`, func(t *testing.T) {
+		tar := full()
+		req := Req{}.Post().Type(rd.TypeJson).BodyString(`{"num": null}`).Ptr()
+		try(rd.DecodeWith(req, &tar, rd.Config{ZeroNull: true}))
+
+		exp := full()
+		exp.Num = 0
+		eq(t, exp, tar)
+	})
+
+	t.Run(`non-struct`, func(t *testing.T) {
+		tar := map[string]int{`one`: 10}
+		try(rd.Json(`{"one": null}`).DecodeWith(&tar, rd.Config{ZeroNull: true}))
+		eq(t, map[string]int{`one`: 0}, tar)
+	})
+}
+
+func TestJson_DecodeWith_Strict(t *testing.T) {
+	conf := rd.Config{Strict: true}
+
+	t.Run(`valid`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Json(testOuterJson).DecodeWith(&tar, conf))
+		eq(t, testOuter, tar)
 	})
 
-	t.Run(`invokes SliceParser`, func(t *testing.T) {
-		type T struct {
-			One   SliceParserStruct `json:"one"`
-			Two   SliceParserStruct `json:"two"`
-			Three SliceParserStruct `json:"three"`
+	t.Run(`unknown keys`, func(t *testing.T) {
+		src := rd.Json(`{"outerStr": "one", "two": "three"}`)
+
+		var tar Outer
+		try(src.Decode(&tar))
+		errs(t, `unknown field "two"`, src.DecodeWith(&tar, conf))
+		errs(t, `unknown field "two"`, src.DecodeWith(&tar, rd.Config{Strict: true, Coerce: true}))
+		errs(t, `unknown field "two"`, rd.Json(`{"inner": {"two": 10}}`).DecodeWith(&tar, conf))
+		errs(t, `unknown fields ["two"]`, src.DecodeWith(&tar, rd.Config{Strict: true, RejectUnknown: true}))
+	})
+
+	t.Run(`duplicates`, func(t *testing.T) {
+		src := rd.Json(`{"outerStr": "one", "outerStr": "two"}`)
+
+		var tar Outer
+		try(src.Decode(&tar))
+		errs(t, `duplicate JSON key "outerStr"`, src.DecodeWith(&tar, conf))
+	})
+
+	t.Run(`trailing data`, func(t *testing.T) {
+		var tar Outer
+		errs(t, `invalid character`, rd.Json(`{} {}`).Decode(&tar))
+		errs(t, `unexpected data after top-level value`, rd.Json(`{} {}`).DecodeWith(&tar, conf))
+	})
+}
+
+func TestDecodeWith_RejectUnknown(t *testing.T) {
+	conf := rd.Config{RejectUnknown: true}
+
+	t.Run(`json`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Json(testOuterJson).DecodeWith(&tar, conf))
+		eq(t, testOuter, tar)
+
+		// Matched like "encoding/json", case-insensitively.
+		try(rd.Json(`{"OUTERSTR": "one"}`).DecodeWith(&tar, conf))
+
+		src := rd.Json(`{"outerStr": "one", "two": 10, "three": {}}`)
+		try(src.Decode(&tar))
+		errs(t, `unknown fields ["three" "two"]`, src.DecodeWith(&tar, conf))
+
+		// Nested unknown keys are reported by "encoding/json", one at a time.
+		err := rd.Json(`{"inner": {"two": 10, "three": 20}}`).DecodeWith(&tar, conf)
+		errs(t, `unknown field "two"`, err)
+		eq(t, false, strings.Contains(err.Error(), `unknown fields`))
+
+		// Unlike strict mode, duplicates are allowed.
+		try(rd.Json(`{"outerStr": "one", "outerStr": "two"}`).DecodeWith(&tar, conf))
+		eq(t, `two`, tar.OuterStr)
+	})
+
+	t.Run(`form`, func(t *testing.T) {
+		var tar Outer
+		try(rd.Form(testOuterQuery).DecodeWith(&tar, conf))
+		eq(t, testOuterSimple, tar)
+
+		src := rd.Form{`outerStr`: {`one`}, `two`: {`10`}, `inner.three`: {`20`}}
+		try(src.Decode(&tar))
+		errs(t, `unknown fields ["inner.three" "two"]`, src.DecodeWith(&tar, conf))
+
+		// Unlike strict mode, multiple values are allowed.
+		try(rd.Form{`outerStr`: {`one`, `two`}}.DecodeWith(&tar, conf))
+		eq(t, `one`, tar.OuterStr)
+	})
+
+	t.Run(`via request`, func(t *testing.T) {
+		test := func(req *http.Request) {
+			t.Helper()
+			err := rd.DecodeWith(req, new(Outer), conf)
+			errs(t, `unknown fields ["two"]`, err)
+			errStatus(t, http.StatusBadRequest, err)
 		}
 
-		test(
-			t,
-			T{
-				One:   SliceParserStruct{[]int{70, 80}},
-				Two:   SliceParserStruct{[]int{90, 100}},
-				Three: SliceParserStruct{[]int{50, 60}},
-			},
-			T{
-				One:   SliceParserStruct{[]int{10, 20}},
-				Two:   SliceParserStruct{[]int{30, 40}},
-				Three: SliceParserStruct{[]int{50, 60}},
-			},
-			url.Values{
-				`one`: {`70`, `80`},
-				`two`: {`90`, `100`},
-			},
-		)
+		test(Req{}.Post().BodyJson(`{"outerStr": "one", "two": 10}`).Ptr())
+		test(Req{}.Post().BodyForm(url.Values{`outerStr`: {`one`}, `two`: {`10`}}).Ptr())
+		test(Req{}.Query(url.Values{`two`: {`10`}}).Ptr())
+		test(Req{}.Post().BodyForm(url.Values{`two`: {`10`}}).Ptr())
+
+		req := Req{}.Post().BodyForm(url.Values{`two`: {`10`}}).Ptr()
+		errs(t, `unknown fields ["two"]`, rd.DecodeWith(req, new(Outer), rd.Config{RejectUnknown: true, StreamForm: true}))
 	})
 }
 
-func testDec(t testing.TB, exp, tar interface{}, dec rd.Dec) {
-	t.Helper()
+func TestDecodeWith_PreferHeader(t *testing.T) {
+	const src = `{"outerStr": "one", "unknown": "two"}`
 
-	ptr := r.New(r.TypeOf(tar))
-	ptr.Elem().Set(r.ValueOf(tar))
+	req := func(prefs ...string) *http.Request {
+		req := Req{}.Post().BodyJson(src).Ptr()
+		for _, val := range prefs {
+			req.Header.Add(`Prefer`, val)
+		}
+		return req
+	}
 
-	try(dec.Decode(ptr.Interface()))
-	eq(t, exp, ptr.Elem().Interface())
+	lenient := rd.Config{PreferHeader: `Prefer`}
+	strict := rd.Config{PreferHeader: `Prefer`, Strict: true}
+	msg := `unknown field "unknown"`
+
+	try(rd.DecodeWith(req(), new(Outer), lenient))
+	try(rd.DecodeWith(req(`respond-async`), new(Outer), lenient))
+	try(rd.DecodeWith(req(`handling=lenient`), new(Outer), strict))
+	try(rd.DecodeWith(req(`Lenient`), new(Outer), strict))
+	try(rd.DecodeWith(req(`handling=strict`), new(Outer), rd.Config{}))
+
+	errs(t, msg, rd.DecodeWith(req(), new(Outer), strict))
+	errs(t, msg, rd.DecodeWith(req(`handling=strict`), new(Outer), lenient))
+	errs(t, msg, rd.DecodeWith(req(`handling="strict"`), new(Outer), lenient))
+	errs(t, msg, rd.DecodeWith(req(`respond-async, handling=strict; x=y`), new(Outer), lenient))
+	errs(t, msg, rd.DecodeWith(req(`STRICT`), new(Outer), lenient))
+	errs(t, msg, rd.DecodeWith(req(`lenient`, `strict`), new(Outer), lenient))
+
+	t.Run(`form`, func(t *testing.T) {
+		req := Req{}.Post().BodyForm(url.Values{`unknown`: {`one`}}).Ptr()
+		req.Header.Set(`Prefer`, `handling=strict`)
+		errs(t, `unknown fields ["unknown"]`, rd.DecodeWith(req, new(Outer), lenient))
+	})
 }
 
-func TestForm_Parse(t *testing.T) {
-	var tar rd.Form
-	try(tar.Parse(testOuterQuery.Encode()))
-	eq(t, url.Values(tar), testOuterQuery)
+func TestForm_DecodeWithDefaults(t *testing.T) {
+	defaults := PtrOuter{
+		Embed:    &Embed{EmbedStr: `embed default`, EmbedNum: 10},
+		Inner:    Inner{InnerStr: `inner default`},
+		OuterStr: `outer default`,
+	}
+
+	test := func(exp PtrOuter, src rd.Form) {
+		t.Helper()
+
+		var tar PtrOuter
+		try(src.DecodeWithDefaults(defaults, &tar))
+		eq(t, exp, tar)
+
+		tar = PtrOuter{}
+		try(src.DecodeWithDefaults(&defaults, &tar))
+		eq(t, exp, tar)
+	}
+
+	test(defaults, nil)
+	test(defaults, rd.Form{`unknown`: {`one`}})
+
+	test(
+		PtrOuter{
+			Embed:    &Embed{EmbedStr: `embed default`, EmbedNum: 20},
+			Inner:    Inner{InnerStr: `inner default`},
+			OuterStr: `outer val`,
+		},
+		rd.Form{`outerStr`: {`outer val`}, `embedNum`: {`20`}},
+	)
+
+	test(
+		PtrOuter{
+			Embed: &Embed{EmbedStr: `embed default`},
+			Inner: Inner{InnerStr: `inner default`},
+		},
+		rd.Form{`outerStr`: {``}, `embedNum`: {``}},
+	)
+
+	eq(t, &Embed{EmbedStr: `embed default`, EmbedNum: 10}, defaults.Embed)
+
+	t.Run(`maps and slices are copied`, func(t *testing.T) {
+		type Tar struct {
+			Meta map[string]string `json:"meta"`
+			Tags []string          `json:"tags"`
+		}
+
+		defaults := Tar{Meta: map[string]string{`a`: `b`}, Tags: []string{`one`}}
+
+		var tar Tar
+		try(rd.Form{`meta[x]`: {`y`}}.DecodeWithDefaults(&defaults, &tar))
+		eq(t, Tar{Meta: map[string]string{`a`: `b`, `x`: `y`}, Tags: []string{`one`}}, tar)
+
+		tar.Tags[0] = `two`
+		eq(t, Tar{Meta: map[string]string{`a`: `b`}, Tags: []string{`one`}}, defaults)
+	})
+
+	t.Run(`cycles`, func(t *testing.T) {
+		type Node struct {
+			Name  string           `json:"name"`
+			Next  *Node            `json:"-"`
+			Self  *Node            `json:"self"`
+			Peers map[string]*Node `json:"peers"`
+		}
+
+		var defaults Node
+		defaults.Name = `one`
+		defaults.Next = &defaults
+		defaults.Self = &defaults
+		defaults.Peers = map[string]*Node{`self`: &defaults}
+
+		var tar Node
+		try(rd.Form{`name`: {`two`}}.DecodeWithDefaults(&defaults, &tar))
+		eq(t, `two`, tar.Name)
+		eq(t, &defaults, tar.Next)
+		eq(t, true, tar.Self != &defaults)
+		eq(t, tar.Self, tar.Self.Self)
+		eq(t, tar.Self, tar.Peers[`self`])
+		eq(t, `one`, defaults.Name)
+	})
+
+	errs(t, `expected defaults of type rd_test.PtrOuter, got rd_test.Outer`, rd.Form{}.DecodeWithDefaults(Outer{}, new(PtrOuter)))
+	errs(t, `expected settable struct pointer`, rd.Form{}.DecodeWithDefaults(PtrOuter{}, PtrOuter{}))
+	errs(t, `expected defaults of type rd_test.PtrOuter, got <nil>`, rd.Form{}.DecodeWithDefaults(nil, new(PtrOuter)))
 }
 
-func TestDecode_GET_query(t *testing.T) {
-	req := Req{}.Query(testOuterQuery).Ptr()
+func TestYaml_Decode(t *testing.T) {
+	errs(t, `missing unmarshaler for content type "application/yaml"`, rd.Yaml(testOuterYaml).Decode(new(Outer)))
+
+	defer withRegistered(rd.TypeYaml, yamlUnmarshal)()
 
 	var tar Outer
-	rd.TryDecode(req, &tar)
+	try(rd.Yaml(testOuterYaml).Decode(&tar))
+	eq(t, testOuter, tar)
 
-	eq(t, testOuterSimple, tar)
+	errs(t, `invalid YAML line`, rd.Yaml(`garbage`).Decode(&tar))
 }
 
-func TestDecode_GET_json(t *testing.T) {
-	req := Req{}.BodyJson(testOuterJson).Ptr()
+func TestYamlUnmarshal(t *testing.T) {
+	defer func(prev func([]byte, interface{}) error) { rd.YamlUnmarshal = prev }(rd.YamlUnmarshal)
+	rd.YamlUnmarshal = yamlUnmarshal
 
 	var tar Outer
-	rd.TryDecode(req, &tar)
+	try(rd.Yaml(testOuterYaml).Decode(&tar))
+	eq(t, testOuter, tar)
 
+	tar = Outer{}
+	try(rd.Decode(Req{}.Post().Type(rd.TypeYamlText).BodyString(testOuterYaml).Ptr(), &tar))
 	eq(t, testOuter, tar)
+
+	t.Run(`registered takes priority`, func(t *testing.T) {
+		defer withRegistered(rd.TypeYaml, func([]byte, interface{}) error {
+			return errors.New(`registered`)
+		})()
+		errs(t, `registered`, rd.Yaml(testOuterYaml).Decode(new(Outer)))
+	})
 }
 
-func TestDecode_POST_json(t *testing.T) {
-	req := Req{}.Post().BodyJson(testOuterJson).Ptr()
+func TestYaml_Set(t *testing.T) {
+	test := func(exp rd.Set, src string) {
+		t.Helper()
+		eq(t, exp, rd.Yaml(src).Set())
+	}
 
-	var tar Outer
-	rd.TryDecode(req, &tar)
+	test(set(), ``)
+	test(set(), "  \n\t\n  ")
+	test(set(), `# comment`)
+	test(set(), `garbage`)
+	test(set(), `- one: two`)
+	test(set(), `{one: two}`)
+	test(set(), `[one, two]`)
+	test(set(), `one:two`)
+	test(set(), `one #: two`)
+	test(set(`embedStr`, `embedNum`, `outerStr`, `inner`), testOuterYaml)
 
-	eq(t, testOuter, tar)
+	test(set(`one`), `one: two`)
+	test(set(`one`), `one:`)
+	test(set(`one`), "one:\r\n")
+	test(set(`one`), `one : two`)
+	test(set(`one`), `one: two: three`)
+	test(set(`one`), `one: two # comment`)
+	test(set(`one#two`), `one#two: three`)
+	test(set(`one two`), `one two: three`)
+	test(set(`-one`), `-one: two`)
+	test(set(`one`), "one:\n  - two\n  - three: four")
+	test(set(`one`, `five`), "one: |\n  two: three\n\n  four\nfive: six")
+	test(set(`one`), "one:\n  two:\n    three: four")
+	test(set(`one: two`), `"one: two": three`)
+	test(set(`one"two`), `"one\"two": three`)
+	test(set(`one'two`), `'one''two': three`)
+	test(set(`one`), "%YAML 1.2\n---\none: two")
+	test(set(`one`), "---\none: two\n---\nthree: four")
+	test(set(`one`), "one: two\n...\nthree: four")
+	test(set(`one`), "one: two\n---\nthree: four")
+	test(set(`one`, `two`), "# comment\none: 1\n\n# comment\ntwo: 2\n")
 }
 
-func TestDecode_POST_form(t *testing.T) {
-	req := Req{}.Post().Query(testUrlQuery).BodyForm(testOuterQuery).Ptr()
+func TestRegister_yaml(t *testing.T) {
+	req := func(typ string) *http.Request {
+		return Req{}.Post().Type(typ).BodyString(testOuterYaml).Ptr()
+	}
 
-	var tar Outer
-	rd.TryDecode(req, &tar)
+	errs(t, `unsupported content type "text/yaml"`, rd.Decode(req(rd.TypeYamlText), new(Outer)))
 
-	eq(t, testOuterSimple, tar)
+	defer withRegistered(rd.TypeYamlText, yamlUnmarshal)()
+
+	for _, typ := range []string{rd.TypeYaml, rd.TypeYamlText} {
+		var tar Outer
+		rd.TryDecode(req(typ), &tar)
+		eq(t, testOuter, tar)
+
+		eq(t, rd.Yaml(testOuterYaml), rd.TryDownload(req(typ)))
+	}
 }
 
-func TestDecode_POST_multi(t *testing.T) {
-	req := Req{}.Post().Query(testUrlQuery).BodyMulti(testOuterQuery).Ptr()
+func TestMsgpack_Decode(t *testing.T) {
+	errs(t, `missing unmarshaler for content type "application/msgpack"`, rd.Msgpack(testOuterMsgpack).Decode(new(Outer)))
+
+	defer withRegistered(rd.TypeMsgpack, msgpackUnmarshal)()
 
 	var tar Outer
-	rd.TryDecode(req, &tar)
+	try(rd.Msgpack(testOuterMsgpack).Decode(&tar))
+	eq(t, testOuter, tar)
 
-	eq(t, testOuterSimple, tar)
+	errs(t, `invalid msgpack`, rd.Msgpack{0xc1}.Decode(&tar))
 }
 
-func TestDownload_GET_query(t *testing.T) {
-	req := Req{}.Query(testOuterQuery).Ptr()
-	eq(t, rd.Form(req.URL.Query()), rd.TryDownload(req))
+func TestMsgpack_Set(t *testing.T) {
+	test := func(exp rd.Set, src interface{}) {
+		t.Helper()
+		eq(t, exp, rd.Msgpack(msgpackEncode(src)).Set())
+	}
+
+	eq(t, set(), rd.Msgpack(nil).Set())
+	eq(t, set(`embedStr`, `embedNum`, `inner`, `outerStr`), rd.Msgpack(testOuterMsgpack).Set())
+
+	test(set(), nil)
+	test(set(), 10)
+	test(set(), `one`)
+	test(set(), []interface{}{msgpackMap{{`one`, 1}}})
+	test(set(), msgpackMap{})
+	test(set(`one`), msgpackMap{{`one`, nil}})
+	test(set(`one`, `two`), msgpackMap{{`one`, true}, {`two`, false}})
+	test(set(`one`, `two`), msgpackMap{{`one`, -1}, {`two`, -1 << 40}})
+	test(set(`one`, `two`), msgpackMap{{`one`, 1.5}, {`two`, 1 << 40}})
+	test(set(`one`, `two`), msgpackMap{{`one`, strings.Repeat(`a`, 300)}, {`two`, `three`}})
+	test(set(`one`, `two`), msgpackMap{{`one`, []byte(`three`)}, {`two`, 4}})
+	test(set(`one`, `two`), msgpackMap{{`one`, msgpackMap{{`three`, msgpackMap{{`four`, 5}}}}}, {`two`, 6}})
+	test(set(`one`, `two`), msgpackMap{{`one`, []interface{}{1, `three`, msgpackMap{{`four`, 5}}}}, {`two`, 6}})
+	test(set(`one`), msgpackMap{{1, `two`}, {`one`, 3}, {msgpackMap{}, 4}})
+	test(set(strings.Repeat(`a`, 40)), msgpackMap{{strings.Repeat(`a`, 40), 1}})
+
+	big := make(msgpackMap, 20)
+	exp := rd.Set{}
+	for i := range big {
+		key := strconv.Itoa(i)
+		big[i] = [2]interface{}{key, i}
+		exp.Add(key)
+	}
+	test(exp, big)
+
+	// Extensions and 32-bit sizes aren't produced by the test encoder.
+	eq(t, set(`one`, `two`), rd.Msgpack("\x82\xa3one\xd4\x01\x02\xa3two\xc7\x02\x01ab").Set())
+	eq(t, set(`one`), rd.Msgpack("\xdf\x00\x00\x00\x01\xdb\x00\x00\x00\x03one\xdd\x00\x00\x00\x00").Set())
+
+	panics(t, `invalid msgpack syntax in position 1: unexpected byte 0xc1`, func() { rd.Msgpack{0x81, 0xc1}.Set() })
+	panics(t, `unexpected msgpack EOF`, func() { rd.Msgpack{0x81}.Set() })
+	panics(t, `unexpected msgpack EOF`, func() { rd.Msgpack{0x81, 0xa3, 'o'}.Set() })
+	panics(t, `unexpected msgpack EOF`, func() { rd.Msgpack{0x81, 0xa3, 'o', 'n', 'e'}.Set() })
+	panics(t, `unexpected msgpack EOF`, func() { rd.Msgpack{0xde, 0x00}.Set() })
+	panics(t, `unexpected msgpack EOF`, func() { rd.Msgpack{0x81, 0xa3, 'o', 'n', 'e', 0x92, 0x01}.Set() })
 }
 
-func TestDownload_POST_query(t *testing.T) {
-	req := Req{}.Post().Query(testOuterQuery).Ptr()
-	eq(t, rd.Form(req.URL.Query()), rd.TryDownload(req))
+func TestRegister_msgpack(t *testing.T) {
+	req := func(typ string) *http.Request {
+		return Req{}.Post().Type(typ).BodyString(string(testOuterMsgpack)).Ptr()
+	}
+
+	errs(t, `unsupported content type "application/x-msgpack"`, rd.Decode(req(rd.TypeMsgpackX), new(Outer)))
+
+	defer withRegistered(rd.TypeMsgpackX, msgpackUnmarshal)()
+
+	for _, typ := range []string{rd.TypeMsgpack, rd.TypeMsgpackX} {
+		var tar Outer
+		rd.TryDecode(req(typ), &tar)
+		eq(t, testOuter, tar)
+
+		dec := rd.TryDownload(req(typ))
+		eq(t, rd.Msgpack(testOuterMsgpack), dec)
+		eq(t, set(`embedStr`, `embedNum`, `inner`, `outerStr`), dec.Set())
+	}
 }
 
-func TestDownload_POST_json(t *testing.T) {
-	req := Req{}.Post().Query(testUrlQuery).BodyJson(testJsonStr).Ptr()
-	eq(t, rd.Json(testJsonStr), rd.TryDownload(req))
+func TestDownload_content_type_status(t *testing.T) {
+	t.Run(`unsupported`, func(t *testing.T) {
+		req := func() *http.Request { return Req{}.Post().Type(`text/foo`).BodyString(`one`).Ptr() }
+
+		_, err := rd.Download(req())
+		errs(t, `unsupported content type "text/foo"`, err)
+
+		var tar rd.Err
+		eq(t, true, errors.As(err, &tar))
+		eq(t, http.StatusUnsupportedMediaType, tar.Status)
+		eq(t, http.StatusUnsupportedMediaType, tar.HttpStatusCode())
+
+		errStatus(t, http.StatusUnsupportedMediaType, rd.Decode(req(), new(Outer)))
+		errStatus(t, http.StatusUnsupportedMediaType, rd.Validate(req()))
+	})
+
+	t.Run(`unregistered`, func(t *testing.T) {
+		_, err := rd.Download(Req{}.Post().Type(rd.TypeToml).BodyString(`one = 1`).Ptr())
+		errStatus(t, http.StatusUnsupportedMediaType, err)
+	})
+
+	t.Run(`missing`, func(t *testing.T) {
+		_, err := rd.Download(Req{}.Post().BodyString(`one`).Ptr())
+		errs(t, `missing content type`, err)
+		errStatus(t, http.StatusBadRequest, err)
+	})
 }
 
-func TestDownload_POST_form(t *testing.T) {
-	req := Req{}.Post().Query(testUrlQuery).BodyForm(testBodyQuery).Ptr()
-	eq(t, rd.Form(testBodyQuery), rd.TryDownload(req))
+func TestDecodeWith_RequireBody(t *testing.T) {
+	conf := rd.Config{RequireBody: true}
+
+	t.Run(`missing json body`, func(t *testing.T) {
+		req := func() *http.Request { return Req{}.Post().TypeJson().Ptr() }
+
+		tar := testOuter
+		try(rd.Decode(req(), &tar))
+		eq(t, testOuter, tar)
+
+		err := rd.DecodeWith(req(), &tar, conf)
+		errs(t, `missing request body for content type "application/json"`, err)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, testOuter, tar)
+
+		_, err = rd.DownloadWith(req(), conf)
+		errStatus(t, http.StatusBadRequest, err)
+	})
+
+	t.Run(`empty body`, func(t *testing.T) {
+		test := func(typ string) {
+			t.Helper()
+			req := func() *http.Request {
+				return Req{}.Post().Type(typ).BodyReadCloser(http.NoBody).Ptr()
+			}
+
+			errs(t, fmt.Sprintf(`missing request body for content type %q`, typ), rd.DecodeWith(req(), new(Outer), conf))
+
+			_, err := rd.DownloadWith(req(), conf)
+			errStatus(t, http.StatusBadRequest, err)
+		}
+
+		test(rd.TypeJson)
+		test(rd.TypeForm)
+		test(rd.TypeXml)
+	})
+
+	t.Run(`empty form body is lenient by default`, func(t *testing.T) {
+		tar := testOuter
+		try(rd.Decode(Req{}.Post().TypeForm().BodyReadCloser(http.NoBody).Ptr(), &tar))
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`non-empty body`, func(t *testing.T) {
+		var tar Outer
+		try(rd.DecodeWith(Req{}.Post().BodyJson(testOuterJson).Ptr(), &tar, conf))
+		eq(t, testOuter, tar)
+
+		dec, err := rd.DownloadWith(Req{}.Post().BodyJson(testOuterJson).Ptr(), conf)
+		try(err)
+		eq(t, rd.Json(testOuterJson), dec)
+	})
+
+	t.Run(`query`, func(t *testing.T) {
+		var tar Outer
+		try(rd.DecodeWith(Req{}.Query(testOuterQuery).Ptr(), &tar, conf))
+		eq(t, testOuterSimple, tar)
+	})
 }
 
-func TestDownload_POST_multi(t *testing.T) {
-	req := Req{}.Post().Query(testUrlQuery).BodyMulti(testBodyQuery).Ptr()
-	eq(t, rd.Form(testBodyQuery), rd.TryDownload(req))
+func TestContentType(t *testing.T) {
+	test := func(exp, src string) {
+		t.Helper()
+		eq(t, exp, rd.ContentType(Req{}.Type(src).Ptr()))
+	}
+
+	test(``, ``)
+	test(``, `;`)
+	test(rd.TypeJson, `application/json; charset`)
+	test(rd.TypeJson, rd.TypeJson)
+	test(rd.TypeJson, `application/json; charset=utf-8`)
+	test(rd.TypeJson, ` Application/JSON `)
+	test(rd.TypeMulti, `multipart/form-data; boundary=one`)
+	test(`text/plain`, `text/plain`)
+
+	eq(t, ``, rd.ContentType(nil))
+	eq(t, ``, rd.ContentType(Req{}.Ptr()))
+}
+
+func TestValidate(t *testing.T) {
+	ok := func(req Req) {
+		t.Helper()
+		try(rd.Validate(req.Ptr()))
+	}
+
+	fail := func(msg string, req Req) {
+		t.Helper()
+		errs(t, msg, rd.Validate(req.Ptr()))
+	}
+
+	ok(Req{})
+	ok(Req{}.Query(testOuterQuery))
+	ok(Req{}.Post().TypeJson())
+	ok(Req{}.Post().BodyJson(`   `))
+	ok(Req{}.Post().BodyJson(testOuterJson))
+	ok(Req{}.Post().BodyJson(`[]`))
+	ok(Req{}.Post().BodyJson(`"str"`))
+	ok(Req{}.Post().BodyJson(`-10`))
+	ok(Req{}.Post().BodyJson(`10`))
+	ok(Req{}.Post().BodyJson(`null`))
+	ok(Req{}.Post().BodyJson(`true`))
+	ok(Req{}.Post().BodyJson(strings.Repeat(` `, 4096) + `{}`))
+	ok(Req{}.Post().BodyForm(testOuterQuery))
+	ok(Req{}.Post().BodyMulti(testOuterQuery))
+
+	fail(`missing content type`, Req{}.Post().BodyString(`{}`))
+	fail(`unsupported content type "text/plain"`, Req{}.Post().Type(`text/plain`).BodyString(`one`))
+	fail(`unsupported content type "application/toml"`, Req{}.Post().Type(rd.TypeToml).BodyString(`one = 1`))
+	fail(`content type "application/json" doesn't match body starting with "one=two"`, Req{}.Post().BodyJson(`one=two`))
+	fail(`content type "application/json" doesn't match body starting with "  <one/>"`, Req{}.Post().BodyJson(`  <one/>`))
+	fail(`content type "application/x-www-form-urlencoded" doesn't match body starting with "{\"one\": \"two\"}"`, Req{}.Post().TypeForm().BodyString(`{"one": "two"}`))
+	fail(`content type "multipart/form-data" doesn't match body starting with "[]"`, Req{}.Post().Type(`multipart/form-data; boundary=one`).BodyString(`[]`))
+	fail(`missing multipart boundary`, Req{}.Post().Type(rd.TypeMulti).BodyString(`--one`))
+
+	t.Run(`body is preserved`, func(t *testing.T) {
+		req := Req{}.Post().BodyJson(testOuterJson).Ptr()
+		try(rd.Validate(req))
+
+		var tar Outer
+		rd.TryDecode(req, &tar)
+		eq(t, testOuter, tar)
+	})
+
+	t.Run(`multipart body is preserved`, func(t *testing.T) {
+		req := Req{}.Post().BodyMulti(testOuterQuery).Ptr()
+		try(rd.Validate(req))
+
+		var tar Outer
+		rd.TryDecode(req, &tar)
+		eq(t, testOuterSimple, tar)
+	})
+
+	t.Run(`no progress`, func(t *testing.T) {
+		req := Req{}.Post().TypeJson().Ptr()
+		req.Body = io.NopCloser(EmptyReader{})
+		req.ContentLength = -1
+
+		err := rd.Validate(req)
+		errStatus(t, http.StatusBadRequest, err)
+		eq(t, true, errors.Is(err, io.ErrNoProgress))
+	})
+}
+
+func TestForm_Decode_querystring(t *testing.T) {
+	type Tar struct {
+		Outer
+		Rest     string  `rd:",querystring"`
+		RestForm rd.Form `rd:"querystring"`
+	}
+
+	test := func(exp Tar, src rd.Form) {
+		t.Helper()
+		var tar Tar
+		try(src.Decode(&tar))
+		eq(t, exp, tar)
+	}
+
+	test(Tar{}, nil)
+	test(Tar{Outer: testOuterSimple, RestForm: rd.Form{}}, rd.Form(testOuterQuery))
+
+	test(
+		Tar{
+			Outer:    Outer{OuterStr: `one`},
+			Rest:     `three=four&three=five&two=`,
+			RestForm: rd.Form{`two`: {``}, `three`: {`four`, `five`}},
+		},
+		rd.Form{`outerStr`: {`one`}, `two`: {``}, `three`: {`four`, `five`}},
+	)
+
+	t.Run(`strict`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`two`: {`three`}}.DecodeWith(&tar, rd.Config{Strict: true}))
+		eq(t, `two=three`, tar.Rest)
+	})
+
+	t.Run(`json key is not forwarded`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Form{`_json`: {`{}`}, `two`: {`three`}}.DecodeWith(&tar, rd.Config{JsonKey: `_json`}))
+		eq(t, `two=three`, tar.Rest)
+	})
+
+	t.Run(`map types`, func(t *testing.T) {
+		var tar struct {
+			Outer
+			Rest    url.Values          `rd:"querystring"`
+			RestMap map[string][]string `rd:"querystring"`
+			RestPtr *url.Values         `rd:"querystring"`
+		}
+
+		src := rd.Form{`outerStr`: {`one`}, `two`: {``}, `three`: {`four`, `five`}}
+		try(src.Decode(&tar))
+
+		exp := url.Values{`two`: {``}, `three`: {`four`, `five`}}
+		eq(t, Outer{OuterStr: `one`}, tar.Outer)
+		eq(t, exp, tar.Rest)
+		eq(t, map[string][]string(exp), tar.RestMap)
+		eq(t, &exp, tar.RestPtr)
+
+		// The output doesn't share memory with the source.
+		tar.Rest[`three`][0] = `six`
+		eq(t, []string{`four`, `five`}, src[`three`])
+
+		try(rd.Form{`outerStr`: {`two`}}.Decode(&tar))
+		eq(t, url.Values{}, tar.Rest)
+	})
+
+	t.Run(`all keys`, func(t *testing.T) {
+		type Tar struct {
+			Outer
+			All  map[string][]string `rd:"querystring,all"`
+			Str  string              `rd:"querystring,all"`
+			Rest url.Values          `rd:"querystring"`
+		}
+
+		src := rd.Form{
+			`outerStr`:       {`one`},
+			`filter`:         {`a`, `b`},
+			`sort`:           {`c`},
+			`inner.innerNum`: {`10`},
+		}
+
+		var tar Tar
+		try(src.Decode(&tar))
+
+		eq(t, Outer{Inner: Inner{InnerNum: 10}, OuterStr: `one`}, tar.Outer)
+		eq(t, map[string][]string(src), tar.All)
+		eq(t, url.Values(src).Encode(), tar.Str)
+		eq(t, url.Values{`filter`: {`a`, `b`}, `sort`: {`c`}}, tar.Rest)
+
+		// The output doesn't share memory with the source.
+		tar.All[`filter`][0] = `d`
+		eq(t, []string{`a`, `b`}, src[`filter`])
+
+		// Unknown keys are allowed in strict mode.
+		try(src.DecodeWith(new(Tar), rd.Config{Strict: true}))
+
+		t.Run(`encode`, func(t *testing.T) {
+			var tar struct {
+				Outer
+				All url.Values `rd:"querystring,all"`
+			}
+			try(src.Decode(&tar))
+
+			form := tryVal(rd.EncodeForm(tar))
+			eq(t, []string{`one`}, form[`outerStr`])
+			eq(t, []string{`10`}, form[`inner.innerNum`])
+			eq(t, []string{`a`, `b`}, form[`filter`])
+			eq(t, []string{`c`}, form[`sort`])
+		})
+
+		t.Run(`encode with config`, func(t *testing.T) {
+			var tar struct {
+				Outer
+				All url.Values `rd:"querystring,all"`
+			}
+			conf := rd.Config{LowercaseKeys: true}
+			try(rd.Form{`OUTERSTR`: {`one`}, `filter`: {`a`}}.DecodeWith(&tar, conf))
+
+			form := tryVal(rd.EncodeFormWith(tar, conf))
+			eq(t, []string{`one`}, form[`outerStr`])
+			eq(t, []string(nil), form[`OUTERSTR`])
+			eq(t, []string{`a`}, form[`filter`])
+		})
+	})
+
+	t.Run(`unsupported type`, func(t *testing.T) {
+		var tar struct {
+			Rest int `rd:"querystring"`
+		}
+		errs(t, `failed to parse "one=two" into int`, rd.Form{`one`: {`two`}}.Decode(&tar))
+	})
+
+	t.Run(`ignored by JSON`, func(t *testing.T) {
+		var tar Tar
+		try(rd.Json(`{"outerStr": "one", "": "two"}`).DecodeWith(&tar, rd.Config{Coerce: true}))
+		eq(t, Tar{Outer: Outer{OuterStr: `one`}}, tar)
+	})
+}
+
+func TestPanicRecovery(t *testing.T) {
+	t.Run(`Parse`, func(t *testing.T) {
+		err := rd.Parse(`10`, r.Value{})
+		errs(t, `unexpected panic`, err)
+		errStatus(t, http.StatusInternalServerError, err)
+
+		err = rd.Parse(`10`, r.ValueOf(10))
+		errs(t, `unaddressable`, err)
+		errStatus(t, http.StatusInternalServerError, err)
+
+		var tar PanicParser
+		errs(t, `unexpected panic: one`, rd.Parse(`one`, r.ValueOf(&tar).Elem()))
+	})
+
+	t.Run(`ParseSlice`, func(t *testing.T) {
+		var num int
+		err := rd.ParseSlice([]string{`10`}, r.ValueOf(&num).Elem())
+		errs(t, `MakeSlice of non-slice type`, err)
+		errStatus(t, http.StatusInternalServerError, err)
+
+		var tar PanicSliceParser
+		err = rd.ParseSlice([]string{`one`}, r.ValueOf(&tar).Elem())
+		errs(t, `[one]`, err)
+		errStatus(t, http.StatusBadRequest, err)
+	})
+
+	t.Run(`Form.Decode`, func(t *testing.T) {
+		var tar struct {
+			One   PanicParser      `json:"one"`
+			Two   []PanicParser    `json:"two"`
+			Three PanicSliceParser `json:"three"`
+		}
+
+		errs(t, `unexpected panic: four`, rd.Form{`one`: {`four`}}.Decode(&tar))
+		errs(t, `unexpected panic: five`, rd.Form{`two`: {`five`}}.Decode(&tar))
+		errs(t, `[six seven]`, rd.Form{`three`: {`six`, `seven`}}.Decode(&tar))
+	})
+
+	t.Run(`Decode`, func(t *testing.T) {
+		var tar struct {
+			One PanicParser `json:"one"`
+		}
+
+		req := Req{}.Query(url.Values{`one`: {`two`}}).Ptr()
+		errs(t, `unexpected panic: two`, rd.Decode(req, &tar))
+	})
+
+	t.Run(`Json.DecodeWith`, func(t *testing.T) {
+		var tar struct {
+			One JsonPanic `json:"one"`
+		}
+		err := rd.Json(`{"one": [1]}`).DecodeWith(&tar, rd.Config{Coerce: true})
+		errs(t, `unexpected panic: 1`, err)
+	})
 }